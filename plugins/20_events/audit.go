@@ -0,0 +1,261 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// DefaultAuditMaxFiles is the number of rotated audit logs kept
+	DefaultAuditMaxFiles = 5
+
+	// DefaultAuditMaxSize is the size in bytes at which the audit log is rotated
+	DefaultAuditMaxSize = 10 * 1024 * 1024
+
+	// globalAppName is the property namespace holding the audit settings
+	globalAppName = "_global_"
+)
+
+var (
+	// DefaultProperties is a map of all valid events properties with corresponding default property values
+	DefaultProperties = map[string]string{
+		"audit-max-files": strconv.Itoa(DefaultAuditMaxFiles),
+		"audit-max-size":  strconv.Itoa(DefaultAuditMaxSize),
+		"audit-syslog":    "false",
+	}
+)
+
+// AuditRecord is a single dokku command invocation
+type AuditRecord struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	SSHUser     string    `json:"ssh-user"`
+	SSHName     string    `json:"ssh-name"`
+	Fingerprint string    `json:"fingerprint"`
+	App         string    `json:"app"`
+	Command     string    `json:"command"`
+	Args        []string  `json:"args"`
+	ExitCode    int       `json:"exit-code"`
+	DurationMs  int64     `json:"duration-ms"`
+}
+
+// AuditQuery filters audit records
+type AuditQuery struct {
+	App     string
+	User    string
+	Command string
+	Since   time.Time
+	Failed  bool
+	Limit   int
+}
+
+// GetAuditLogPath returns the path of the active audit log
+func GetAuditLogPath() string {
+	return strings.Join([]string{common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "events", "audit.log"}, "/")
+}
+
+// Matches returns true if a record satisfies every filter of a query
+func (q AuditQuery) Matches(record AuditRecord) bool {
+	if q.App != "" && record.App != q.App {
+		return false
+	}
+	if q.User != "" && record.User != q.User {
+		return false
+	}
+	if q.Command != "" && record.Command != q.Command && !strings.HasPrefix(record.Command, q.Command+":") {
+		return false
+	}
+	if !q.Since.IsZero() && record.Time.Before(q.Since) {
+		return false
+	}
+	if q.Failed && record.ExitCode == 0 {
+		return false
+	}
+	return true
+}
+
+// NewAuditRecord builds a record for a finished command from the invoking ssh session
+func NewAuditRecord(sshUser string, sshName string, fingerprint string, appName string, args []string, exitCode int, startedAt time.Time) AuditRecord {
+	record := AuditRecord{
+		Time:        startedAt.UTC(),
+		User:        sshName,
+		SSHUser:     sshUser,
+		SSHName:     sshName,
+		Fingerprint: fingerprint,
+		Args:        []string{},
+		ExitCode:    exitCode,
+		DurationMs:  int64(time.Since(startedAt) / time.Millisecond),
+	}
+	if sshUser == "root" || sshName == "" {
+		record.User = sshUser
+	}
+	if userName := getAuthUserName(fingerprint, sshName); userName != "" {
+		record.User = userName
+	}
+
+	if len(args) > 0 {
+		record.Command = args[0]
+		record.Args = common.MaskArgs(args[0], args[1:])
+	}
+
+	if appName == "" {
+		appName = common.CommandAppName(args)
+	}
+	if appName != "" {
+		if common.VerifyAppName(appName) == nil || strings.HasPrefix(record.Command, "apps:") {
+			record.App = appName
+		}
+	}
+	return record
+}
+
+// ReadAuditRecords returns the records matching a query from the active and rotated audit logs, oldest first
+func ReadAuditRecords(query AuditQuery) ([]AuditRecord, error) {
+	records := []AuditRecord{}
+	path := GetAuditLogPath()
+	for i := getAuditMaxFiles(); i >= 0; i-- {
+		filename := path
+		if i > 0 {
+			filename = fmt.Sprintf("%s.%d", path, i)
+		}
+
+		file, err := os.Open(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return records, fmt.Errorf("Unable to read audit log: %s", err.Error())
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var record AuditRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				continue
+			}
+			if query.Matches(record) {
+				records = append(records, record)
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return records, fmt.Errorf("Unable to read audit log: %s", err.Error())
+		}
+	}
+
+	if query.Limit > 0 && len(records) > query.Limit {
+		records = records[len(records)-query.Limit:]
+	}
+	return records, nil
+}
+
+// RecordCommand appends a record to the audit log, rotating it when full, and optionally sends it to syslog
+func RecordCommand(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	path := GetAuditLogPath()
+	if err := os.MkdirAll(strings.TrimSuffix(path, "/audit.log"), 0755); err != nil {
+		return fmt.Errorf("Unable to create audit log directory: %s", err.Error())
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0664)
+	if err != nil {
+		return fmt.Errorf("Unable to lock audit log: %s", err.Error())
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("Unable to lock audit log: %s", err.Error())
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+	common.SetPermissions(path+".lock", 0664)
+
+	if err := rotateAuditLog(path, int64(len(line))); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("Unable to open audit log: %s", err.Error())
+	}
+	defer file.Close()
+	common.SetPermissions(path, 0640)
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("Unable to write audit log: %s", err.Error())
+	}
+
+	if common.PropertyGetDefault("events", globalAppName, "audit-syslog", DefaultProperties["audit-syslog"]) == "true" {
+		writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTHPRIV, "dokku-audit")
+		if err != nil {
+			return fmt.Errorf("Unable to connect to syslog: %s", err.Error())
+		}
+		defer writer.Close()
+		return writer.Info(strings.TrimSpace(string(line)))
+	}
+	return nil
+}
+
+// getAuthUserName returns the name of the user an ssh key is mapped to by the auth plugin
+func getAuthUserName(fingerprint string, sshName string) string {
+	b, err := common.PlugnTriggerOutput("auth-get-user-name", fingerprint, sshName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func getAuditMaxFiles() int {
+	value := common.PropertyGetDefault("events", globalAppName, "audit-max-files", DefaultProperties["audit-max-files"])
+	maxFiles, err := strconv.Atoi(value)
+	if err != nil || maxFiles < 0 {
+		return DefaultAuditMaxFiles
+	}
+	return maxFiles
+}
+
+func getAuditMaxSize() int64 {
+	value := common.PropertyGetDefault("events", globalAppName, "audit-max-size", DefaultProperties["audit-max-size"])
+	maxSize, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || maxSize <= 0 {
+		return DefaultAuditMaxSize
+	}
+	return maxSize
+}
+
+// rotateAuditLog shifts path to path.1, path.1 to path.2 and so on if appending size bytes would exceed the maximum size
+func rotateAuditLog(path string, size int64) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.Size()+size <= getAuditMaxSize()) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read audit log: %s", err.Error())
+	}
+
+	maxFiles := getAuditMaxFiles()
+	if maxFiles == 0 {
+		return os.Remove(path)
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("Unable to rotate audit log: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestEventsNewAuditRecord(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	Expect(os.MkdirAll(filepath.Join(os.Getenv("DOKKU_ROOT"), "node-js-app"), 0755)).To(Succeed())
+	roots.WriteStub(t, "plugn", "#!/usr/bin/env bash\n[[ \"$2 $3\" == \"auth-get-user-name SHA256:alice\" ]] && echo alice\nexit 0\n")
+
+	startedAt := time.Now().Add(-1500 * time.Millisecond)
+	record := NewAuditRecord("dokku", "alice-laptop", "SHA256:unmapped", "", []string{"config:set", "node-js-app", "SECRET=value"}, 0, startedAt)
+	Expect(record.User).To(Equal("alice-laptop"))
+	Expect(record.App).To(Equal("node-js-app"))
+	Expect(record.Command).To(Equal("config:set"))
+	Expect(record.Args).To(Equal([]string{"node-js-app", "SECRET=********"}))
+	Expect(record.DurationMs).To(BeNumerically(">=", 1500))
+
+	record = NewAuditRecord("dokku", "alice-laptop", "SHA256:alice", "", []string{"domains:add-global", "example.com"}, 1, startedAt)
+	Expect(record.User).To(Equal("alice"))
+	Expect(record.App).To(BeEmpty())
+	Expect(record.ExitCode).To(Equal(1))
+
+	record = NewAuditRecord("root", "default", "", "", []string{"apps:destroy", "removed-app"}, 0, startedAt)
+	Expect(record.User).To(Equal("root"))
+	Expect(record.App).To(Equal("removed-app"))
+
+	record = NewAuditRecord("dokku", "admin", "", "", []string{"git-receive-pack", "'node-js-app'"}, 0, startedAt)
+	Expect(record.App).To(Equal("node-js-app"))
+}
+
+func TestEventsRecordCommand(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	now := time.Now()
+	records := []AuditRecord{
+		{Time: now.Add(-48 * time.Hour), User: "alice", App: "api", Command: "config:set", Args: []string{}},
+		{Time: now.Add(-time.Hour), User: "bob", App: "web", Command: "ps:restart", Args: []string{}, ExitCode: 1},
+		{Time: now, User: "alice", App: "web", Command: "config:unset", Args: []string{}},
+	}
+	for _, record := range records {
+		Expect(RecordCommand(record)).To(Succeed())
+	}
+
+	result, err := ReadAuditRecords(AuditQuery{})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(result).To(HaveLen(3))
+	Expect(result[0].Command).To(Equal("config:set"))
+
+	result, _ = ReadAuditRecords(AuditQuery{User: "alice"})
+	Expect(result).To(HaveLen(2))
+	result, _ = ReadAuditRecords(AuditQuery{App: "web", Command: "config"})
+	Expect(result).To(HaveLen(1))
+	result, _ = ReadAuditRecords(AuditQuery{Since: now.Add(-24 * time.Hour)})
+	Expect(result).To(HaveLen(2))
+	result, _ = ReadAuditRecords(AuditQuery{Failed: true})
+	Expect(result).To(HaveLen(1))
+	Expect(result[0].User).To(Equal("bob"))
+	result, _ = ReadAuditRecords(AuditQuery{Limit: 1})
+	Expect(result).To(HaveLen(1))
+	Expect(result[0].Command).To(Equal("config:unset"))
+}
+
+func TestEventsRotateAuditLog(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(CommandSet("audit-max-size", "200")).To(Succeed())
+	Expect(CommandSet("audit-max-files", "2")).To(Succeed())
+	Expect(CommandSet("audit-max-files", "many")).NotTo(Succeed())
+	Expect(CommandSet("audit-syslog", "yes")).NotTo(Succeed())
+	Expect(CommandSet("unknown", "true")).NotTo(Succeed())
+
+	for i := 0; i < 10; i++ {
+		Expect(RecordCommand(AuditRecord{Time: time.Now(), User: "alice", Command: "ps:report", Args: []string{}})).To(Succeed())
+	}
+
+	path := GetAuditLogPath()
+	Expect(path).To(BeAnExistingFile())
+	Expect(path + ".1").To(BeAnExistingFile())
+	Expect(path + ".2").To(BeAnExistingFile())
+	Expect(path + ".3").NotTo(BeAnExistingFile())
+
+	info, err := os.Stat(path)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(info.Size()).To(BeNumerically("<=", 200))
+
+	result, err := ReadAuditRecords(AuditQuery{})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(len(result)).To(BeNumerically("<", 10))
+
+	Expect(CommandSet("audit-max-size", "")).To(Succeed())
+	Expect(getAuditMaxSize()).To(Equal(int64(DefaultAuditMaxSize)))
+}
+
+func TestEventsParseSince(t *testing.T) {
+	RegisterTestingT(t)
+
+	since, err := ParseSince("7d")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(since).To(BeTemporally("~", time.Now().AddDate(0, 0, -7), time.Minute))
+
+	since, err = ParseSince("2h")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(since).To(BeTemporally("~", time.Now().Add(-2*time.Hour), time.Minute))
+
+	since, err = ParseSince("2020-01-02")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(since).To(Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)))
+
+	_, err = ParseSince("yesterday")
+	Expect(err).To(HaveOccurred())
+}
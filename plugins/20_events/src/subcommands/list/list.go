@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	events "github.com/dokku/dokku/plugins/20_events"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// lists logged events or queries the audit log
+func main() {
+	args := flag.NewFlagSet("events:list", flag.ExitOnError)
+	audit := args.Bool("audit", false, "--audit: query the audit log of dokku commands")
+	app := args.String("app", "", "app: only show commands run against an app")
+	user := args.String("user", "", "user: only show commands run by a user")
+	command := args.String("command", "", "command: only show a command or every command of a plugin")
+	since := args.String("since", "", "since: only show commands run after a date, timestamp or duration ago")
+	failed := args.Bool("failed", false, "--failed: only show commands that exited with a non-zero code")
+	limit := args.Int("limit", 100, "limit: the maximum number of commands to show")
+	format := args.String("format", "stdout", "format: [ stdout | json ] which format to output audit records as")
+	args.Parse(os.Args[2:])
+
+	sinceTime, err := events.ParseSince(*since)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+
+	query := events.AuditQuery{
+		App:     *app,
+		User:    *user,
+		Command: *command,
+		Since:   sinceTime,
+		Failed:  *failed,
+		Limit:   *limit,
+	}
+
+	if !*audit {
+		args.Visit(func(f *flag.Flag) {
+			if f.Name != "format" {
+				common.LogFail(fmt.Sprintf("--%s may only be used with --audit", f.Name))
+			}
+		})
+	}
+
+	if err := events.CommandList(*audit, query, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
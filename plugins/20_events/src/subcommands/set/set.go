@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	events "github.com/dokku/dokku/plugins/20_events"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// set or clear an audit log property
+func main() {
+	flag.Parse()
+	property := flag.Arg(1)
+	value := flag.Arg(2)
+
+	if err := events.CommandSet(property, value); err != nil {
+		common.LogFail(err.Error())
+	}
+}
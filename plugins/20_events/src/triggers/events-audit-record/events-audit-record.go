@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"time"
+
+	events "github.com/dokku/dokku/plugins/20_events"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// records a finished dokku command in the audit log
+func main() {
+	flag.Parse()
+	exitCode, err := strconv.Atoi(flag.Arg(0))
+	if err != nil {
+		exitCode = -1
+	}
+
+	startedAt := time.Now()
+	if nanoseconds, err := strconv.ParseInt(flag.Arg(1), 10, 64); err == nil {
+		startedAt = time.Unix(0, nanoseconds)
+	}
+
+	args := []string{}
+	if flag.NArg() > 2 {
+		args = flag.Args()[2:]
+	}
+
+	record := events.NewAuditRecord(os.Getenv("SSH_USER"), os.Getenv("SSH_NAME"), os.Getenv("FINGERPRINT"), os.Getenv("DOKKU_APP_NAME"), args, exitCode, startedAt)
+	if err := events.RecordCommand(record); err != nil {
+		common.LogWarn(err.Error())
+	}
+}
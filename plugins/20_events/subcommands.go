@@ -0,0 +1,133 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandList implements events:list
+func CommandList(audit bool, query AuditQuery, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	if !audit {
+		return listLoggedEvents()
+	}
+
+	records, err := ReadAuditRecords(query)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	common.LogInfo2Quiet("Audit log")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tAPP\tEXIT\tDURATION\tCOMMAND")
+	for _, record := range records {
+		duration := time.Duration(record.DurationMs) * time.Millisecond
+		command := strings.TrimSpace(record.Command + " " + strings.Join(record.Args, " "))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", record.Time.Format(time.RFC3339), record.User, record.App, record.ExitCode, duration, command)
+	}
+	return w.Flush()
+}
+
+// CommandSet implements events:set
+func CommandSet(property string, value string) error {
+	if property == "" {
+		return errors.New("No property specified")
+	}
+	if _, ok := DefaultProperties[property]; !ok {
+		validProperties := []string{}
+		for key := range DefaultProperties {
+			validProperties = append(validProperties, key)
+		}
+		sort.Strings(validProperties)
+		return fmt.Errorf("Invalid property specified, valid properties include: %s", strings.Join(validProperties, ", "))
+	}
+
+	if value == "" {
+		common.LogInfo2Quiet(fmt.Sprintf("Unsetting %s", property))
+		if !common.PropertyExists("events", globalAppName, property) {
+			return nil
+		}
+		return common.PropertyDelete("events", globalAppName, property)
+	}
+
+	switch property {
+	case "audit-syslog":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("Invalid value for %s, valid values include: true, false", property)
+		}
+	case "audit-max-files", "audit-max-size":
+		if number, err := strconv.ParseInt(value, 10, 64); err != nil || number < 0 || (property == "audit-max-size" && number == 0) {
+			return fmt.Errorf("Invalid value for %s, the value must be a positive integer", property)
+		}
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("Setting %s to %s", property, value))
+	return common.PropertyWrite("events", globalAppName, property, value)
+}
+
+// ParseSince parses a --since value given as a duration ago, such as 24h, or as a date or timestamp
+func ParseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days >= 0 {
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return time.Now().Add(-duration), nil
+	}
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	if since, err := time.Parse("2006-01-02", value); err == nil {
+		return since, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid --since value %s, specify a duration (24h, 7d), a date (2006-01-02) or a timestamp (2006-01-02T15:04:05Z)", value)
+}
+
+// listLoggedEvents lists the plugin triggers recorded by the events logger
+func listLoggedEvents() error {
+	if os.Getenv("DOKKU_EVENTS") == "" {
+		common.LogWarn("Events logger disabled")
+		return nil
+	}
+
+	pluginPath := filepath.Join(common.MustGetEnv("PLUGIN_ENABLED_PATH"), "20_events")
+	files, err := ioutil.ReadDir(pluginPath)
+	if err != nil {
+		return fmt.Errorf("Unable to read events plugin: %s", err.Error())
+	}
+
+	common.LogInfo2Quiet("Events currently logged")
+	for _, file := range files {
+		if file.Mode()&os.ModeSymlink != 0 {
+			fmt.Println(file.Name())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package appjson
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+var (
+	cronMacros         = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+	cronFieldRegex     = regexp.MustCompile(`^[0-9A-Za-z*/,\-]+$`)
+	envKeyRegex        = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	healthcheckTypes   = []string{"liveness", "readiness", "startup"}
+	processTypeRegex   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	validEnvGenerators = []string{"secret"}
+)
+
+// AppJSON is the app.json manifest of an app
+type AppJSON struct {
+	Name         string                   `json:"name,omitempty"`
+	Description  string                   `json:"description,omitempty"`
+	Scripts      Scripts                  `json:"scripts,omitempty"`
+	Env          map[string]EnvVar        `json:"env,omitempty"`
+	Formation    map[string]Formation     `json:"formation,omitempty"`
+	Healthchecks map[string][]Healthcheck `json:"healthchecks,omitempty"`
+	Cron         []CronTask               `json:"cron,omitempty"`
+	Buildpacks   []Buildpack              `json:"buildpacks,omitempty"`
+}
+
+// Scripts contains the commands run during a deploy
+type Scripts struct {
+	Dokku DokkuScripts `json:"dokku,omitempty"`
+}
+
+// DokkuScripts are the dokku specific deployment tasks
+type DokkuScripts struct {
+	Predeploy  string `json:"predeploy,omitempty"`
+	Postdeploy string `json:"postdeploy,omitempty"`
+}
+
+// EnvVar is an environment variable declared by an app. It may be specified as a
+// plain string, which is used as its value
+type EnvVar struct {
+	Description string `json:"description,omitempty"`
+	Value       string `json:"value,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Generator   string `json:"generator,omitempty"`
+}
+
+// Formation is the initial scale of a process type. A missing quantity leaves the scale unchanged
+type Formation struct {
+	Quantity *int   `json:"quantity,omitempty"`
+	Size     string `json:"size,omitempty"`
+}
+
+// Healthcheck is a check run against a process type
+type Healthcheck struct {
+	Type         string   `json:"type,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	Command      []string `json:"command,omitempty"`
+	Port         int      `json:"port,omitempty"`
+	Content      string   `json:"content,omitempty"`
+	Attempts     int      `json:"attempts,omitempty"`
+	Timeout      int      `json:"timeout,omitempty"`
+	Wait         int      `json:"wait,omitempty"`
+	InitialDelay int      `json:"initialDelay,omitempty"`
+}
+
+// CronTask is a command run on a schedule
+type CronTask struct {
+	Command  string `json:"command"`
+	Schedule string `json:"schedule"`
+}
+
+// Buildpack is a buildpack used to build an app
+type Buildpack struct {
+	URL string `json:"url"`
+}
+
+// UnmarshalJSON allows an env var to be specified as a plain string value
+func (e *EnvVar) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*e = EnvVar{Value: value}
+		return nil
+	}
+
+	type envVar EnvVar
+	var v envVar
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.New("env values must be a string or an object")
+	}
+	*e = EnvVar(v)
+	return nil
+}
+
+// GetAppJSON extracts and parses the app.json file of an image. An image without an
+// app.json file results in an empty manifest
+func GetAppJSON(image string) (AppJSON, error) {
+	tmpWorkDir, err := ioutil.TempDir("", "dokku_app_json")
+	if err != nil {
+		return AppJSON{}, err
+	}
+	defer os.RemoveAll(tmpWorkDir)
+
+	appJSONPath := filepath.Join(tmpWorkDir, "app.json")
+	if err := common.CopyFromImage(image, "app.json", appJSONPath); err != nil || !common.FileExists(appJSONPath) {
+		return AppJSON{}, nil
+	}
+	return ReadAppJSON(appJSONPath)
+}
+
+// ParseAppJSON parses the contents of an app.json file
+func ParseAppJSON(data []byte) (AppJSON, error) {
+	var appJSON AppJSON
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return appJSON, nil
+	}
+	if err := json.Unmarshal(data, &appJSON); err != nil {
+		return appJSON, fmt.Errorf("Invalid app.json: %s", err.Error())
+	}
+	return appJSON, nil
+}
+
+// ReadAppJSON reads and parses an app.json file
+func ReadAppJSON(filename string) (AppJSON, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return AppJSON{}, err
+	}
+	return ParseAppJSON(b)
+}
+
+// GenerateEnv returns generated values for the env vars with a generator that are not set in the merged app config
+func (a AppJSON) GenerateEnv(configured map[string]string) (map[string]string, error) {
+	generated := map[string]string{}
+	for _, key := range a.EnvKeys() {
+		env := a.Env[key]
+		if env.Generator == "" || configured[key] != "" {
+			continue
+		}
+
+		switch env.Generator {
+		case "secret":
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				return generated, err
+			}
+			generated[key] = hex.EncodeToString(b)
+		default:
+			return generated, fmt.Errorf("env.%s.generator must be one of: %s", key, strings.Join(validEnvGenerators, ", "))
+		}
+	}
+	return generated, nil
+}
+
+// MissingEnv returns the required env vars that have no value in app.json or the merged app config
+func (a AppJSON) MissingEnv(configured map[string]string) []string {
+	missing := []string{}
+	for _, key := range a.EnvKeys() {
+		env := a.Env[key]
+		if env.Required && env.Value == "" && configured[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// EnvKeys returns the names of the declared env vars, sorted
+func (a AppJSON) EnvKeys() []string {
+	keys := []string{}
+	for key := range a.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ProcessTypes returns the process types with a declared formation, sorted
+func (a AppJSON) ProcessTypes() []string {
+	processTypes := []string{}
+	for processType := range a.Formation {
+		processTypes = append(processTypes, processType)
+	}
+	sort.Strings(processTypes)
+	return processTypes
+}
+
+// Validate returns an error describing every invalid value in the manifest
+func (a AppJSON) Validate() error {
+	problems := []string{}
+	for _, key := range a.EnvKeys() {
+		if !envKeyRegex.MatchString(key) {
+			problems = append(problems, fmt.Sprintf("env.%s is not a valid environment variable name", key))
+		}
+		if generator := a.Env[key].Generator; generator != "" && !contains(validEnvGenerators, generator) {
+			problems = append(problems, fmt.Sprintf("env.%s.generator must be one of: %s", key, strings.Join(validEnvGenerators, ", ")))
+		}
+	}
+
+	for _, processType := range a.ProcessTypes() {
+		formation := a.Formation[processType]
+		if !processTypeRegex.MatchString(processType) {
+			problems = append(problems, fmt.Sprintf("formation.%s is not a valid process type", processType))
+		}
+		if formation.Quantity != nil && *formation.Quantity < 0 {
+			problems = append(problems, fmt.Sprintf("formation.%s.quantity must be at least 0", processType))
+		}
+	}
+
+	processTypes := []string{}
+	for processType := range a.Healthchecks {
+		processTypes = append(processTypes, processType)
+	}
+	sort.Strings(processTypes)
+	for _, processType := range processTypes {
+		for i, healthcheck := range a.Healthchecks[processType] {
+			prefix := fmt.Sprintf("healthchecks.%s[%d]", processType, i)
+			if healthcheck.Type != "" && !contains(healthcheckTypes, healthcheck.Type) {
+				problems = append(problems, fmt.Sprintf("%s.type must be one of: %s", prefix, strings.Join(healthcheckTypes, ", ")))
+			}
+			if healthcheck.Path == "" && len(healthcheck.Command) == 0 {
+				problems = append(problems, fmt.Sprintf("%s must specify a path or a command", prefix))
+			}
+			if healthcheck.Path != "" && len(healthcheck.Command) > 0 {
+				problems = append(problems, fmt.Sprintf("%s must not specify both a path and a command", prefix))
+			}
+			if healthcheck.Path != "" && !strings.HasPrefix(healthcheck.Path, "/") {
+				problems = append(problems, fmt.Sprintf("%s.path must begin with /", prefix))
+			}
+			if healthcheck.Port < 0 || healthcheck.Port > 65535 {
+				problems = append(problems, fmt.Sprintf("%s.port must be between 0 and 65535", prefix))
+			}
+			if healthcheck.Attempts < 0 || healthcheck.Timeout < 0 || healthcheck.Wait < 0 || healthcheck.InitialDelay < 0 {
+				problems = append(problems, fmt.Sprintf("%s must not have negative attempts, timeout, wait or initialDelay", prefix))
+			}
+		}
+	}
+
+	for i, task := range a.Cron {
+		if strings.TrimSpace(task.Command) == "" {
+			problems = append(problems, fmt.Sprintf("cron[%d].command must be specified", i))
+		}
+		if err := ValidateCronSchedule(task.Schedule); err != nil {
+			problems = append(problems, fmt.Sprintf("cron[%d].schedule %s", i, err.Error()))
+		}
+	}
+
+	for i, buildpack := range a.Buildpacks {
+		if strings.TrimSpace(buildpack.URL) == "" {
+			problems = append(problems, fmt.Sprintf("buildpacks[%d].url must be specified", i))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid app.json:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// ValidateCronSchedule checks that a schedule is a five field cron expression or a supported macro
+func ValidateCronSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+	if schedule == "" {
+		return errors.New("must be specified")
+	}
+	if strings.HasPrefix(schedule, "@") {
+		if !contains(cronMacros, schedule) {
+			return fmt.Errorf("must be one of: %s", strings.Join(cronMacros, ", "))
+		}
+		return nil
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return errors.New("must have five fields: minute hour day-of-month month day-of-week")
+	}
+	for _, field := range fields {
+		if !cronFieldRegex.MatchString(field) {
+			return fmt.Errorf("has an invalid field %s", field)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package appjson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+const testAppJSON = `{
+  "name": "node-js-app",
+  "scripts": {
+    "dokku": {
+      "predeploy": "npm run migrate",
+      "postdeploy": "curl https://example.com/deployed"
+    },
+    "postdeploy": "ignored by dokku"
+  },
+  "env": {
+    "NODE_ENV": "production",
+    "SECRET_KEY": {"description": "signs sessions", "generator": "secret"},
+    "DATABASE_URL": {"required": true}
+  },
+  "formation": {
+    "web": {"quantity": 2, "size": "standard-1x"},
+    "worker": {"quantity": 1}
+  },
+  "healthchecks": {
+    "web": [{"type": "startup", "path": "/health", "attempts": 3}]
+  },
+  "cron": [{"command": "node tasks/cleanup.js", "schedule": "@daily"}],
+  "buildpacks": [{"url": "heroku/nodejs"}]
+}`
+
+func TestAppJSONParse(t *testing.T) {
+	RegisterTestingT(t)
+
+	appJSON, err := ParseAppJSON([]byte(testAppJSON))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(appJSON.Validate()).To(Succeed())
+	Expect(appJSON.Scripts.Dokku.Predeploy).To(Equal("npm run migrate"))
+	Expect(appJSON.Scripts.Dokku.Postdeploy).To(Equal("curl https://example.com/deployed"))
+	Expect(appJSON.Env["NODE_ENV"]).To(Equal(EnvVar{Value: "production"}))
+	Expect(appJSON.Env["SECRET_KEY"].Generator).To(Equal("secret"))
+	Expect(appJSON.ProcessTypes()).To(Equal([]string{"web", "worker"}))
+	Expect(*appJSON.Formation["web"].Quantity).To(Equal(2))
+	Expect(appJSON.Healthchecks["web"][0].Path).To(Equal("/health"))
+	Expect(appJSON.Cron).To(Equal([]CronTask{{Command: "node tasks/cleanup.js", Schedule: "@daily"}}))
+	Expect(appJSON.Buildpacks).To(Equal([]Buildpack{{URL: "heroku/nodejs"}}))
+
+	Expect(appJSON.MissingEnv(map[string]string{})).To(Equal([]string{"DATABASE_URL"}))
+	Expect(appJSON.MissingEnv(map[string]string{"DATABASE_URL": "postgres://"})).To(BeEmpty())
+
+	appJSON, err = ParseAppJSON([]byte(""))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(appJSON.Validate()).To(Succeed())
+
+	_, err = ParseAppJSON([]byte(`{"scripts": {"dokku": {"predeploy": ["not", "a", "string"]}}}`))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseAppJSON([]byte(`{"env": {"PORT": 5000}}`))
+	Expect(err).To(HaveOccurred())
+}
+
+func TestAppJSONValidate(t *testing.T) {
+	RegisterTestingT(t)
+
+	appJSON, err := ParseAppJSON([]byte(`{
+  "env": {"1KEY": "value", "TOKEN": {"generator": "echo 5"}},
+  "formation": {"web": {"quantity": -1}, "web.1": {"quantity": 1}, "worker": {"size": "free"}},
+  "healthchecks": {"web": [{"type": "ready", "path": "health"}, {"port": 70000}]},
+  "cron": [{"command": "", "schedule": "* * *"}, {"command": "true", "schedule": "@often"}],
+  "buildpacks": [{"url": ""}]
+}`))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(appJSON.Validate()).To(MatchError(`Invalid app.json:
+env.1KEY is not a valid environment variable name
+env.TOKEN.generator must be one of: secret
+formation.web.quantity must be at least 0
+formation.web.1 is not a valid process type
+healthchecks.web[0].type must be one of: liveness, readiness, startup
+healthchecks.web[0].path must begin with /
+healthchecks.web[1] must specify a path or a command
+healthchecks.web[1].port must be between 0 and 65535
+cron[0].command must be specified
+cron[0].schedule must have five fields: minute hour day-of-month month day-of-week
+cron[1].schedule must be one of: @yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly
+buildpacks[0].url must be specified`))
+}
+
+func TestAppJSONGenerateEnv(t *testing.T) {
+	RegisterTestingT(t)
+
+	appJSON, err := ParseAppJSON([]byte(`{"env": {
+  "SECRET_KEY": {"required": true, "generator": "secret"},
+  "SESSION_KEY": {"generator": "secret"},
+  "NODE_ENV": "production"
+}}`))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(appJSON.MissingEnv(map[string]string{})).To(Equal([]string{"SECRET_KEY"}))
+
+	generated, err := appJSON.GenerateEnv(map[string]string{"SESSION_KEY": "configured"})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(generated).To(HaveLen(1))
+	Expect(generated["SECRET_KEY"]).To(MatchRegexp("^[0-9a-f]{64}$"))
+	Expect(appJSON.MissingEnv(generated)).To(BeEmpty())
+
+	again, err := appJSON.GenerateEnv(map[string]string{})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(again["SECRET_KEY"]).NotTo(Equal(generated["SECRET_KEY"]))
+
+	generated, err = appJSON.GenerateEnv(map[string]string{"SECRET_KEY": "configured", "SESSION_KEY": "configured"})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(generated).To(BeEmpty())
+
+	appJSON.Env["TOKEN"] = EnvVar{Generator: "echo 5"}
+	_, err = appJSON.GenerateEnv(map[string]string{})
+	Expect(err).To(MatchError("env.TOKEN.generator must be one of: secret"))
+}
+
+func TestAppJSONValidateCronSchedule(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(ValidateCronSchedule("*/5 * * * *")).To(Succeed())
+	Expect(ValidateCronSchedule("0 3 * * mon-fri")).To(Succeed())
+	Expect(ValidateCronSchedule("@hourly")).To(Succeed())
+	Expect(ValidateCronSchedule("")).NotTo(Succeed())
+	Expect(ValidateCronSchedule("* * * * * *")).NotTo(Succeed())
+	Expect(ValidateCronSchedule("* * * * ?")).NotTo(Succeed())
+}
+
+func TestAppJSONShellSplit(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(shellSplit(` --env=FOO=bar  -v "/var/lib/app data:/data" --label='a b' --name\ x `)).To(Equal([]string{"--env=FOO=bar", "-v", "/var/lib/app data:/data", "--label=a b", "--name x"}))
+	Expect(shellSplit(`--label=""`)).To(Equal([]string{"--label="}))
+	Expect(shellSplit("")).To(BeEmpty())
+	_, err := shellSplit(`--label="unterminated`)
+	Expect(err).To(HaveOccurred())
+
+	Expect(restartArgRegex.ReplaceAllString("--restart=on-failure:10 --cpus=1", "")).To(Equal("--cpus=1"))
+	Expect(scriptCommand("/app/bin/migrate --all")).To(ContainSubstring(`if [[ ! -x "/app/bin/migrate" ]]`))
+	Expect(scriptCommand("npm run migrate")).NotTo(ContainSubstring("not executable"))
+}
+
+func TestAppJSONWriteFormation(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	roots.CreateApps(t, "node-js-app")
+	dokkuRoot := roots.DokkuRoot
+
+	procfilePath := filepath.Join(dokkuRoot, "Procfile")
+	Expect(ioutil.WriteFile(procfilePath, []byte("# processes\nworker: node worker.js\nweb: node web.js\nrelease: npm run migrate\n"), 0644)).To(Succeed())
+	Expect(getProcfileProcessTypes(procfilePath)).To(Equal([]string{"release", "web", "worker"}))
+
+	appJSON, err := ParseAppJSON([]byte(`{"formation": {"worker": {"quantity": 2}, "web": {"size": "free"}}}`))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(writeFormation("node-js-app", appJSON, procfilePath)).To(Succeed())
+	scaleFile := filepath.Join(dokkuRoot, "node-js-app", "DOKKU_SCALE")
+	b, err := ioutil.ReadFile(scaleFile)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("release=0\nweb=1\nworker=2\n"))
+
+	Expect(ioutil.WriteFile(scaleFile, []byte("web=3\n"), 0644)).To(Succeed())
+	Expect(writeFormation("node-js-app", appJSON, procfilePath)).To(Succeed())
+	b, err = ioutil.ReadFile(scaleFile)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("web=3\n"))
+
+	Expect(os.Remove(scaleFile)).To(Succeed())
+	appJSON, err = ParseAppJSON([]byte(`{"formation": {"clock": {"quantity": 1}}}`))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(writeFormation("node-js-app", appJSON, procfilePath)).To(MatchError(ContainSubstring("formation.clock is not a process type declared in the Procfile")))
+	Expect(writeFormation("node-js-app", appJSON, "")).To(MatchError(ContainSubstring("formation.clock")))
+
+	appJSON, err = ParseAppJSON([]byte(`{"formation": {"web": {"quantity": 0}}}`))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(writeFormation("node-js-app", appJSON, "")).To(Succeed())
+	b, err = ioutil.ReadFile(scaleFile)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("web=0\n"))
+}
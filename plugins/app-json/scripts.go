@@ -0,0 +1,339 @@
+package appjson
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+)
+
+var (
+	restartArgRegex = regexp.MustCompile(`--restart=[[:graph:]]+[[:blank:]]?`)
+)
+
+// PreDeploy validates the app.json of the image being deployed, applies its formation
+// and runs the predeploy and release commands
+func PreDeploy(appName string, imageTag string) error {
+	image := common.GetDeployingAppImageName(appName, imageTag, "")
+	appJSON, err := GetAppJSON(image)
+	if err != nil {
+		return err
+	}
+	if err := appJSON.Validate(); err != nil {
+		return err
+	}
+
+	env, err := config.LoadMergedAppEnv(appName)
+	if err != nil {
+		return err
+	}
+	configured := env.Map()
+	generated, err := appJSON.GenerateEnv(configured)
+	if err != nil {
+		return err
+	}
+	if len(generated) > 0 {
+		if err := config.SetMany(appName, generated, false); err != nil {
+			return err
+		}
+		for key, value := range generated {
+			configured[key] = value
+		}
+	}
+	if missing := appJSON.MissingEnv(configured); len(missing) > 0 {
+		return fmt.Errorf("Required app.json env vars are not set: %s", strings.Join(missing, ", "))
+	}
+
+	tmpWorkDir, err := ioutil.TempDir("", "dokku_procfile")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpWorkDir)
+	procfilePath := filepath.Join(tmpWorkDir, "Procfile")
+	if err := common.CopyFromImage(image, "Procfile", procfilePath); err != nil {
+		procfilePath = ""
+	}
+
+	if err := writeFormation(appName, appJSON, procfilePath); err != nil {
+		return err
+	}
+	if err := ExecuteScript(appName, imageTag, "predeploy", appJSON.Scripts.Dokku.Predeploy); err != nil {
+		return err
+	}
+	return ExecuteScript(appName, imageTag, "release", getReleaseCommand(procfilePath))
+}
+
+// PostDeploy runs the postdeploy command of the deployed image
+func PostDeploy(appName string, imageTag string) error {
+	image := common.GetDeployingAppImageName(appName, imageTag, "")
+	appJSON, err := GetAppJSON(image)
+	if err != nil {
+		return err
+	}
+	return ExecuteScript(appName, imageTag, "postdeploy", appJSON.Scripts.Dokku.Postdeploy)
+}
+
+// ExecuteScript runs a deployment task in a container created from the image being deployed.
+// Changes made by the predeploy task are committed to the image
+func ExecuteScript(appName string, imageTag string, phase string, command string) error {
+	if command == "" {
+		return nil
+	}
+
+	image := common.GetDeployingAppImageName(appName, imageTag, "")
+	common.LogExclaim(fmt.Sprintf("%s command declared: '%s'", common.UcFirst(phase), command))
+
+	cacheDir := filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "cache")
+	cacheHostDir := filepath.Join(common.MustGetEnv("DOKKU_HOST_ROOT"), appName, "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+
+	b, err := common.PlugnTriggerOutput("docker-args-deploy", appName, imageTag)
+	if err != nil {
+		return err
+	}
+	dockerArgs := restartArgRegex.ReplaceAllString(string(b), "")
+
+	imageSourceType := "dockerfile"
+	if common.IsImageHerokuishBased(image) {
+		imageSourceType = "herokuish"
+	}
+	b, err = common.PlugnTriggerOutput("docker-args-process-deploy", appName, imageTag, imageSourceType, phase)
+	if err != nil {
+		return err
+	}
+	dockerArgs += string(b)
+
+	extraArgs, err := shellSplit(dockerArgs)
+	if err != nil {
+		return fmt.Errorf("Unable to parse docker args: %s", err.Error())
+	}
+
+	appShell := config.GetWithDefault(appName, "DOKKU_APP_SHELL", config.GetWithDefault("", "DOKKU_APP_SHELL", "/bin/bash"))
+	if appShell == "" {
+		appShell = "/bin/bash"
+	}
+
+	args := []string{"run"}
+	if globalArgs := os.Getenv("DOKKU_GLOBAL_RUN_ARGS"); globalArgs != "" {
+		args = append(args, globalArgs)
+	}
+	args = append(args, "-e", "DOKKU_TRACE="+os.Getenv("DOKKU_TRACE"), "--label=dokku_phase_script="+phase, "-d", "-v", cacheHostDir+":/cache")
+	args = append(args, extraArgs...)
+	args = append(args, image, appShell, "-c", scriptCommand(command))
+
+	b, err = exec.Command("docker", args...).Output()
+	if err != nil {
+		return fmt.Errorf("Unable to start %s container: %s", phase, err.Error())
+	}
+	containerID := strings.TrimSpace(string(b))
+
+	b, err = exec.Command("docker", "wait", containerID).Output()
+	if err != nil {
+		return err
+	}
+	logContainerOutput(containerID)
+	if status, err := strconv.Atoi(strings.TrimSpace(string(b))); err != nil || status != 0 {
+		return fmt.Errorf("execution of '%s' failed!", command)
+	}
+
+	if phase != "predeploy" {
+		return nil
+	}
+
+	commitArgs := []string{"commit"}
+	if imageSourceType != "herokuish" {
+		// committing the container would otherwise replace the image entrypoint and command
+		entrypoint := config.GetWithDefault(appName, "DOKKU_DOCKERFILE_ENTRYPOINT", "")
+		if entrypoint == "" {
+			value, err := common.DockerInspect(image, "{{range .Config.Entrypoint}}{{.}} {{end}}")
+			if err != nil {
+				return err
+			}
+			if value != "" {
+				entrypoint = "ENTRYPOINT " + value
+			}
+		}
+		cmd := config.GetWithDefault(appName, "DOKKU_DOCKERFILE_CMD", "")
+		if cmd == "" {
+			value, err := common.DockerInspect(image, "{{range .Config.Cmd}}{{.}} {{end}}")
+			if err != nil {
+				return err
+			}
+			if value = strings.TrimSpace(strings.Replace(value, "/bin/sh -c", "", 1)); value != "" {
+				cmd = "CMD " + value
+			}
+		}
+		for _, change := range []string{entrypoint, cmd} {
+			if change != "" {
+				commitArgs = append(commitArgs, "--change", change)
+			}
+		}
+	}
+	commitArgs = append(commitArgs, containerID, image)
+	if out, err := exec.Command("docker", commitArgs...).CombinedOutput(); err != nil {
+		return fmt.Errorf("Unable to commit %s changes to %s: %s", phase, image, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// getReleaseCommand returns the release command declared in a Procfile
+func getReleaseCommand(procfilePath string) string {
+	if procfilePath == "" {
+		return ""
+	}
+	b, err := exec.Command("procfile-util", "show", "--procfile", procfilePath, "--process-type", "release", "--default-port", "5000").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// getProcfileProcessTypes returns the process types declared in a Procfile
+func getProcfileProcessTypes(procfilePath string) ([]string, error) {
+	lines, err := common.FileToSlice(procfilePath)
+	if err != nil {
+		return []string{}, err
+	}
+
+	processTypes := []string{}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || !strings.Contains(line, ":") {
+			continue
+		}
+		processType := strings.TrimSpace(strings.SplitN(line, ":", 2)[0])
+		if processType != "" && !seen[processType] {
+			seen[processType] = true
+			processTypes = append(processTypes, processType)
+		}
+	}
+	sort.Strings(processTypes)
+	return processTypes, nil
+}
+
+func logContainerOutput(containerID string) {
+	cmd := exec.Command("docker", "logs", containerID)
+	b, _ := cmd.CombinedOutput()
+	scanner := bufio.NewScanner(strings.NewReader(string(b)))
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			common.LogVerboseQuiet(line)
+		}
+	}
+}
+
+// scriptCommand wraps a deployment task so that it runs from the app directory with
+// the app profile loaded and the build cache available
+func scriptCommand(command string) string {
+	script := []string{
+		"set -eo pipefail; [[ $DOKKU_TRACE ]] && set -x ;",
+		"if [[ -d '/app' ]]; then export HOME=/app ; cd $HOME ; fi ;",
+		"if [[ -d '/app/.profile.d' ]]; then for file in /app/.profile.d/*; do source $file; done ; fi ;",
+		"if [[ -d '/cache' ]]; then rm -rf /tmp/cache ; ln -sf /cache /tmp/cache ; fi ;",
+	}
+	if strings.HasPrefix(command, "/") {
+		binary := strings.Fields(command)[0]
+		script = append(script, fmt.Sprintf("if [[ ! -x \"%s\" ]]; then echo specified binary is not executable ; exit 1 ; fi ;", binary))
+	}
+	script = append(script, command+" || exit 1;", "if [[ -d '/cache' ]]; then rm -f /tmp/cache ; fi ;")
+	return strings.Join(script, " ")
+}
+
+// shellSplit splits a string into words using shell quoting rules
+func shellSplit(value string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return words, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// writeFormation generates the scale file of an app from its app.json formation. The scale file
+// is only generated when it does not exist yet, so that later ps:scale calls are preserved
+func writeFormation(appName string, appJSON AppJSON, procfilePath string) error {
+	scaleFile := filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "DOKKU_SCALE")
+	if len(appJSON.Formation) == 0 || common.FileExists(scaleFile) {
+		return nil
+	}
+
+	processTypes := []string{"web"}
+	if procfilePath != "" {
+		var err error
+		if processTypes, err = getProcfileProcessTypes(procfilePath); err != nil {
+			return err
+		}
+	}
+
+	scale := map[string]int{}
+	for _, processType := range processTypes {
+		scale[processType] = 0
+		if processType == "web" {
+			scale[processType] = 1
+		}
+	}
+	for _, processType := range appJSON.ProcessTypes() {
+		if _, ok := scale[processType]; !ok {
+			return fmt.Errorf("Invalid app.json: formation.%s is not a process type declared in the Procfile", processType)
+		}
+		if quantity := appJSON.Formation[processType].Quantity; quantity != nil {
+			scale[processType] = *quantity
+		}
+	}
+
+	lines := []string{}
+	for _, processType := range processTypes {
+		lines = append(lines, fmt.Sprintf("%s=%d", processType, scale[processType]))
+	}
+	common.LogInfo1Quiet("Generating DOKKU_SCALE file from app.json formation")
+	if err := ioutil.WriteFile(scaleFile, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	common.SetPermissions(scaleFile, 0644)
+	return nil
+}
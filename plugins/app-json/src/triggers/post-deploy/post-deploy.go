@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	appjson "github.com/dokku/dokku/plugins/app-json"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the app.json postdeploy command
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	imageTag := flag.Arg(3)
+
+	if err := appjson.PostDeploy(appName, imageTag); err != nil {
+		common.LogFail(err.Error())
+	}
+}
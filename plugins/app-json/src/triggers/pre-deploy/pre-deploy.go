@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	appjson "github.com/dokku/dokku/plugins/app-json"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// validates app.json and runs the predeploy and release commands
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	imageTag := flag.Arg(1)
+
+	if err := appjson.PreDeploy(appName, imageTag); err != nil {
+		common.LogFail(err.Error())
+	}
+}
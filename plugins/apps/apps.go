@@ -0,0 +1,340 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+)
+
+var (
+	appNameRegex = regexp.MustCompile(`^[a-z0-9][^A-Z:]*$`)
+)
+
+// AppExists returns true if an app has been created
+func AppExists(appName string) bool {
+	return common.DirectoryExists(getAppPath(appName))
+}
+
+// CloneApp creates a new app from an existing one, copying its files and the properties of every plugin
+func CloneApp(oldAppName string, newAppName string, skipDeploy bool, ignoreExisting bool) error {
+	if oldAppName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+	if err := IsValidAppName(oldAppName); err != nil {
+		return err
+	}
+	if err := IsValidAppName(newAppName); err != nil {
+		return err
+	}
+	if !AppExists(oldAppName) {
+		return errors.New("App does not exist")
+	}
+	if AppExists(newAppName) {
+		if ignoreExisting {
+			common.LogWarn("Name is already taken")
+			return nil
+		}
+		return errors.New("Name is already taken")
+	}
+
+	if err := CreateApp(newAppName); err != nil {
+		return err
+	}
+	if err := copyAppFiles(oldAppName, newAppName, true); err != nil {
+		return err
+	}
+	if err := cloneProperties(oldAppName, newAppName); err != nil {
+		return err
+	}
+	if err := common.PlugnTrigger("post-app-clone-setup", oldAppName, newAppName); err != nil {
+		return err
+	}
+
+	if err := replaceInAppFile(newAppName, "hooks/pre-receive", "git-hook "+oldAppName, "git-hook "+newAppName); err != nil {
+		return err
+	}
+	if !skipDeploy {
+		if err := common.PlugnTrigger("receive-app", newAppName); err != nil {
+			return err
+		}
+	}
+	if err := common.PlugnTrigger("post-app-clone", oldAppName, newAppName); err != nil {
+		return err
+	}
+
+	common.LogInfo1Quiet(fmt.Sprintf("Cloning %s to %s... done", oldAppName, newAppName))
+	return nil
+}
+
+// CreateApp validates an app name and creates the app
+func CreateApp(appName string) error {
+	if err := IsValidAppName(appName); err != nil {
+		return err
+	}
+	if AppExists(appName) {
+		return errors.New("Name is already taken")
+	}
+
+	appRoot := getAppPath(appName)
+	if err := os.MkdirAll(appRoot, 0755); err != nil {
+		return fmt.Errorf("Unable to create app: %s", err.Error())
+	}
+	common.SetPermissions(appRoot, 0755)
+
+	common.LogInfo1Quiet(fmt.Sprintf("Creating %s... done", appName))
+	return common.PlugnTrigger("post-create", appName)
+}
+
+// IsValidAppName returns an error if an app name does not begin with a lowercase alphanumeric character
+// or contains uppercase characters or colons
+func IsValidAppName(appName string) error {
+	if appName == "" {
+		return errors.New("APP must not be null")
+	}
+	if !appNameRegex.MatchString(appName) {
+		return errors.New("App name must begin with lowercase alphanumeric character")
+	}
+	return nil
+}
+
+// RenameApp moves an app to a new name, along with its files and the properties of every plugin
+func RenameApp(oldAppName string, newAppName string, skipDeploy bool) error {
+	if oldAppName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+	if err := IsValidAppName(newAppName); err != nil {
+		return err
+	}
+	if AppExists(newAppName) {
+		return errors.New("Name is already taken")
+	}
+	if err := common.VerifyAppName(oldAppName); err != nil {
+		return err
+	}
+
+	if err := clearCache(oldAppName); err != nil {
+		return err
+	}
+	if err := CreateApp(newAppName); err != nil {
+		return err
+	}
+	if err := copyAppFiles(oldAppName, newAppName, false); err != nil {
+		return err
+	}
+	if err := renameProperties(oldAppName, newAppName); err != nil {
+		return err
+	}
+	if err := common.PlugnTrigger("post-app-rename-setup", oldAppName, newAppName); err != nil {
+		return err
+	}
+	if err := common.PlugnTrigger("proxy-clear-config", newAppName); err != nil {
+		return err
+	}
+	if err := removeRenamedApp(oldAppName); err != nil {
+		return err
+	}
+
+	for _, filename := range []string{"URLS", "VHOST"} {
+		if err := replaceInAppFile(newAppName, filename, oldAppName, newAppName); err != nil {
+			return err
+		}
+	}
+	if err := replaceInAppFile(newAppName, "hooks/pre-receive", "git-hook "+oldAppName, "git-hook "+newAppName); err != nil {
+		return err
+	}
+	if !skipDeploy {
+		if err := common.PlugnTrigger("receive-app", newAppName); err != nil {
+			return err
+		}
+	}
+	if err := common.PlugnTrigger("post-app-rename", oldAppName, newAppName); err != nil {
+		return err
+	}
+
+	fmt.Printf("Renaming %s to %s... done\n", oldAppName, newAppName)
+	return nil
+}
+
+// clearCache removes the build cache of an app, resetting permissions on files written by the build container
+func clearCache(appName string) error {
+	cacheDir := filepath.Join(getAppPath(appName), "cache")
+	if !common.DirectoryExists(cacheDir) {
+		return nil
+	}
+
+	if err := os.Remove(cacheDir); err != nil {
+		cacheHostDir := strings.Join([]string{common.MustGetEnv("DOKKU_HOST_ROOT"), appName, "cache"}, "/")
+		chmodCmd := common.NewShellCmd(strings.Join([]string{"docker run", os.Getenv("DOKKU_GLOBAL_RUN_ARGS"), "--rm",
+			"-v", cacheHostDir + ":/cache", common.GetAppImageRepo(appName), "chmod 777 -R /cache"}, " "))
+		chmodCmd.ShowOutput = false
+		chmodCmd.Execute()
+	}
+	return os.RemoveAll(cacheDir)
+}
+
+// cloneProperties copies the properties of every plugin from one app to another
+func cloneProperties(oldAppName string, newAppName string) error {
+	pluginNames, err := common.PropertyPlugins(oldAppName)
+	if err != nil {
+		return err
+	}
+	for _, pluginName := range pluginNames {
+		if err := common.PropertyClone(pluginName, oldAppName, newAppName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyAppFiles copies the contents of an app directory to another app, optionally skipping build caches
+func copyAppFiles(oldAppName string, newAppName string, skipCache bool) error {
+	oldAppRoot := getAppPath(oldAppName)
+	newAppRoot := getAppPath(newAppName)
+	return filepath.Walk(oldAppRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(oldAppRoot, path)
+		if err != nil || relativePath == "." {
+			return err
+		}
+		if skipCache && info.IsDir() && (info.Name() == "cache" || info.Name() == ".cache") {
+			return filepath.SkipDir
+		}
+
+		target := filepath.Join(newAppRoot, relativePath)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		default:
+			return copyFile(path, target, info)
+		}
+	})
+}
+
+func copyFile(source string, target string, info os.FileInfo) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+// destroyApp stops and removes an app without confirmation
+func destroyApp(appName string) error {
+	imageTag := getRunningImageTag(appName)
+	fmt.Printf("Destroying %s (including all add-ons)\n", appName)
+	if err := common.PlugnTrigger("pre-delete", appName, imageTag); err != nil {
+		return err
+	}
+
+	scheduler := config.GetWithDefault(appName, "DOKKU_SCHEDULER", config.GetWithDefault("", "DOKKU_SCHEDULER", "docker-local"))
+	if err := common.PlugnTrigger("scheduler-stop", scheduler, appName, "true"); err != nil {
+		return err
+	}
+	return common.PlugnTrigger("post-delete", appName, imageTag)
+}
+
+func getAppPath(appName string) string {
+	return strings.Join([]string{common.MustGetEnv("DOKKU_ROOT"), appName}, "/")
+}
+
+// getRunningImageTag returns the image tag of the first running container for an app, if any
+func getRunningImageTag(appName string) string {
+	containerFiles, _ := filepath.Glob(filepath.Join(getAppPath(appName), "CONTAINER.*"))
+	containerFiles = append([]string{filepath.Join(getAppPath(appName), "CONTAINER")}, containerFiles...)
+	for _, containerFile := range containerFiles {
+		containerID := common.ReadFirstLine(containerFile)
+		if containerID == "" {
+			continue
+		}
+		image, err := common.DockerInspect(containerID, "{{ .Config.Image }}")
+		if err != nil {
+			return ""
+		}
+		if parts := strings.SplitN(image, ":", 2); len(parts) == 2 {
+			return parts[1]
+		}
+		return ""
+	}
+	return ""
+}
+
+// removeRenamedApp stops the containers of an app that was renamed and removes its remaining files
+// state keyed by the old app name is cleaned up by plugins in the post-app-rename trigger
+func removeRenamedApp(appName string) error {
+	scheduler := config.GetWithDefault(appName, "DOKKU_SCHEDULER", config.GetWithDefault("", "DOKKU_SCHEDULER", "docker-local"))
+	if err := common.PlugnTrigger("scheduler-stop", scheduler, appName, "true"); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(deployLockQueue(appName).path); err != nil {
+		return err
+	}
+	return os.RemoveAll(getAppPath(appName))
+}
+
+// renameProperties moves the properties of every plugin from one app to another
+func renameProperties(oldAppName string, newAppName string) error {
+	pluginNames, err := common.PropertyPlugins(oldAppName)
+	if err != nil {
+		return err
+	}
+	for _, pluginName := range pluginNames {
+		if err := common.PropertyRename(pluginName, oldAppName, newAppName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceInAppFile replaces every occurrence of a string in a file within an app directory, if the file exists
+func replaceInAppFile(appName string, filename string, old string, new string) error {
+	path := filepath.Join(getAppPath(appName), filename)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(strings.Replace(string(b), old, new, -1)), info.Mode().Perm())
+}
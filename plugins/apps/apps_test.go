@@ -0,0 +1,221 @@
+package apps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+// triggersLogPath is the file the plugn stub records fired triggers to
+var triggersLogPath string
+
+// setupTestRoots creates dokku directories and
+// puts a plugn stub on the PATH that records every trigger fired
+func setupTestRoots(t *testing.T) func() {
+	roots := testutil.SetupRoots(t)
+	triggersLogPath = roots.StubPlugn(t)
+	return roots.Teardown
+}
+
+// firedTriggers returns the triggers recorded by the plugn stub
+func firedTriggers() []string {
+	b, err := ioutil.ReadFile(triggersLogPath)
+	if os.IsNotExist(err) {
+		return []string{}
+	}
+	Expect(err).NotTo(HaveOccurred())
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func writeAppFile(appName string, filename string, contents string) {
+	path := filepath.Join(os.Getenv("DOKKU_ROOT"), appName, filename)
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+}
+
+func readAppFile(appName string, filename string) string {
+	b, err := ioutil.ReadFile(filepath.Join(os.Getenv("DOKKU_ROOT"), appName, filename))
+	Expect(err).NotTo(HaveOccurred())
+	return string(b)
+}
+
+// setupPluginState writes the app files and plugin properties that should follow an app
+func setupPluginState(appName string) {
+	writeAppFile(appName, "ENV", "export KEY='value'\n")
+	writeAppFile(appName, "VHOST", appName+".dokku.me\n")
+	writeAppFile(appName, "URLS", "http://"+appName+".dokku.me\n")
+	writeAppFile(appName, "hooks/pre-receive", "#!/usr/bin/env bash\ncat | DOKKU_ROOT=\"/home/dokku\" dokku git-hook "+appName+"\n")
+	writeAppFile(appName, "cache/build.tar", "cached")
+
+	Expect(common.PropertyWrite("network", appName, "bind-all-interfaces", "true")).To(Succeed())
+	Expect(common.PropertyWrite("resource", appName, "_default_.limit.memory", "512MB")).To(Succeed())
+	Expect(common.PropertyListAdd("buildpacks", appName, "buildpacks", "https://github.com/heroku/heroku-buildpack-nodejs.git", 0)).To(Succeed())
+	Expect(common.PropertyListAdd("buildpacks", appName, "buildpacks", "https://github.com/heroku/heroku-buildpack-ruby.git", 0)).To(Succeed())
+	Expect(common.PropertyWrite("network", "other-app", "bind-all-interfaces", "false")).To(Succeed())
+}
+
+// expectPluginState asserts that the app files and plugin properties written by setupPluginState belong to an app
+func expectPluginState(appName string) {
+	Expect(readAppFile(appName, "ENV")).To(Equal("export KEY='value'\n"))
+	Expect(common.PropertyGet("network", appName, "bind-all-interfaces")).To(Equal("true"))
+	Expect(common.PropertyGet("resource", appName, "_default_.limit.memory")).To(Equal("512MB"))
+	buildpacks, err := common.PropertyListGet("buildpacks", appName, "buildpacks")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(buildpacks).To(Equal([]string{"https://github.com/heroku/heroku-buildpack-nodejs.git", "https://github.com/heroku/heroku-buildpack-ruby.git"}))
+	Expect(common.PropertyGet("network", "other-app", "bind-all-interfaces")).To(Equal("false"))
+}
+
+func TestAppsIsValidAppName(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(IsValidAppName("")).NotTo(Succeed())
+	Expect(IsValidAppName("-app")).NotTo(Succeed())
+	Expect(IsValidAppName("App")).NotTo(Succeed())
+	Expect(IsValidAppName("my-App")).NotTo(Succeed())
+	Expect(IsValidAppName("my:app")).NotTo(Succeed())
+	Expect(IsValidAppName("my-app")).To(Succeed())
+	Expect(IsValidAppName("01-app")).To(Succeed())
+	Expect(IsValidAppName("my.app_1")).To(Succeed())
+}
+
+func TestAppsCreateApp(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	Expect(CreateApp("my-app")).To(Succeed())
+	Expect(AppExists("my-app")).To(BeTrue())
+	Expect(CreateApp("my-app")).NotTo(Succeed())
+	Expect(CreateApp("My-app")).NotTo(Succeed())
+	Expect(AppExists("My-app")).To(BeFalse())
+	Expect(firedTriggers()).To(Equal([]string{"trigger post-create my-app"}))
+}
+
+func TestAppsRenameApp(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	Expect(CreateApp("old-app")).To(Succeed())
+	setupPluginState("old-app")
+
+	Expect(RenameApp("missing-app", "new-app", false)).NotTo(Succeed())
+	Expect(CreateApp("other-app")).To(Succeed())
+	Expect(RenameApp("old-app", "other-app", false)).NotTo(Succeed())
+
+	Expect(RenameApp("old-app", "new-app", true)).To(Succeed())
+	expectPluginState("new-app")
+	Expect(readAppFile("new-app", "VHOST")).To(Equal("new-app.dokku.me\n"))
+	Expect(readAppFile("new-app", "URLS")).To(Equal("http://new-app.dokku.me\n"))
+	Expect(readAppFile("new-app", "hooks/pre-receive")).To(ContainSubstring("dokku git-hook new-app\n"))
+	Expect(common.DirectoryExists(filepath.Join(os.Getenv("DOKKU_ROOT"), "new-app", "cache"))).To(BeFalse())
+
+	plugins, err := common.PropertyPlugins("old-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(plugins).To(BeEmpty())
+
+	Expect(firedTriggers()).To(Equal([]string{
+		"trigger post-create old-app",
+		"trigger post-create other-app",
+		"trigger post-create new-app",
+		"trigger post-app-rename-setup old-app new-app",
+		"trigger proxy-clear-config new-app",
+		"trigger scheduler-stop docker-local old-app true",
+		"trigger post-app-rename old-app new-app",
+	}))
+	Expect(AppExists("old-app")).To(BeFalse())
+}
+
+func TestAppsCloneApp(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	Expect(CreateApp("old-app")).To(Succeed())
+	setupPluginState("old-app")
+
+	Expect(CloneApp("missing-app", "new-app", false, false)).NotTo(Succeed())
+	Expect(CreateApp("other-app")).To(Succeed())
+	Expect(CloneApp("old-app", "other-app", false, false)).NotTo(Succeed())
+	Expect(CloneApp("old-app", "other-app", false, true)).To(Succeed())
+
+	Expect(CloneApp("old-app", "new-app", false, false)).To(Succeed())
+	expectPluginState("old-app")
+	expectPluginState("new-app")
+	Expect(readAppFile("new-app", "hooks/pre-receive")).To(ContainSubstring("dokku git-hook new-app\n"))
+	Expect(readAppFile("old-app", "hooks/pre-receive")).To(ContainSubstring("dokku git-hook old-app\n"))
+	Expect(common.DirectoryExists(filepath.Join(os.Getenv("DOKKU_ROOT"), "new-app", "cache"))).To(BeFalse())
+	Expect(common.DirectoryExists(filepath.Join(os.Getenv("DOKKU_ROOT"), "old-app", "cache"))).To(BeTrue())
+
+	Expect(common.PropertyWrite("network", "new-app", "bind-all-interfaces", "false")).To(Succeed())
+	Expect(common.PropertyGet("network", "old-app", "bind-all-interfaces")).To(Equal("true"))
+
+	Expect(firedTriggers()).To(Equal([]string{
+		"trigger post-create old-app",
+		"trigger post-create other-app",
+		"trigger post-create new-app",
+		"trigger post-app-clone-setup old-app new-app",
+		"trigger receive-app new-app",
+		"trigger post-app-clone old-app new-app",
+	}))
+}
+
+func TestAppsSetMetadata(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	Expect(SetMetadata("my-app", "owner", "payments-team")).To(Succeed())
+	Expect(SetMetadata("my-app", "description", "Payments API")).To(Succeed())
+	Expect(SetMetadata("my-app", "label.team", "payments")).To(Succeed())
+	Expect(SetMetadata("my-app", "label.tier", "it's critical")).To(Succeed())
+	Expect(SetMetadata("my-app", "label.Team", "payments")).NotTo(Succeed())
+	Expect(SetMetadata("my-app", "label.", "payments")).NotTo(Succeed())
+	Expect(SetMetadata("my-app", "color", "blue")).NotTo(Succeed())
+	Expect(SetMetadata("my-app", "owner", "line\nbreak")).NotTo(Succeed())
+
+	metadata, err := GetMetadata("my-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(metadata).To(Equal(Metadata{
+		Name:        "my-app",
+		Owner:       "payments-team",
+		Description: "Payments API",
+		Labels:      map[string]string{"team": "payments", "tier": "it's critical"},
+	}))
+
+	args, err := DockerLabelArgs("my-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(args).To(Equal(`--label='team=payments' --label='tier=it'\''s critical'`))
+
+	Expect(SetMetadata("my-app", "label.tier", "")).To(Succeed())
+	Expect(SetMetadata("my-app", "label.missing", "")).To(Succeed())
+	metadata, err = GetMetadata("my-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(metadata.Labels).To(Equal(map[string]string{"team": "payments"}))
+
+	metadata, err = GetMetadata("other-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(metadata.Labels).To(BeEmpty())
+	args, err = DockerLabelArgs("other-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(args).To(Equal(""))
+}
+
+func TestAppsLabelFilters(t *testing.T) {
+	RegisterTestingT(t)
+
+	filter, err := ParseLabelFilters([]string{"team=payments", "tier="})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(filter).To(Equal(map[string]string{"team": "payments", "tier": ""}))
+	_, err = ParseLabelFilters([]string{"team"})
+	Expect(err).To(HaveOccurred())
+	_, err = ParseLabelFilters([]string{"=payments"})
+	Expect(err).To(HaveOccurred())
+
+	metadata := Metadata{Labels: map[string]string{"team": "payments", "tier": "web"}}
+	Expect(metadata.MatchesLabels(map[string]string{})).To(BeTrue())
+	Expect(metadata.MatchesLabels(map[string]string{"team": "payments"})).To(BeTrue())
+	Expect(metadata.MatchesLabels(map[string]string{"team": "payments", "tier": "worker"})).To(BeFalse())
+	Expect(metadata.MatchesLabels(map[string]string{"region": "us"})).To(BeFalse())
+}
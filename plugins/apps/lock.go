@@ -0,0 +1,412 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+)
+
+const (
+	// LockTypeWaiting waits for the current holder to release a deploy lock
+	LockTypeWaiting = "waiting"
+
+	// LockTypeExclusive fails when a deploy lock is already held
+	LockTypeExclusive = "exclusive"
+
+	// MaxConcurrentBuildsKey is the global config key limiting the number of builds running at once
+	MaxConcurrentBuildsKey = "DOKKU_MAX_CONCURRENT_BUILDS"
+)
+
+var lockPollInterval = time.Second
+
+// LockRecord describes a process holding or waiting for a lock
+type LockRecord struct {
+	App       string    `json:"app"`
+	PID       int       `json:"pid"`
+	User      string    `json:"user"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"started-at"`
+}
+
+// LockStatus is the state of the deploy lock of an app
+type LockStatus struct {
+	App     string       `json:"app"`
+	Locked  bool         `json:"locked"`
+	Holder  *LockRecord  `json:"holder"`
+	Waiters []LockRecord `json:"waiters"`
+}
+
+// lockQueue keeps holder and waiter records of a lock on disk. Records
+// belonging to processes that have exited are ignored and removed
+type lockQueue struct {
+	path string
+}
+
+// NewLockRecord returns a record for a dokku process, using the user and
+// command of the outermost dokku invocation
+func NewLockRecord(appName string, pid int) LockRecord {
+	user := os.Getenv("SSH_NAME")
+	if user == "" {
+		user = os.Getenv("SSH_USER")
+	}
+	return LockRecord{
+		App:       appName,
+		PID:       pid,
+		User:      user,
+		Command:   os.Getenv("DOKKU_COMMAND"),
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+// String returns a human readable description of a lock record
+func (r LockRecord) String() string {
+	user := r.User
+	if user == "" {
+		user = "unknown"
+	}
+	description := fmt.Sprintf("pid %d by %s since %s", r.PID, user, r.StartedAt.Format("2006-01-02 15:04:05 MST"))
+	if r.Command != "" {
+		description = fmt.Sprintf("%s (%s)", description, r.Command)
+	}
+	return description
+}
+
+// AcquireDeployLock takes the deploy lock of an app on an open lock file descriptor
+// and records the process holding it. Waiting processes are granted the lock in
+// the order they requested it
+func AcquireDeployLock(appName string, lockType string, lockFd int, record LockRecord) error {
+	if lockType != LockTypeWaiting && lockType != LockTypeExclusive {
+		return fmt.Errorf("Invalid lock type %s, valid types include: %s, %s", lockType, LockTypeWaiting, LockTypeExclusive)
+	}
+
+	queue := deployLockQueue(appName)
+	tryLock := func() bool {
+		return syscall.Flock(lockFd, syscall.LOCK_EX|syscall.LOCK_NB) == nil
+	}
+
+	if lockType == LockTypeExclusive {
+		if !tryLock() {
+			return fmt.Errorf("%s currently has a deploy lock in place. Exiting...%s", appName, describeHolders(queue))
+		}
+		return queue.addHolder(record)
+	}
+
+	return queue.wait(record, func(position int, holders []LockRecord) bool {
+		return position == 0 && tryLock()
+	}, func() {
+		fmt.Printf("%s currently has a deploy lock in place. Waiting...%s\n", appName, describeHolders(queue))
+	})
+}
+
+// ReleaseDeployLock removes the holder record of a process from the deploy lock of an app.
+// The lock itself is released when the lock file descriptor is unlocked or closed
+func ReleaseDeployLock(appName string, pid int) error {
+	return deployLockQueue(appName).removeHolder(pid)
+}
+
+// GetDeployLockStatus returns the holder and waiters of the deploy lock of an app
+func GetDeployLockStatus(appName string) (LockStatus, error) {
+	status := LockStatus{
+		App:     appName,
+		Locked:  isDeployLocked(appName),
+		Waiters: []LockRecord{},
+	}
+
+	queue := deployLockQueue(appName)
+	holders, err := queue.holders()
+	if err != nil {
+		return status, err
+	}
+	if status.Locked && len(holders) > 0 {
+		status.Holder = &holders[0]
+	}
+
+	waiters, err := queue.waiters()
+	if err != nil {
+		return status, err
+	}
+	for _, waiter := range waiters {
+		status.Waiters = append(status.Waiters, waiter.record)
+	}
+	return status, nil
+}
+
+// GetMaxConcurrentBuilds returns the number of builds allowed to run at once, or 0 when unlimited
+func GetMaxConcurrentBuilds() int {
+	limit, err := strconv.Atoi(config.GetWithDefault("--global", MaxConcurrentBuildsKey, "0"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// AcquireBuildSlot waits until fewer than the maximum number of concurrent builds are
+// running before recording the process as a running build. Queued builds start in the
+// order they were requested
+func AcquireBuildSlot(record LockRecord) error {
+	limit := GetMaxConcurrentBuilds()
+	if limit == 0 {
+		return nil
+	}
+
+	queue := buildQueue()
+	return queue.wait(record, func(position int, holders []LockRecord) bool {
+		return len(holders)+position < limit
+	}, func() {
+		fmt.Printf("Maximum of %d concurrent builds reached, %s is queued. Waiting...\n", limit, record.App)
+	})
+}
+
+// ReleaseBuildSlot removes a process from the running builds
+func ReleaseBuildSlot(pid int) error {
+	return buildQueue().removeHolder(pid)
+}
+
+func buildQueue() lockQueue {
+	return lockQueue{path: filepath.Join(getLockDataPath(), "build-queue")}
+}
+
+func deployLockQueue(appName string) lockQueue {
+	return lockQueue{path: filepath.Join(getLockDataPath(), "deploy-locks", appName)}
+}
+
+func describeHolders(queue lockQueue) string {
+	holders, err := queue.holders()
+	if err != nil || len(holders) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nLock held by %s", holders[0].String())
+}
+
+func getLockDataPath() string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "apps")
+}
+
+// isDeployLocked probes the deploy lock file of an app, as the file itself is left
+// behind once a deploy releases its lock
+func isDeployLocked(appName string) bool {
+	f, err := os.Open(filepath.Join(getAppPath(appName), ".deploy.lock"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return err == syscall.EWOULDBLOCK
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false
+}
+
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+type queuedRecord struct {
+	ticket string
+	record LockRecord
+}
+
+// wait queues a record and polls until ready allows it to proceed, at which point it is
+// recorded as a holder. ready is called with the position of the record among the live
+// waiters and the live holders, and decisions are serialized across processes
+func (q lockQueue) wait(record LockRecord, ready func(position int, holders []LockRecord) bool, waiting func()) error {
+	if err := q.ensureDirectories(); err != nil {
+		return err
+	}
+
+	ticket := fmt.Sprintf("%020d-%d", time.Now().UnixNano(), record.PID)
+	if err := q.writeRecord(filepath.Join(q.path, "waiters", ticket+".json"), record); err != nil {
+		return err
+	}
+	defer os.Remove(filepath.Join(q.path, "waiters", ticket+".json"))
+
+	notified := false
+	for {
+		acquired, err := q.tryAcquire(ticket, record, ready)
+		if err != nil || acquired {
+			return err
+		}
+		if !notified {
+			waiting()
+			notified = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+func (q lockQueue) tryAcquire(ticket string, record LockRecord, ready func(position int, holders []LockRecord) bool) (bool, error) {
+	unlock, err := q.lockMutex()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	holders, err := q.holders()
+	if err != nil {
+		return false, err
+	}
+	waiters, err := q.waiters()
+	if err != nil {
+		return false, err
+	}
+
+	position := len(waiters)
+	for i, waiter := range waiters {
+		if waiter.ticket == ticket {
+			position = i
+			break
+		}
+	}
+
+	if !ready(position, holders) {
+		return false, nil
+	}
+	return true, q.addHolder(record)
+}
+
+func (q lockQueue) lockMutex() (func(), error) {
+	if err := q.ensureDirectories(); err != nil {
+		return nil, err
+	}
+
+	mutexPath := filepath.Join(q.path, ".mutex")
+	mutex, err := os.OpenFile(mutexPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open lock mutex: %s", err.Error())
+	}
+	common.SetPermissions(mutexPath, 0644)
+	if err := syscall.Flock(int(mutex.Fd()), syscall.LOCK_EX); err != nil {
+		mutex.Close()
+		return nil, fmt.Errorf("Unable to lock mutex: %s", err.Error())
+	}
+	return func() { mutex.Close() }, nil
+}
+
+func (q lockQueue) addHolder(record LockRecord) error {
+	if err := q.ensureDirectories(); err != nil {
+		return err
+	}
+	return q.writeRecord(filepath.Join(q.path, "holders", strconv.Itoa(record.PID)+".json"), record)
+}
+
+func (q lockQueue) removeHolder(pid int) error {
+	err := os.Remove(filepath.Join(q.path, "holders", strconv.Itoa(pid)+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// holders returns the live holders, oldest first
+func (q lockQueue) holders() ([]LockRecord, error) {
+	records, err := q.readRecords("holders")
+	if err != nil {
+		return []LockRecord{}, err
+	}
+
+	holders := []LockRecord{}
+	for _, record := range records {
+		holders = append(holders, record.record)
+	}
+	sort.SliceStable(holders, func(i, j int) bool {
+		return holders[i].StartedAt.Before(holders[j].StartedAt)
+	})
+	return holders, nil
+}
+
+// waiters returns the live waiters in the order they were queued
+func (q lockQueue) waiters() ([]queuedRecord, error) {
+	return q.readRecords("waiters")
+}
+
+func (q lockQueue) readRecords(kind string) ([]queuedRecord, error) {
+	dir := filepath.Join(q.path, kind)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []queuedRecord{}, nil
+	}
+	if err != nil {
+		return []queuedRecord{}, err
+	}
+
+	records := []queuedRecord{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		recordPath := filepath.Join(dir, file.Name())
+		b, err := ioutil.ReadFile(recordPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return []queuedRecord{}, err
+		}
+
+		var record LockRecord
+		if err := json.Unmarshal(b, &record); err != nil || !isProcessAlive(record.PID) {
+			os.Remove(recordPath)
+			continue
+		}
+		records = append(records, queuedRecord{
+			ticket: strings.TrimSuffix(file.Name(), ".json"),
+			record: record,
+		})
+	}
+	return records, nil
+}
+
+func (q lockQueue) writeRecord(recordPath string, record LockRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(recordPath, b, 0644); err != nil {
+		return fmt.Errorf("Unable to write lock record: %s", err.Error())
+	}
+	common.SetPermissions(recordPath, 0644)
+	return nil
+}
+
+func (q lockQueue) ensureDirectories() error {
+	dataPath := getLockDataPath()
+	relative, err := filepath.Rel(dataPath, q.path)
+	if err != nil {
+		return err
+	}
+
+	dirs := []string{dataPath}
+	current := dataPath
+	for _, part := range strings.Split(relative, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		dirs = append(dirs, current)
+	}
+	dirs = append(dirs, filepath.Join(q.path, "holders"), filepath.Join(q.path, "waiters"))
+
+	for _, dir := range dirs {
+		if common.DirectoryExists(dir) {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("Unable to create lock directory: %s", err.Error())
+		}
+		common.SetPermissions(dir, 0755)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package apps
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+	. "github.com/onsi/gomega"
+)
+
+func openDeployLock(appName string) *os.File {
+	Expect(os.MkdirAll(getAppPath(appName), 0755)).To(Succeed())
+	f, err := os.OpenFile(filepath.Join(getAppPath(appName), ".deploy.lock"), os.O_CREATE|os.O_WRONLY, 0644)
+	Expect(err).NotTo(HaveOccurred())
+	return f
+}
+
+func exitedPid() int {
+	cmd := exec.Command("true")
+	Expect(cmd.Run()).To(Succeed())
+	return cmd.Process.Pid
+}
+
+func TestAppsDeployLockExclusive(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	holder := openDeployLock("api")
+	defer holder.Close()
+	record := LockRecord{App: "api", PID: os.Getpid(), User: "admin", Command: "git-hook api", StartedAt: time.Now().UTC()}
+	Expect(AcquireDeployLock("api", LockTypeExclusive, int(holder.Fd()), record)).To(Succeed())
+
+	status, err := GetDeployLockStatus("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(status.Locked).To(BeTrue())
+	Expect(status.Holder).NotTo(BeNil())
+	Expect(status.Holder.User).To(Equal("admin"))
+	Expect(status.Waiters).To(BeEmpty())
+
+	other := openDeployLock("api")
+	defer other.Close()
+	err = AcquireDeployLock("api", LockTypeExclusive, int(other.Fd()), NewLockRecord("api", os.Getppid()))
+	Expect(err).To(MatchError(ContainSubstring("Exiting...")))
+	Expect(err).To(MatchError(ContainSubstring("Lock held by pid")))
+	Expect(AcquireDeployLock("api", "sometimes", int(other.Fd()), record)).NotTo(Succeed())
+
+	Expect(ReleaseDeployLock("api", record.PID)).To(Succeed())
+	Expect(ReleaseDeployLock("api", record.PID)).To(Succeed())
+	status, err = GetDeployLockStatus("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(status.Holder).To(BeNil())
+
+	Expect(syscall.Flock(int(holder.Fd()), syscall.LOCK_UN)).To(Succeed())
+	status, err = GetDeployLockStatus("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(status.Locked).To(BeFalse())
+}
+
+func TestAppsDeployLockStatusIgnoresLeftoverLockFile(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	status, err := GetDeployLockStatus("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(status.Locked).To(BeFalse())
+
+	openDeployLock("api").Close()
+	Expect(common.FileExists(filepath.Join(getAppPath("api"), ".deploy.lock"))).To(BeTrue())
+	status, err = GetDeployLockStatus("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(status.Locked).To(BeFalse())
+}
+
+func TestAppsDeployLockWaiting(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+	lockPollInterval = 10 * time.Millisecond
+
+	holder := openDeployLock("api")
+	Expect(AcquireDeployLock("api", LockTypeWaiting, int(holder.Fd()), NewLockRecord("api", os.Getpid()))).To(Succeed())
+
+	waiter := openDeployLock("api")
+	defer waiter.Close()
+	done := make(chan error)
+	go func() {
+		done <- AcquireDeployLock("api", LockTypeWaiting, int(waiter.Fd()), NewLockRecord("api", os.Getppid()))
+	}()
+
+	Eventually(func() int {
+		status, err := GetDeployLockStatus("api")
+		Expect(err).NotTo(HaveOccurred())
+		return len(status.Waiters)
+	}).Should(Equal(1))
+	Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+
+	Expect(ReleaseDeployLock("api", os.Getpid())).To(Succeed())
+	Expect(syscall.Flock(int(holder.Fd()), syscall.LOCK_UN)).To(Succeed())
+	holder.Close()
+	Eventually(done).Should(Receive(BeNil()))
+
+	status, err := GetDeployLockStatus("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(status.Holder.PID).To(Equal(os.Getppid()))
+	Expect(status.Waiters).To(BeEmpty())
+}
+
+func TestAppsLockQueuePrunesExitedProcesses(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	queue := deployLockQueue("api")
+	Expect(queue.ensureDirectories()).To(Succeed())
+	Expect(queue.addHolder(NewLockRecord("api", exitedPid()))).To(Succeed())
+	Expect(queue.writeRecord(filepath.Join(queue.path, "waiters", "00000000000000000001-1.json"), NewLockRecord("api", exitedPid()))).To(Succeed())
+
+	Expect(queue.holders()).To(BeEmpty())
+	Expect(queue.waiters()).To(BeEmpty())
+	entries, err := filepath.Glob(filepath.Join(queue.path, "*", "*.json"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(entries).To(BeEmpty())
+}
+
+func TestAppsBuildSlots(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+	lockPollInterval = 10 * time.Millisecond
+
+	Expect(GetMaxConcurrentBuilds()).To(Equal(0))
+	Expect(AcquireBuildSlot(NewLockRecord("api", os.Getpid()))).To(Succeed())
+	Expect(buildQueue().holders()).To(BeEmpty())
+
+	Expect(config.SetMany("--global", map[string]string{MaxConcurrentBuildsKey: "1"}, false)).To(Succeed())
+	Expect(GetMaxConcurrentBuilds()).To(Equal(1))
+	Expect(AcquireBuildSlot(NewLockRecord("api", os.Getpid()))).To(Succeed())
+
+	done := make(chan error)
+	go func() {
+		done <- AcquireBuildSlot(NewLockRecord("web", os.Getppid()))
+	}()
+	Eventually(func() int {
+		waiters, err := buildQueue().waiters()
+		Expect(err).NotTo(HaveOccurred())
+		return len(waiters)
+	}).Should(Equal(1))
+	Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+
+	Expect(ReleaseBuildSlot(os.Getpid())).To(Succeed())
+	Eventually(done).Should(Receive(BeNil()))
+	holders, err := buildQueue().holders()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(holders).To(HaveLen(1))
+	Expect(holders[0].App).To(Equal("web"))
+}
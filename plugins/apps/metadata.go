@@ -0,0 +1,161 @@
+package apps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// labelPrefix is the property prefix for app labels
+	labelPrefix = "label."
+)
+
+var (
+	// MetadataProperties are the properties that may be set with apps:set in addition to labels
+	MetadataProperties = []string{"description", "owner"}
+
+	labelKeyRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+)
+
+// Metadata describes the ownership and labels of an app
+type Metadata struct {
+	Name        string            `json:"name"`
+	Owner       string            `json:"owner"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// DockerLabelArgs returns the docker run arguments applying the labels of an app to its containers
+func DockerLabelArgs(appName string) (string, error) {
+	metadata, err := GetMetadata(appName)
+	if err != nil {
+		return "", err
+	}
+
+	keys := []string{}
+	for key := range metadata.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{}
+	for _, key := range keys {
+		args = append(args, "--label="+shellQuote(key+"="+metadata.Labels[key]))
+	}
+	return strings.Join(args, " "), nil
+}
+
+// TriggerDockerArgs outputs the docker arguments read from stdin with the labels of an app added,
+// implementing both the docker-args-deploy and docker-args-run triggers
+func TriggerDockerArgs(appName string) error {
+	stdin, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	labelArgs, err := DockerLabelArgs(appName)
+	if err != nil {
+		common.LogWarn(err.Error())
+	}
+	if labelArgs != "" {
+		fmt.Printf(" %s ", labelArgs)
+	}
+	fmt.Print(string(stdin))
+	return nil
+}
+
+// GetMetadata returns the owner, description and labels of an app
+func GetMetadata(appName string) (Metadata, error) {
+	metadata := Metadata{
+		Name:   appName,
+		Labels: map[string]string{},
+	}
+
+	properties, err := common.PropertyGetAll("apps", appName)
+	if os.IsNotExist(err) {
+		return metadata, nil
+	}
+	if err != nil {
+		return metadata, fmt.Errorf("Unable to read metadata for %s: %s", appName, err.Error())
+	}
+
+	for property, value := range properties {
+		switch {
+		case property == "owner":
+			metadata.Owner = value
+		case property == "description":
+			metadata.Description = value
+		case strings.HasPrefix(property, labelPrefix):
+			metadata.Labels[strings.TrimPrefix(property, labelPrefix)] = value
+		}
+	}
+	return metadata, nil
+}
+
+// MatchesLabels returns true if the app has every label in the filter with the same value
+func (m Metadata) MatchesLabels(filter map[string]string) bool {
+	for key, value := range filter {
+		if labelValue, ok := m.Labels[key]; !ok || labelValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabelFilters parses a list of key=value label filters
+func ParseLabelFilters(labels []string) (map[string]string, error) {
+	filter := map[string]string{}
+	for _, label := range labels {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return filter, fmt.Errorf("Invalid label filter %s, specify labels as key=value", label)
+		}
+		filter[parts[0]] = parts[1]
+	}
+	return filter, nil
+}
+
+// SetMetadata sets a metadata property for an app, removing it when the value is empty
+func SetMetadata(appName string, property string, value string) error {
+	if err := validateMetadataProperty(property); err != nil {
+		return err
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("Invalid value for %s, the value must not contain newlines", property)
+	}
+
+	if value == "" {
+		if !common.PropertyExists("apps", appName, property) {
+			return nil
+		}
+		return common.PropertyDelete("apps", appName, property)
+	}
+	return common.PropertyWrite("apps", appName, property, value)
+}
+
+// shellQuote quotes a value for use in the docker arguments evaluated by the scheduler
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+func validateMetadataProperty(property string) error {
+	if strings.HasPrefix(property, labelPrefix) {
+		if key := strings.TrimPrefix(property, labelPrefix); !labelKeyRegex.MatchString(key) {
+			return fmt.Errorf("Invalid label %s, labels must begin and end with a lowercase alphanumeric character and contain only lowercase alphanumeric characters, periods, underscores and hyphens", key)
+		}
+		return nil
+	}
+
+	for _, metadataProperty := range MetadataProperties {
+		if property == metadataProperty {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid property specified, valid properties include: %s, label.<key>", strings.Join(MetadataProperties, ", "))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// converges an app to a state document
+func main() {
+	var filename string
+	args := flag.NewFlagSet("apps:apply", flag.ExitOnError)
+	args.StringVar(&filename, "f", "", "-f: path to the state document, or - to read from stdin")
+	args.StringVar(&filename, "file", "", "--file: path to the state document, or - to read from stdin")
+	dryRun := args.Bool("dry-run", false, "--dry-run: print the changes without applying them")
+	args.Parse(os.Args[2:])
+
+	if err := apps.CommandApply(filename, *dryRun); err != nil {
+		common.LogFail(err.Error())
+	}
+}
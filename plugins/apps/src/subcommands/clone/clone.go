@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// clones an app
+func main() {
+	args := flag.NewFlagSet("apps:clone", flag.ExitOnError)
+	skipDeploy := args.Bool("skip-deploy", false, "--skip-deploy: skip deploy of the new app")
+	ignoreExisting := args.Bool("ignore-existing", false, "--ignore-existing: exit 0 if the new app already exists")
+	args.Parse(os.Args[2:])
+	oldAppName := args.Arg(0)
+	newAppName := args.Arg(1)
+
+	if err := apps.CommandClone(oldAppName, newAppName, *skipDeploy, *ignoreExisting); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// creates an app, optionally from a template
+func main() {
+	args := flag.NewFlagSet("apps:create", flag.ExitOnError)
+	templateName := args.String("template", "", "--template: name of the template to apply to the new app")
+	dryRun := args.Bool("dry-run", false, "--dry-run: print the changes the template would make without creating the app")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := apps.CommandCreate(appName, *templateName, *dryRun); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// exports the state of an app as a yaml or json document
+func main() {
+	args := flag.NewFlagSet("apps:export", flag.ExitOnError)
+	format := args.String("format", "yaml", "--format: output format (yaml, json)")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := apps.CommandExport(appName, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// labelFlags collects every --label flag passed to apps:list
+type labelFlags []string
+
+func (l *labelFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *labelFlags) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// lists apps, optionally filtered by label
+func main() {
+	var labels labelFlags
+	args := flag.NewFlagSet("apps:list", flag.ExitOnError)
+	args.Var(&labels, "label", "--label: only list apps with the given key=value label, may be specified more than once")
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	args.Parse(os.Args[2:])
+
+	if err := apps.CommandList(labels, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
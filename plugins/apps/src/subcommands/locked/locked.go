@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// checks if an app is locked for deployment, exiting non-zero when it is not
+func main() {
+	args := flag.NewFlagSet("apps:locked", flag.ExitOnError)
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+
+	locked, err := apps.CommandLocked(appName, *format)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+	if !locked && *format != "json" {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// renames an app
+func main() {
+	args := flag.NewFlagSet("apps:rename", flag.ExitOnError)
+	skipDeploy := args.Bool("skip-deploy", false, "--skip-deploy: skip deploy of the renamed app")
+	args.Parse(os.Args[2:])
+	oldAppName := args.Arg(0)
+	newAppName := args.Arg(1)
+
+	if err := apps.CommandRename(oldAppName, newAppName, *skipDeploy); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// sets or clears the owner, description or a label of an app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(1)
+
+	if err := apps.CommandSet(appName, flag.Args()[2:]); err != nil {
+		common.LogFail(err.Error())
+	}
+}
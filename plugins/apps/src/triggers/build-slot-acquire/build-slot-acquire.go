@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// waits for a free build slot when the number of concurrent builds is limited
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	pid, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		common.LogFail("Please specify the pid of the building process")
+	}
+
+	if err := apps.AcquireBuildSlot(apps.NewLockRecord(appName, pid)); err != nil {
+		common.LogFail(err.Error())
+	}
+}
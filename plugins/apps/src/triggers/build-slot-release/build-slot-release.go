@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// frees the build slot held by a process
+func main() {
+	flag.Parse()
+	pid, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		common.LogFail("Please specify the pid of the building process")
+	}
+
+	if err := apps.ReleaseBuildSlot(pid); err != nil {
+		common.LogWarn(err.Error())
+	}
+}
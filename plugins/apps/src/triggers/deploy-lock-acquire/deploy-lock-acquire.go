@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// takes the deploy lock of an app on a lock file descriptor opened by the calling process
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	lockType := flag.Arg(1)
+	pid, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		common.LogFail("Please specify the pid of the process acquiring the lock")
+	}
+	lockFd, err := strconv.Atoi(flag.Arg(3))
+	if err != nil {
+		common.LogFail("Please specify the lock file descriptor")
+	}
+
+	if err := apps.AcquireDeployLock(appName, lockType, lockFd, apps.NewLockRecord(appName, pid)); err != nil {
+		common.LogFail(err.Error())
+	}
+}
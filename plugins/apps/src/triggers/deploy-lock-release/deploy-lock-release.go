@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// removes the holder record of a process from the deploy lock of an app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	pid, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		common.LogFail("Please specify the pid of the process releasing the lock")
+	}
+
+	if err := apps.ReleaseDeployLock(appName, pid); err != nil {
+		common.LogWarn(err.Error())
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/apps"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// outputs the app labels as docker labels
+func main() {
+	flag.Parse()
+	if err := apps.TriggerDockerArgs(flag.Arg(0)); err != nil {
+		common.LogFail(err.Error())
+	}
+}
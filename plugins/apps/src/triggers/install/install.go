@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the apps plugin
+func main() {
+	if err := common.PropertySetup("apps"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the apps plugin: %s", err.Error()))
+	}
+}
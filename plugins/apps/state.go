@@ -0,0 +1,570 @@
+package apps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/buildpacks"
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+	dockeroptions "github.com/dokku/dokku/plugins/docker-options"
+	"github.com/dokku/dokku/plugins/domains"
+	"github.com/dokku/dokku/plugins/network"
+	"github.com/dokku/dokku/plugins/proxy"
+	"github.com/dokku/dokku/plugins/ps"
+	"github.com/dokku/dokku/plugins/resource"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	// managedConfigKeys are config keys written by dokku itself or exported in their own section
+	managedConfigKeys = map[string]bool{
+		"DOKKU_APP_RESTORE":           true,
+		"DOKKU_APP_TYPE":              true,
+		"DOKKU_CHECKS_DISABLED":       true,
+		"DOKKU_CHECKS_SKIPPED":        true,
+		"DOKKU_DOCKERFILE_CMD":        true,
+		"DOKKU_DOCKERFILE_ENTRYPOINT": true,
+		"DOKKU_DOCKERFILE_PORTS":      true,
+		"DOKKU_PROXY_PORT":            true,
+		"DOKKU_PROXY_PORT_MAP":        true,
+		"DOKKU_PROXY_SSL_PORT":        true,
+		"GIT_REV":                     true,
+	}
+)
+
+// State is the declarative configuration of an app. Sections omitted from a
+// state document are left unchanged when the document is applied
+type State struct {
+	Name          string              `json:"name" yaml:"name"`
+	Config        map[string]string   `json:"config" yaml:"config"`
+	Domains       []string            `json:"domains" yaml:"domains"`
+	ProxyPorts    []string            `json:"proxy-ports" yaml:"proxy-ports"`
+	Buildpacks    []string            `json:"buildpacks" yaml:"buildpacks"`
+	Resources     *TemplateResources  `json:"resources" yaml:"resources"`
+	Network       map[string]string   `json:"network" yaml:"network"`
+	Storage       []string            `json:"storage" yaml:"storage"`
+	DockerOptions map[string][]string `json:"docker-options" yaml:"docker-options"`
+	Checks        *TemplateChecks     `json:"checks" yaml:"checks"`
+	Scale         map[string]int      `json:"scale" yaml:"scale"`
+}
+
+// ApplyState converges an app to a state document, creating the app if necessary, or only prints the changes on a dry run
+func ApplyState(desired State, dryRun bool) error {
+	if err := IsValidAppName(desired.Name); err != nil {
+		return err
+	}
+
+	if !AppExists(desired.Name) {
+		if dryRun {
+			common.LogInfo1(fmt.Sprintf("Changes for %s", desired.Name))
+			common.LogVerbose(fmt.Sprintf("Create app %s", desired.Name))
+			for _, step := range desired.Diff(newState(desired.Name)) {
+				common.LogVerbose(step.Description)
+			}
+			return nil
+		}
+
+		// the state is read again after creation, as plugins may set defaults when an app is created
+		if err := CreateApp(desired.Name); err != nil {
+			return err
+		}
+	}
+
+	current, err := ExportState(desired.Name)
+	if err != nil {
+		return err
+	}
+
+	steps := desired.Diff(current)
+	if len(steps) == 0 {
+		common.LogInfo1(fmt.Sprintf("No changes for %s", desired.Name))
+		return nil
+	}
+
+	common.LogInfo1(fmt.Sprintf("Changes for %s", desired.Name))
+	for _, step := range steps {
+		common.LogVerbose(step.Description)
+	}
+	if dryRun {
+		return nil
+	}
+
+	restart := false
+	for _, step := range steps {
+		if err := step.Apply(); err != nil {
+			return fmt.Errorf("Unable to apply state to %s (%s): %s", desired.Name, step.Description, err.Error())
+		}
+		restart = restart || step.Restart
+	}
+
+	if restart && common.IsDeployed(desired.Name) {
+		common.LogInfo1(fmt.Sprintf("Restarting app %s", desired.Name))
+		return common.PlugnTrigger("app-restart", desired.Name)
+	}
+	return nil
+}
+
+// Diff returns the changes required to converge the current state of an app to this state
+func (s State) Diff(current State) []Step {
+	appName := s.Name
+	steps := []Step{}
+
+	if s.Config != nil {
+		set := map[string]string{}
+		unset := []string{}
+		for key, value := range s.Config {
+			if currentValue, ok := current.Config[key]; !ok || currentValue != value {
+				set[key] = value
+			}
+		}
+		for key := range current.Config {
+			if _, ok := s.Config[key]; !ok {
+				unset = append(unset, key)
+			}
+		}
+		sort.Strings(unset)
+
+		if len(set) > 0 {
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Set config keys %s", strings.Join(sortedKeys(set), ", ")),
+				Restart:     true,
+				Apply: func() error {
+					return config.SetMany(appName, set, false)
+				},
+			})
+		}
+		if len(unset) > 0 {
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Unset config keys %s", strings.Join(unset, ", ")),
+				Restart:     true,
+				Apply: func() error {
+					return config.UnsetMany(appName, unset, false)
+				},
+			})
+		}
+	}
+
+	if s.Domains != nil && !sameValues(s.Domains, current.Domains) {
+		steps = append(steps, listStep(appName, "domains", s.Domains, func() error {
+			if len(s.Domains) == 0 {
+				return runPluginSubcommand("domains", "clear", appName)
+			}
+			return runPluginSubcommand("domains", "set", append([]string{appName}, s.Domains...)...)
+		}))
+	}
+
+	if s.ProxyPorts != nil && !sameValues(s.ProxyPorts, current.ProxyPorts) {
+		steps = append(steps, listStep(appName, "proxy ports", s.ProxyPorts, func() error {
+			if len(s.ProxyPorts) == 0 {
+				return runPluginSubcommand("proxy", "ports-clear", appName)
+			}
+			return runPluginSubcommand("proxy", "ports-set", append([]string{appName}, s.ProxyPorts...)...)
+		}))
+	}
+
+	if s.Buildpacks != nil && !reflect.DeepEqual(s.Buildpacks, current.Buildpacks) {
+		steps = append(steps, listStep(appName, "buildpacks", s.Buildpacks, func() error {
+			if err := buildpacks.CommandClear([]string{appName}); err != nil {
+				return err
+			}
+			for _, buildpack := range s.Buildpacks {
+				if err := buildpacks.CommandAdd([]string{appName, buildpack}, 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	}
+
+	if s.Resources != nil {
+		steps = append(steps, resourceSteps(appName, "limit", s.Resources.Limit, current.Resources.Limit)...)
+		steps = append(steps, resourceSteps(appName, "reserve", s.Resources.Reserve, current.Resources.Reserve)...)
+	}
+
+	if s.Network != nil {
+		for _, property := range sortedKeys(network.DefaultProperties) {
+			value, currentValue := s.Network[property], current.Network[property]
+			if value == currentValue {
+				continue
+			}
+			property := property
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Set network property %s to %s", property, value),
+				Restart:     true,
+				Apply: func() error {
+					return network.SetProperty(appName, property, value)
+				},
+			})
+		}
+	}
+
+	if s.Storage != nil {
+		add, remove := valuesDiff(s.Storage, current.Storage)
+		for _, mount := range add {
+			mount := mount
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Mount storage %s", mount),
+				Restart:     true,
+				Apply: func() error {
+					return setStorageMount(appName, mount, true)
+				},
+			})
+		}
+		for _, mount := range remove {
+			mount := mount
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Unmount storage %s", mount),
+				Restart:     true,
+				Apply: func() error {
+					return setStorageMount(appName, mount, false)
+				},
+			})
+		}
+	}
+
+	if s.DockerOptions != nil {
+		for _, phase := range dockeroptions.Phases {
+			add, remove := valuesDiff(s.DockerOptions[phase], current.DockerOptions[phase])
+			for _, option := range add {
+				phase, option := phase, option
+				steps = append(steps, Step{
+					Description: fmt.Sprintf("Add docker option %s to phase %s", option, phase),
+					Restart:     phase != "build",
+					Apply: func() error {
+						return dockeroptions.AddDockerOptionToPhase(appName, phase, option)
+					},
+				})
+			}
+			for _, option := range remove {
+				phase, option := phase, option
+				steps = append(steps, Step{
+					Description: fmt.Sprintf("Remove docker option %s from phase %s", option, phase),
+					Restart:     phase != "build",
+					Apply: func() error {
+						return dockeroptions.RemoveDockerOptionFromPhase(appName, phase, option)
+					},
+				})
+			}
+		}
+	}
+
+	if s.Checks != nil {
+		for _, check := range []struct {
+			key     string
+			name    string
+			desired []string
+			current []string
+		}{
+			{"DOKKU_CHECKS_DISABLED", "disabled", s.Checks.Disabled, current.Checks.Disabled},
+			{"DOKKU_CHECKS_SKIPPED", "skipped", s.Checks.Skipped, current.Checks.Skipped},
+		} {
+			if sameValues(check.desired, check.current) {
+				continue
+			}
+			key, processTypes := check.key, strings.Join(check.desired, ",")
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Set checks %s to %s", check.name, displayList(check.desired)),
+				Apply: func() error {
+					if processTypes == "" {
+						return config.UnsetMany(appName, []string{key}, false)
+					}
+					return config.SetMany(appName, map[string]string{key: processTypes}, false)
+				},
+			})
+		}
+	}
+
+	if s.Scale != nil {
+		scale := map[string]int{}
+		descriptions := []string{}
+		for _, processType := range sortedKeys(s.Scale) {
+			if count, ok := current.Scale[processType]; !ok || count != s.Scale[processType] {
+				scale[processType] = s.Scale[processType]
+				descriptions = append(descriptions, fmt.Sprintf("%s=%d", processType, s.Scale[processType]))
+			}
+		}
+		if len(scale) > 0 {
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Scale %s", strings.Join(descriptions, " ")),
+				Restart:     true,
+				Apply: func() error {
+					return ps.SetScale(appName, scale)
+				},
+			})
+		}
+	}
+
+	return steps
+}
+
+// ExportState returns the current state of an app
+func ExportState(appName string) (State, error) {
+	if err := common.VerifyAppName(appName); err != nil {
+		return State{}, err
+	}
+	state := newState(appName)
+
+	env, err := config.LoadAppEnv(appName)
+	if err != nil {
+		return state, err
+	}
+	for key, value := range env.Map() {
+		if !managedConfigKeys[key] {
+			state.Config[key] = value
+		}
+	}
+	state.Checks.Disabled = splitList(env.GetDefault("DOKKU_CHECKS_DISABLED", ""))
+	state.Checks.Skipped = splitList(env.GetDefault("DOKKU_CHECKS_SKIPPED", ""))
+
+	state.Domains = append(state.Domains, domains.GetAppDomains(appName)...)
+	state.ProxyPorts = append(state.ProxyPorts, proxy.GetPortMap(appName)...)
+
+	appBuildpacks, err := common.PropertyListGet("buildpacks", appName, "buildpacks")
+	if err != nil {
+		return state, err
+	}
+	state.Buildpacks = append(state.Buildpacks, appBuildpacks...)
+
+	resources, err := common.PropertyGetAll("resource", appName)
+	if err != nil && !os.IsNotExist(err) {
+		return state, err
+	}
+	for property, value := range resources {
+		parts := strings.SplitN(property, ".", 3)
+		if value == "" || len(parts) != 3 {
+			continue
+		}
+		processType, resourceType, key := parts[0], parts[1], parts[2]
+		target := state.Resources.Limit
+		if resourceType == "reserve" {
+			target = state.Resources.Reserve
+		}
+		r := target[processType]
+		r.set(key, value)
+		target[processType] = r
+	}
+
+	for property := range network.DefaultProperties {
+		if value := common.PropertyGet("network", appName, property); value != "" {
+			state.Network[property] = value
+		}
+	}
+
+	phaseOptions := map[string][]string{}
+	for _, phase := range dockeroptions.Phases {
+		if phaseOptions[phase], err = dockeroptions.GetDockerOptionsForPhase(appName, phase); err != nil {
+			return state, err
+		}
+	}
+	for _, option := range phaseOptions["deploy"] {
+		if strings.HasPrefix(option, "-v ") && containsValue(phaseOptions["run"], option) {
+			state.Storage = append(state.Storage, strings.TrimPrefix(option, "-v "))
+		}
+	}
+	for _, phase := range dockeroptions.Phases {
+		options := []string{}
+		for _, option := range phaseOptions[phase] {
+			if phase == "build" || !containsValue(state.Storage, strings.TrimPrefix(option, "-v ")) {
+				options = append(options, option)
+			}
+		}
+		state.DockerOptions[phase] = options
+	}
+
+	if state.Scale, err = ps.GetScale(appName); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// FormatState serializes a state document as yaml or json
+func FormatState(state State, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(state, "", "  ")
+	case "yaml":
+		return yaml.Marshal(state)
+	}
+	return []byte{}, errors.New("Invalid format specified, valid formats include: yaml, json")
+}
+
+// ParseState parses and validates a yaml or json state document, rejecting unknown keys
+func ParseState(b []byte) (State, error) {
+	state := State{}
+	if err := yaml.UnmarshalStrict(b, &state); err != nil {
+		return state, err
+	}
+	if state.Name == "" {
+		return state, errors.New("The state document must specify the app name")
+	}
+
+	for phase := range state.DockerOptions {
+		if !isDockerOptionPhase(phase) {
+			return state, fmt.Errorf("Invalid docker-options phase %s, valid phases include: %s", phase, strings.Join(dockerOptionPhases, ", "))
+		}
+	}
+	for _, property := range sortedKeys(state.Network) {
+		if err := network.ValidateProperty(property, state.Network[property]); err != nil {
+			return state, fmt.Errorf("Invalid network property %s: %s", property, err.Error())
+		}
+	}
+	for _, mount := range state.Storage {
+		if !strings.HasPrefix(mount, "/") || !strings.Contains(mount, ":/") {
+			return state, fmt.Errorf("Invalid storage mount %s, mounts must be two paths divided by a colon", mount)
+		}
+	}
+	for processType, count := range state.Scale {
+		if count < 0 {
+			return state, fmt.Errorf("Invalid scale for process type %s, the value must not be negative", processType)
+		}
+	}
+	return state, nil
+}
+
+func (r *TemplateResource) set(key string, value string) {
+	switch key {
+	case "cpu":
+		r.CPU = value
+	case "memory":
+		r.Memory = value
+	case "memory-swap":
+		r.MemorySwap = value
+	case "network":
+		r.Network = value
+	case "network-ingress":
+		r.NetworkIngress = value
+	case "network-egress":
+		r.NetworkEgress = value
+	}
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func displayList(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+// listStep returns a step replacing a list of values
+func listStep(appName string, name string, values []string, apply func() error) Step {
+	description := fmt.Sprintf("Set %s to %s", name, strings.Join(values, ", "))
+	if len(values) == 0 {
+		description = fmt.Sprintf("Clear %s", name)
+	}
+	return Step{Description: description, Apply: apply}
+}
+
+func newState(appName string) State {
+	return State{
+		Name:          appName,
+		Config:        map[string]string{},
+		Domains:       []string{},
+		ProxyPorts:    []string{},
+		Buildpacks:    []string{},
+		Resources:     &TemplateResources{Limit: map[string]TemplateResource{}, Reserve: map[string]TemplateResource{}},
+		Network:       map[string]string{},
+		Storage:       []string{},
+		DockerOptions: map[string][]string{},
+		Checks:        &TemplateChecks{Disabled: []string{}, Skipped: []string{}},
+		Scale:         map[string]int{},
+	}
+}
+
+// resourceSteps returns the steps converging the resource limits or reservations of each process type
+func resourceSteps(appName string, resourceType string, desired map[string]TemplateResource, current map[string]TemplateResource) []Step {
+	steps := []Step{}
+	for _, processType := range sortedKeys(desired) {
+		if desired[processType] == current[processType] {
+			continue
+		}
+		processType, r := processType, desired[processType]
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Set resource %s for process type %s", resourceType, processType),
+			Restart:     true,
+			Apply: func() error {
+				if resourceType == "reserve" {
+					return resource.CommandReserve([]string{appName}, processType, r.toResource())
+				}
+				return resource.CommandLimit([]string{appName}, processType, r.toResource())
+			},
+		})
+	}
+	for _, processType := range sortedKeys(current) {
+		if _, ok := desired[processType]; ok {
+			continue
+		}
+		processType := processType
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Clear resource %s for process type %s", resourceType, processType),
+			Restart:     true,
+			Apply: func() error {
+				if resourceType == "reserve" {
+					return resource.CommandReserveClear([]string{appName}, processType)
+				}
+				return resource.CommandLimitClear([]string{appName}, processType)
+			},
+		})
+	}
+	return steps
+}
+
+func sameValues(a []string, b []string) bool {
+	add, remove := valuesDiff(a, b)
+	return len(add) == 0 && len(remove) == 0
+}
+
+// setStorageMount adds or removes a bind mount in the same phases as storage:mount
+func setStorageMount(appName string, mount string, add bool) error {
+	for _, phase := range []string{"deploy", "run"} {
+		option := "-v " + mount
+		if add {
+			if err := dockeroptions.AddDockerOptionToPhase(appName, phase, option); err != nil {
+				return err
+			}
+		} else if err := dockeroptions.RemoveDockerOptionFromPhase(appName, phase, option); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// valuesDiff returns the values that must be added to and removed from current to match desired
+func valuesDiff(desired []string, current []string) (add []string, remove []string) {
+	for _, value := range desired {
+		if !containsValue(current, value) {
+			add = append(add, value)
+		}
+	}
+	for _, value := range current {
+		if !containsValue(desired, value) {
+			remove = append(remove, value)
+		}
+	}
+	return add, remove
+}
@@ -0,0 +1,163 @@
+package apps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+	. "github.com/onsi/gomega"
+)
+
+func stepDescriptions(steps []Step) []string {
+	descriptions := []string{}
+	for _, step := range steps {
+		descriptions = append(descriptions, step.Description)
+	}
+	return descriptions
+}
+
+func TestAppsParseState(t *testing.T) {
+	RegisterTestingT(t)
+
+	state, err := ParseState([]byte("name: api\nconfig:\n  PORT: 5000\nscale:\n  web: 2\n"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(state.Config).To(Equal(map[string]string{"PORT": "5000"}))
+	Expect(state.Scale).To(Equal(map[string]int{"web": 2}))
+	Expect(state.Domains).To(BeNil())
+	Expect(state.Checks).To(BeNil())
+
+	state, err = ParseState([]byte(`{"name": "api", "domains": []}`))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(state.Domains).To(Equal([]string{}))
+
+	_, err = ParseState([]byte("config:\n  PORT: 5000\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseState([]byte("name: api\nvolumes: []\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseState([]byte("name: api\ndocker-options:\n  deploy,run: []\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseState([]byte("name: api\nnetwork:\n  attach: bridge\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseState([]byte("name: api\nnetwork:\n  bind-all-interfaces: yes-please\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseState([]byte("name: api\nstorage:\n  - /var/lib/api\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseState([]byte("name: api\nscale:\n  web: -1\n"))
+	Expect(err).To(HaveOccurred())
+}
+
+func TestAppsStateDiff(t *testing.T) {
+	RegisterTestingT(t)
+
+	current := newState("api")
+	current.Config = map[string]string{"PORT": "5000", "OLD": "value"}
+	current.Domains = []string{"b.example.com", "a.example.com"}
+	current.Storage = []string{"/var/lib/api:/app/storage"}
+	current.Resources.Limit["web"] = TemplateResource{Memory: "256m"}
+	current.Checks.Disabled = []string{"worker"}
+	current.Scale = map[string]int{"web": 1, "worker": 1}
+
+	Expect(current.Diff(current)).To(BeEmpty())
+	Expect(State{Name: "api"}.Diff(current)).To(BeEmpty())
+
+	desired := State{
+		Name:          "api",
+		Config:        map[string]string{"PORT": "5000", "NEW": "value"},
+		Domains:       []string{"a.example.com", "b.example.com"},
+		Storage:       []string{"/var/lib/api/data:/app/data"},
+		DockerOptions: map[string][]string{"deploy": {"--shm-size 256m"}},
+		Resources:     &TemplateResources{Limit: map[string]TemplateResource{"web": {Memory: "512m"}}},
+		Checks:        &TemplateChecks{Disabled: []string{"worker"}, Skipped: []string{"web"}},
+		Scale:         map[string]int{"web": 2, "worker": 1},
+	}
+	steps := desired.Diff(current)
+	Expect(stepDescriptions(steps)).To(Equal([]string{
+		"Set config keys NEW",
+		"Unset config keys OLD",
+		"Set resource limit for process type web",
+		"Mount storage /var/lib/api/data:/app/data",
+		"Unmount storage /var/lib/api:/app/storage",
+		"Add docker option --shm-size 256m to phase deploy",
+		"Set checks skipped to web",
+		"Scale web=2",
+	}))
+	Expect(steps[len(steps)-1].Restart).To(BeTrue())
+	Expect(steps[0].Restart).To(BeTrue())
+
+	desired = State{Name: "api", Domains: []string{}, Resources: &TemplateResources{}}
+	Expect(stepDescriptions(desired.Diff(current))).To(Equal([]string{
+		"Clear domains",
+		"Clear resource limit for process type web",
+	}))
+}
+
+func TestAppsExportState(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	Expect(CreateApp("api")).To(Succeed())
+	Expect(config.SetMany("api", map[string]string{"PORT": "5000", "GIT_REV": "abc123", "DOKKU_CHECKS_SKIPPED": "web,worker"}, false)).To(Succeed())
+	writeAppFile("api", "VHOST", "api.example.com\n")
+	writeAppFile("api", "DOCKER_OPTIONS_DEPLOY", "--shm-size 256m\n-v /var/lib/api:/app/storage\n")
+	writeAppFile("api", "DOCKER_OPTIONS_RUN", "-v /var/lib/api:/app/storage\n")
+	writeAppFile("api", "DOKKU_SCALE", "web=2\n")
+	Expect(common.PropertyListAdd("buildpacks", "api", "buildpacks", "https://github.com/heroku/heroku-buildpack-nodejs.git", 0)).To(Succeed())
+	Expect(common.PropertyWrite("resource", "api", "web.limit.memory", "512m")).To(Succeed())
+	Expect(common.PropertyWrite("resource", "api", "web.limit.cpu", "")).To(Succeed())
+	Expect(common.PropertyWrite("network", "api", "bind-all-interfaces", "false")).To(Succeed())
+
+	state, err := ExportState("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(state.Config).To(Equal(map[string]string{"PORT": "5000"}))
+	Expect(state.Domains).To(Equal([]string{"api.example.com"}))
+	Expect(state.ProxyPorts).To(Equal([]string{}))
+	Expect(state.Buildpacks).To(Equal([]string{"https://github.com/heroku/heroku-buildpack-nodejs.git"}))
+	Expect(state.Resources.Limit).To(Equal(map[string]TemplateResource{"web": {Memory: "512m"}}))
+	Expect(state.Network).To(Equal(map[string]string{"bind-all-interfaces": "false"}))
+	Expect(state.Storage).To(Equal([]string{"/var/lib/api:/app/storage"}))
+	Expect(state.DockerOptions).To(Equal(map[string][]string{"build": {}, "deploy": {"--shm-size 256m"}, "run": {}}))
+	Expect(state.Checks.Skipped).To(Equal([]string{"web", "worker"}))
+	Expect(state.Scale).To(Equal(map[string]int{"web": 2}))
+
+	for _, format := range []string{"yaml", "json"} {
+		b, err := FormatState(state, format)
+		Expect(err).NotTo(HaveOccurred())
+		parsed, err := ParseState(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Diff(state)).To(BeEmpty())
+	}
+	_, err = FormatState(state, "toml")
+	Expect(err).To(HaveOccurred())
+}
+
+func TestAppsApplyState(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	desired := State{
+		Name:          "api",
+		Config:        map[string]string{"PORT": "5000"},
+		Storage:       []string{"/var/lib/api:/app/storage"},
+		DockerOptions: map[string][]string{"build": {"--pull"}},
+	}
+	Expect(ApplyState(desired, true)).To(Succeed())
+	Expect(AppExists("api")).To(BeFalse())
+
+	Expect(ApplyState(desired, false)).To(Succeed())
+	Expect(AppExists("api")).To(BeTrue())
+	Expect(config.GetWithDefault("api", "PORT", "")).To(Equal("5000"))
+	Expect(readAppFile("api", "DOCKER_OPTIONS_BUILD")).To(Equal("--pull\n"))
+	Expect(readAppFile("api", "DOCKER_OPTIONS_DEPLOY")).To(Equal("-v /var/lib/api:/app/storage\n"))
+	Expect(readAppFile("api", "DOCKER_OPTIONS_RUN")).To(Equal("-v /var/lib/api:/app/storage\n"))
+
+	current, err := ExportState("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(desired.Diff(current)).To(BeEmpty())
+
+	Expect(ApplyState(State{Name: "api", Config: map[string]string{}}, false)).To(Succeed())
+	b, err := ioutil.ReadFile(filepath.Join(getAppPath("api"), "ENV"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).NotTo(ContainSubstring("PORT"))
+}
@@ -0,0 +1,195 @@
+package apps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandApply implements apps:apply
+func CommandApply(filename string, dryRun bool) error {
+	if filename == "" {
+		return errors.New("Please specify a state document with -f, or - to read from stdin")
+	}
+
+	var b []byte
+	var err error
+	if filename == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read state document: %s", err.Error())
+	}
+
+	state, err := ParseState(b)
+	if err != nil {
+		return fmt.Errorf("Invalid state document: %s", err.Error())
+	}
+	return ApplyState(state, dryRun)
+}
+
+// CommandClone implements apps:clone
+func CommandClone(oldAppName string, newAppName string, skipDeploy bool, ignoreExisting bool) error {
+	if newAppName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+	return CloneApp(oldAppName, newAppName, skipDeploy, ignoreExisting)
+}
+
+// CommandCreate implements apps:create
+func CommandCreate(appName string, templateName string, dryRun bool) error {
+	if templateName != "" {
+		return CreateAppFromTemplate(appName, templateName, dryRun)
+	}
+	if dryRun {
+		return errors.New("The --dry-run flag may only be used along with --template")
+	}
+	return CreateApp(appName)
+}
+
+// CommandExport implements apps:export
+func CommandExport(appName string, format string) error {
+	if appName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+
+	state, err := ExportState(appName)
+	if err != nil {
+		return err
+	}
+	b, err := FormatState(state, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimSuffix(string(b), "\n"))
+	return nil
+}
+
+// CommandList implements apps:list
+func CommandList(labels []string, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+	filter, err := ParseLabelFilters(labels)
+	if err != nil {
+		return err
+	}
+
+	appNames, err := common.DokkuApps()
+	if err != nil {
+		return err
+	}
+
+	apps := []Metadata{}
+	for _, appName := range appNames {
+		metadata, err := GetMetadata(appName)
+		if err != nil {
+			return err
+		}
+		if metadata.MatchesLabels(filter) {
+			apps = append(apps, metadata)
+		}
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(apps)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	common.LogInfo2Quiet("My Apps")
+	for _, metadata := range apps {
+		fmt.Println(metadata.Name)
+	}
+	return nil
+}
+
+// CommandLocked implements apps:locked, returning whether the app has a deploy lock in place
+func CommandLocked(appName string, format string) (bool, error) {
+	if format != "stdout" && format != "json" {
+		return false, errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return false, err
+	}
+
+	status, err := GetDeployLockStatus(appName)
+	if err != nil {
+		return false, err
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(status)
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(b))
+		return status.Locked, nil
+	}
+
+	if os.Getenv("DOKKU_QUIET_OUTPUT") != "" {
+		return status.Locked, nil
+	}
+	if !status.Locked {
+		fmt.Fprintln(os.Stderr, "Deploy lock does not exist")
+		return false, nil
+	}
+
+	fmt.Println("Deploy lock exists")
+	if status.Holder != nil {
+		fmt.Printf("Held by %s\n", status.Holder.String())
+	}
+	for i, waiter := range status.Waiters {
+		fmt.Printf("Waiting %d: %s\n", i+1, waiter.String())
+	}
+	return true, nil
+}
+
+// CommandRename implements apps:rename
+func CommandRename(oldAppName string, newAppName string, skipDeploy bool) error {
+	if newAppName == "" {
+		return errors.New("Please specify a new app name")
+	}
+	return RenameApp(oldAppName, newAppName, skipDeploy)
+}
+
+// CommandSet implements apps:set
+func CommandSet(appName string, args []string) error {
+	if appName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	property, value := "", ""
+	switch {
+	case len(args) == 1 && strings.Contains(args[0], "="):
+		parts := strings.SplitN(args[0], "=", 2)
+		property, value = parts[0], parts[1]
+	case len(args) > 0:
+		property, value = args[0], strings.Join(args[1:], " ")
+	default:
+		return errors.New("Please specify a property to set, ie: dokku apps:set <app> owner <value>")
+	}
+
+	if err := SetMetadata(appName, property, value); err != nil {
+		return err
+	}
+	if value == "" {
+		common.LogInfo2Quiet(fmt.Sprintf("Unsetting %s", property))
+	} else {
+		common.LogInfo2Quiet(fmt.Sprintf("Setting %s to %s", property, value))
+	}
+	return nil
+}
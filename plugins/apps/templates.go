@@ -0,0 +1,415 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/buildpacks"
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+	"github.com/dokku/dokku/plugins/domains"
+	"github.com/dokku/dokku/plugins/resource"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	templateNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+	// templateExtensions are the file extensions searched for when loading a template, in order
+	templateExtensions = []string{".yml", ".yaml"}
+
+	// dockerOptionPhases are the phases a docker option may be added to
+	dockerOptionPhases = []string{"build", "deploy", "run"}
+)
+
+// Template is a declarative description of the configuration applied to an app on creation
+type Template struct {
+	Description   string              `yaml:"description"`
+	Owner         string              `yaml:"owner"`
+	Labels        map[string]string   `yaml:"labels"`
+	Config        map[string]string   `yaml:"config"`
+	Buildpacks    []string            `yaml:"buildpacks"`
+	Domains       []string            `yaml:"domains"`
+	DockerOptions map[string][]string `yaml:"docker-options"`
+	Resources     TemplateResources   `yaml:"resources"`
+	Checks        TemplateChecks      `yaml:"checks"`
+}
+
+// TemplateChecks lists the process types for which zero-downtime checks are disabled or skipped
+type TemplateChecks struct {
+	Disabled []string `json:"disabled" yaml:"disabled"`
+	Skipped  []string `json:"skipped" yaml:"skipped"`
+}
+
+// TemplateResources maps process types to resource limits and reservations
+type TemplateResources struct {
+	Limit   map[string]TemplateResource `json:"limit" yaml:"limit"`
+	Reserve map[string]TemplateResource `json:"reserve" yaml:"reserve"`
+}
+
+// TemplateResource is a set of resource constraints for a single process type
+type TemplateResource struct {
+	CPU            string `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Memory         string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	MemorySwap     string `json:"memory-swap,omitempty" yaml:"memory-swap,omitempty"`
+	Network        string `json:"network,omitempty" yaml:"network,omitempty"`
+	NetworkIngress string `json:"network-ingress,omitempty" yaml:"network-ingress,omitempty"`
+	NetworkEgress  string `json:"network-egress,omitempty" yaml:"network-egress,omitempty"`
+}
+
+// Step is a single change made to an app when applying a template or state document
+type Step struct {
+	Description string
+	Apply       func() error
+
+	// Validate is an optional check run against every step before any step is applied
+	Validate func() error
+
+	// Restart is true if a deployed app must be restarted for the change to take effect
+	Restart bool
+}
+
+// GetTemplatePath returns the path to a template file, searching each supported extension
+func GetTemplatePath(templateName string) (string, error) {
+	if !templateNameRegex.MatchString(templateName) {
+		return "", fmt.Errorf("Invalid template name %s", templateName)
+	}
+
+	for _, extension := range templateExtensions {
+		templatePath := filepath.Join(getTemplatesPath(), templateName+extension)
+		if common.FileExists(templatePath) {
+			return templatePath, nil
+		}
+	}
+
+	available, err := ListTemplates()
+	if err != nil || len(available) == 0 {
+		return "", fmt.Errorf("Template %s not found, templates are read from %s", templateName, getTemplatesPath())
+	}
+	return "", fmt.Errorf("Template %s not found, available templates: %s", templateName, strings.Join(available, ", "))
+}
+
+// ListTemplates returns the names of all templates
+func ListTemplates() ([]string, error) {
+	files, err := ioutil.ReadDir(getTemplatesPath())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return []string{}, err
+	}
+
+	templates := []string{}
+	for _, file := range files {
+		for _, extension := range templateExtensions {
+			if !file.IsDir() && strings.HasSuffix(file.Name(), extension) {
+				templates = append(templates, strings.TrimSuffix(file.Name(), extension))
+			}
+		}
+	}
+	sort.Strings(templates)
+	return templates, nil
+}
+
+// LoadTemplate reads and validates a template by name
+func LoadTemplate(templateName string) (Template, error) {
+	templatePath, err := GetTemplatePath(templateName)
+	if err != nil {
+		return Template{}, err
+	}
+
+	b, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return Template{}, fmt.Errorf("Unable to read template %s: %s", templateName, err.Error())
+	}
+
+	template, err := ParseTemplate(b)
+	if err != nil {
+		return template, fmt.Errorf("Invalid template %s: %s", templateName, err.Error())
+	}
+	return template, nil
+}
+
+// ParseTemplate parses and validates the yaml contents of a template, rejecting unknown keys
+func ParseTemplate(b []byte) (Template, error) {
+	template := Template{}
+	if err := yaml.UnmarshalStrict(b, &template); err != nil {
+		return template, err
+	}
+
+	for key := range template.Labels {
+		if err := validateMetadataProperty(labelPrefix + key); err != nil {
+			return template, err
+		}
+	}
+	for key, value := range map[string]string{"owner": template.Owner, "description": template.Description} {
+		if strings.ContainsAny(value, "\r\n") {
+			return template, fmt.Errorf("Invalid value for %s, the value must not contain newlines", key)
+		}
+	}
+	for phases := range template.DockerOptions {
+		for _, phase := range strings.Split(phases, ",") {
+			if !isDockerOptionPhase(phase) {
+				return template, fmt.Errorf("Invalid docker-options phase %s, valid phases include: %s", phase, strings.Join(dockerOptionPhases, ", "))
+			}
+		}
+	}
+	return template, nil
+}
+
+// Steps returns the changes required to apply the template to an app, in the order they are applied
+func (t Template) Steps(appName string) []Step {
+	steps := []Step{}
+
+	if t.Owner != "" {
+		steps = append(steps, metadataStep(appName, "owner", t.Owner))
+	}
+	if t.Description != "" {
+		steps = append(steps, metadataStep(appName, "description", t.Description))
+	}
+	for _, key := range sortedKeys(t.Labels) {
+		steps = append(steps, metadataStep(appName, labelPrefix+key, t.Labels[key]))
+	}
+
+	if len(t.Config) > 0 {
+		entries := t.Config
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Set config keys %s", strings.Join(sortedKeys(entries), ", ")),
+			Apply: func() error {
+				return config.SetMany(appName, entries, false)
+			},
+			Validate: func() error {
+				for key := range entries {
+					if err := config.ValidateKey(key); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+
+	for _, buildpack := range t.Buildpacks {
+		buildpack := buildpack
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Add buildpack %s", buildpack),
+			Apply: func() error {
+				return buildpacks.CommandAdd([]string{appName, buildpack}, 0)
+			},
+			Validate: func() error {
+				if buildpack == "" {
+					return errors.New("Must specify a buildpack to add")
+				}
+				return nil
+			},
+		})
+	}
+
+	if len(t.Domains) > 0 {
+		domains := t.Domains
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Add domains %s", strings.Join(domains, ", ")),
+			Apply: func() error {
+				return runPluginSubcommand("domains", "add", append([]string{appName}, domains...)...)
+			},
+			Validate: func() error {
+				return validateTemplateDomains(appName, domains)
+			},
+		})
+	}
+
+	for _, phases := range sortedKeys(t.DockerOptions) {
+		for _, option := range t.DockerOptions[phases] {
+			phases, option := phases, option
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Add docker option %s to phases %s", option, phases),
+				Apply: func() error {
+					return runPluginSubcommand("docker-options", "add", appName, phases, option)
+				},
+			})
+		}
+	}
+
+	for _, resourceType := range []string{"limit", "reserve"} {
+		resources := t.Resources.Limit
+		if resourceType == "reserve" {
+			resources = t.Resources.Reserve
+		}
+		for _, processType := range sortedKeys(resources) {
+			processType, resourceType, r := processType, resourceType, resources[processType].toResource()
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("Set resource %s for process type %s", resourceType, processType),
+				Apply: func() error {
+					if resourceType == "reserve" {
+						return resource.CommandReserve([]string{appName}, processType, r)
+					}
+					return resource.CommandLimit([]string{appName}, processType, r)
+				},
+			})
+		}
+	}
+
+	if len(t.Checks.Disabled) > 0 {
+		processTypes := strings.Join(t.Checks.Disabled, ",")
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Disable checks for process types %s", processTypes),
+			Apply: func() error {
+				return runPluginSubcommand("checks", "disable", appName, processTypes)
+			},
+		})
+	}
+	if len(t.Checks.Skipped) > 0 {
+		processTypes := strings.Join(t.Checks.Skipped, ",")
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("Skip checks for process types %s", processTypes),
+			Apply: func() error {
+				return runPluginSubcommand("checks", "skip", appName, processTypes)
+			},
+		})
+	}
+
+	return steps
+}
+
+// CreateAppFromTemplate creates an app and applies a template to it, or only prints the plan on a dry run
+func CreateAppFromTemplate(appName string, templateName string, dryRun bool) error {
+	if err := IsValidAppName(appName); err != nil {
+		return err
+	}
+	if AppExists(appName) {
+		return errors.New("Name is already taken")
+	}
+
+	template, err := LoadTemplate(templateName)
+	if err != nil {
+		return err
+	}
+	steps := template.Steps(appName)
+	for _, step := range steps {
+		if step.Validate == nil {
+			continue
+		}
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("Invalid template %s (%s): %s", templateName, step.Description, err.Error())
+		}
+	}
+
+	if dryRun {
+		common.LogInfo1(fmt.Sprintf("Plan for creating %s from template %s", appName, templateName))
+		common.LogVerbose(fmt.Sprintf("Create app %s", appName))
+		for _, step := range steps {
+			common.LogVerbose(step.Description)
+		}
+		return nil
+	}
+
+	if err := CreateApp(appName); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Applying template %s to %s", templateName, appName))
+	for _, step := range steps {
+		if err := step.Apply(); err != nil {
+			common.LogWarn(fmt.Sprintf("Unable to apply template %s (%s), destroying %s", templateName, step.Description, appName))
+			if destroyErr := destroyApp(appName); destroyErr != nil {
+				common.LogWarn(fmt.Sprintf("Unable to destroy %s: %s", appName, destroyErr.Error()))
+			}
+			return fmt.Errorf("Unable to apply template %s (%s): %s", templateName, step.Description, err.Error())
+		}
+	}
+	return nil
+}
+
+func (r TemplateResource) toResource() resource.Resource {
+	return resource.Resource{
+		CPU:            r.CPU,
+		Memory:         r.Memory,
+		MemorySwap:     r.MemorySwap,
+		Network:        r.Network,
+		NetworkIngress: r.NetworkIngress,
+		NetworkEgress:  r.NetworkEgress,
+	}
+}
+
+func getTemplatesPath() string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "apps", "templates")
+}
+
+func isDockerOptionPhase(phase string) bool {
+	for _, validPhase := range dockerOptionPhases {
+		if phase == validPhase {
+			return true
+		}
+	}
+	return false
+}
+
+func metadataStep(appName string, property string, value string) Step {
+	return Step{
+		Description: fmt.Sprintf("Set %s to %s", property, value),
+		Apply: func() error {
+			return SetMetadata(appName, property, value)
+		},
+	}
+}
+
+// validateTemplateDomains returns an error if a domain is invalid or already claimed by another app
+func validateTemplateDomains(appName string, domainList []string) error {
+	entries, err := domains.IndexEntries()
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range domainList {
+		normalized, err := domains.Normalize(domain)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Domain == normalized && entry.App != appName {
+				return fmt.Errorf("Domain %s is already in use by %s", normalized, entry.App)
+			}
+		}
+	}
+	return nil
+}
+
+// runPluginSubcommand runs a subcommand of a plugin that has no go api
+func runPluginSubcommand(pluginName string, subcommand string, args ...string) error {
+	cmdPath := filepath.Join(common.MustGetEnv("PLUGIN_AVAILABLE_PATH"), pluginName, "subcommands", subcommand)
+	cmd := exec.Command(cmdPath, append([]string{pluginName + ":" + subcommand}, args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch values := m.(type) {
+	case map[string]string:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string][]string:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]TemplateResource:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]int:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
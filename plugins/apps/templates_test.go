@@ -0,0 +1,149 @@
+package apps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+	. "github.com/onsi/gomega"
+)
+
+var testTemplate = `
+owner: payments@example.com
+description: Payments API
+labels:
+  team: payments
+config:
+  PORT: 5000
+  WEB_CONCURRENCY: "2"
+buildpacks:
+  - https://github.com/heroku/heroku-buildpack-nodejs.git
+domains:
+  - api.example.com
+docker-options:
+  deploy,run:
+    - -v /var/log/api:/app/log
+resources:
+  limit:
+    web:
+      memory: 512m
+checks:
+  disabled:
+    - worker
+`
+
+// setupTemplate writes a template and stubs the subcommands of plugins without a go api
+func setupTemplate(name string, contents string) string {
+	templatesPath := getTemplatesPath()
+	Expect(os.MkdirAll(templatesPath, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(templatesPath, name+".yml"), []byte(contents), 0644)).To(Succeed())
+
+	pluginPath := filepath.Join(os.Getenv("DOKKU_LIB_ROOT"), "plugins", "available")
+	subcommandsLog := filepath.Join(pluginPath, "subcommands.log")
+	for _, subcommand := range []string{"checks/subcommands/disable", "docker-options/subcommands/add", "domains/subcommands/add"} {
+		Expect(os.MkdirAll(filepath.Join(pluginPath, filepath.Dir(subcommand)), 0755)).To(Succeed())
+		stub := "#!/bin/sh\necho \"$*\" >> \"" + subcommandsLog + "\"\n"
+		Expect(ioutil.WriteFile(filepath.Join(pluginPath, subcommand), []byte(stub), 0755)).To(Succeed())
+	}
+	os.Setenv("PLUGIN_AVAILABLE_PATH", pluginPath)
+	return subcommandsLog
+}
+
+func TestAppsParseTemplate(t *testing.T) {
+	RegisterTestingT(t)
+
+	template, err := ParseTemplate([]byte(testTemplate))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(template.Config).To(Equal(map[string]string{"PORT": "5000", "WEB_CONCURRENCY": "2"}))
+	Expect(template.DockerOptions).To(Equal(map[string][]string{"deploy,run": {"-v /var/log/api:/app/log"}}))
+	Expect(template.Resources.Limit["web"].Memory).To(Equal("512m"))
+
+	_, err = ParseTemplate([]byte("domain:\n  - api.example.com\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseTemplate([]byte("labels:\n  Team: payments\n"))
+	Expect(err).To(HaveOccurred())
+	_, err = ParseTemplate([]byte("docker-options:\n  release:\n    - --rm\n"))
+	Expect(err).To(HaveOccurred())
+}
+
+func TestAppsTemplateSteps(t *testing.T) {
+	RegisterTestingT(t)
+
+	template, err := ParseTemplate([]byte(testTemplate))
+	Expect(err).NotTo(HaveOccurred())
+
+	descriptions := []string{}
+	for _, step := range template.Steps("api") {
+		descriptions = append(descriptions, step.Description)
+	}
+	Expect(descriptions).To(Equal([]string{
+		"Set owner to payments@example.com",
+		"Set description to Payments API",
+		"Set label.team to payments",
+		"Set config keys PORT, WEB_CONCURRENCY",
+		"Add buildpack https://github.com/heroku/heroku-buildpack-nodejs.git",
+		"Add domains api.example.com",
+		"Add docker option -v /var/log/api:/app/log to phases deploy,run",
+		"Set resource limit for process type web",
+		"Disable checks for process types worker",
+	}))
+}
+
+func TestAppsCreateAppFromTemplate(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+	subcommandsLog := setupTemplate("api", testTemplate)
+
+	Expect(CreateAppFromTemplate("api", "missing", false)).NotTo(Succeed())
+	Expect(CreateAppFromTemplate("api", "../api", false)).NotTo(Succeed())
+
+	Expect(CreateAppFromTemplate("api", "api", true)).To(Succeed())
+	Expect(AppExists("api")).To(BeFalse())
+	Expect(common.FileExists(subcommandsLog)).To(BeFalse())
+
+	Expect(CreateAppFromTemplate("api", "api", false)).To(Succeed())
+	Expect(AppExists("api")).To(BeTrue())
+
+	metadata, err := GetMetadata("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(metadata.Owner).To(Equal("payments@example.com"))
+	Expect(metadata.Labels).To(Equal(map[string]string{"team": "payments"}))
+	Expect(config.GetWithDefault("api", "PORT", "")).To(Equal("5000"))
+	Expect(common.PropertyListGet("buildpacks", "api", "buildpacks")).To(Equal([]string{"https://github.com/heroku/heroku-buildpack-nodejs.git"}))
+	Expect(common.PropertyGet("resource", "api", "web.limit.memory")).To(Equal("512m"))
+
+	b, err := ioutil.ReadFile(subcommandsLog)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(strings.Split(strings.TrimSpace(string(b)), "\n")).To(Equal([]string{
+		"domains:add api api.example.com",
+		"docker-options:add api deploy,run -v /var/log/api:/app/log",
+		"checks:disable api worker",
+	}))
+
+	Expect(CreateAppFromTemplate("api", "api", false)).NotTo(Succeed())
+}
+
+func TestAppsCreateAppFromTemplateFailure(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	subcommandsLog := setupTemplate("bad-key", "config:\n  1BAD: value\n")
+	Expect(CreateAppFromTemplate("api", "bad-key", false)).NotTo(Succeed())
+	Expect(CreateAppFromTemplate("api", "bad-key", true)).NotTo(Succeed())
+	Expect(AppExists("api")).To(BeFalse())
+
+	setupTemplate("bad-domain", "domains:\n  - not a domain\n")
+	Expect(CreateAppFromTemplate("api", "bad-domain", false)).NotTo(Succeed())
+	Expect(AppExists("api")).To(BeFalse())
+	Expect(common.FileExists(subcommandsLog)).To(BeFalse())
+
+	setupTemplate("api", testTemplate)
+	failing := filepath.Join(os.Getenv("PLUGIN_AVAILABLE_PATH"), "checks", "subcommands", "disable")
+	Expect(ioutil.WriteFile(failing, []byte("#!/bin/sh\nexit 1\n"), 0755)).To(Succeed())
+	Expect(CreateAppFromTemplate("api", "api", false)).NotTo(Succeed())
+	Expect(firedTriggers()).To(ContainElement(HavePrefix("trigger post-delete api")))
+}
@@ -0,0 +1,490 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// AdminRole is the built-in role allowed to run every command
+	AdminRole = "admin"
+
+	// AllApps is the app name used to store grants that apply to every app
+	AllApps = "_all_"
+
+	// PermissionConfig allows changing the settings of an app
+	PermissionConfig = "config"
+
+	// PermissionDeploy allows deploying, restarting and running commands within an app
+	PermissionDeploy = "deploy"
+
+	// PermissionReadOnly allows viewing reports and logs for an app
+	PermissionReadOnly = "read-only"
+
+	// globalAppName is the property namespace holding the access control lists
+	globalAppName = "_global_"
+)
+
+var (
+	nameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+	// Permissions is the list of valid per-app permissions
+	Permissions = []string{PermissionConfig, PermissionDeploy, PermissionReadOnly}
+)
+
+// Grant allows a user, or every member of a role, a permission on an app
+type Grant struct {
+	Subject    string `json:"subject"`
+	App        string `json:"app"`
+	Permission string `json:"permission"`
+}
+
+// User is a dokku user identified by one or more ssh key names or fingerprints
+type User struct {
+	Name   string   `json:"name"`
+	Keys   []string `json:"keys"`
+	Roles  []string `json:"roles"`
+	Grants []Grant  `json:"grants"`
+}
+
+// AddGrant allows a user or role, given as @role, a permission on an app
+func AddGrant(grant Grant) error {
+	if err := validateGrant(grant); err != nil {
+		return err
+	}
+
+	grants, err := GetGrants()
+	if err != nil {
+		return err
+	}
+	for _, existing := range grants {
+		if existing == grant {
+			return nil
+		}
+	}
+	return writeGrants(append(grants, grant))
+}
+
+// AddUser creates a user with an optional list of roles
+func AddUser(userName string, roles []string) error {
+	if err := validateName("user", userName); err != nil {
+		return err
+	}
+	if UserExists(userName) {
+		return fmt.Errorf("User %s already exists", userName)
+	}
+
+	for _, role := range roles {
+		if err := validateName("role", role); err != nil {
+			return err
+		}
+	}
+
+	if err := common.PropertyListAdd("auth", globalAppName, "users", userName, 0); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if err := addPair("roles", userName, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddUserKey maps an ssh key name or fingerprint to a user
+func AddUserKey(userName string, key string) error {
+	if !UserExists(userName) {
+		return fmt.Errorf("User %s does not exist", userName)
+	}
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return errors.New("Key must be a non-empty ssh key name or fingerprint")
+	}
+
+	keys, err := getPairs("keys")
+	if err != nil {
+		return err
+	}
+	for _, pair := range keys {
+		if pair[1] == key && pair[0] != userName {
+			return fmt.Errorf("Key %s is already mapped to user %s", key, pair[0])
+		}
+	}
+	return addPair("keys", userName, key)
+}
+
+// AddUserRole adds a role to a user
+func AddUserRole(userName string, role string) error {
+	if !UserExists(userName) {
+		return fmt.Errorf("User %s does not exist", userName)
+	}
+	if err := validateName("role", role); err != nil {
+		return err
+	}
+	return addPair("roles", userName, role)
+}
+
+// FindUser returns the user mapped to an ssh key fingerprint or, failing that, an ssh key name
+func FindUser(fingerprint string, keyName string) (User, bool) {
+	keys, err := getPairs("keys")
+	if err != nil {
+		return User{}, false
+	}
+
+	for _, candidate := range []string{fingerprint, keyName} {
+		if candidate == "" {
+			continue
+		}
+		for _, pair := range keys {
+			if pair[1] == candidate {
+				user, err := GetUser(pair[0])
+				return user, err == nil
+			}
+		}
+	}
+	return User{}, false
+}
+
+// GetGrants returns every grant
+func GetGrants() ([]Grant, error) {
+	lines, err := common.PropertyListGet("auth", globalAppName, "grants")
+	if err != nil {
+		return []Grant{}, err
+	}
+
+	grants := []Grant{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) != 3 {
+			continue
+		}
+		grants = append(grants, Grant{Subject: parts[0], App: parts[1], Permission: parts[2]})
+	}
+	return grants, nil
+}
+
+// GetUser returns a user along with their keys, roles and direct grants
+func GetUser(userName string) (User, error) {
+	if !UserExists(userName) {
+		return User{}, fmt.Errorf("User %s does not exist", userName)
+	}
+
+	user := User{Name: userName, Keys: []string{}, Roles: []string{}, Grants: []Grant{}}
+	keys, err := getPairs("keys")
+	if err != nil {
+		return user, err
+	}
+	for _, pair := range keys {
+		if pair[0] == userName {
+			user.Keys = append(user.Keys, pair[1])
+		}
+	}
+
+	roles, err := getPairs("roles")
+	if err != nil {
+		return user, err
+	}
+	for _, pair := range roles {
+		if pair[0] == userName {
+			user.Roles = append(user.Roles, pair[1])
+		}
+	}
+
+	grants, err := GetGrants()
+	if err != nil {
+		return user, err
+	}
+	for _, grant := range grants {
+		if grant.Subject == userName {
+			user.Grants = append(user.Grants, grant)
+		}
+	}
+	return user, nil
+}
+
+// GetUsers returns every user sorted by name
+func GetUsers() ([]User, error) {
+	names, err := common.PropertyListGet("auth", globalAppName, "users")
+	if err != nil {
+		return []User{}, err
+	}
+
+	sort.Strings(names)
+	users := []User{}
+	for _, name := range names {
+		user, err := GetUser(name)
+		if err != nil {
+			return users, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// HasPermission returns true if a user, directly or through one of their roles, holds a permission on an app
+// every permission implies read-only access
+func HasPermission(user User, appName string, permission string) bool {
+	if user.IsAdmin() {
+		return true
+	}
+
+	grants, err := GetGrants()
+	if err != nil {
+		return false
+	}
+
+	subjects := map[string]bool{user.Name: true}
+	for _, role := range user.Roles {
+		subjects["@"+role] = true
+	}
+
+	for _, grant := range grants {
+		if !subjects[grant.Subject] {
+			continue
+		}
+		if grant.App != appName && grant.App != AllApps {
+			continue
+		}
+		if grant.Permission == permission || permission == PermissionReadOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin returns true if the user holds the admin role
+func (u User) IsAdmin() bool {
+	for _, role := range u.Roles {
+		if role == AdminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEnabled returns true if access control is enforced
+func IsEnabled() bool {
+	return common.PropertyGet("auth", globalAppName, "enabled") == "true"
+}
+
+// RemoveAppGrants removes every grant for an app
+func RemoveAppGrants(appName string) error {
+	return RenameAppGrants(appName, "")
+}
+
+// RemoveGrant revokes a permission on an app from a user or role
+func RemoveGrant(grant Grant) error {
+	grants, err := GetGrants()
+	if err != nil {
+		return err
+	}
+
+	filtered := []Grant{}
+	found := false
+	for _, existing := range grants {
+		if existing == grant {
+			found = true
+			continue
+		}
+		filtered = append(filtered, existing)
+	}
+	if !found {
+		return fmt.Errorf("No %s grant on %s found for %s", grant.Permission, grant.App, grant.Subject)
+	}
+	return writeGrants(filtered)
+}
+
+// RemoveUser deletes a user along with their keys, roles and grants
+func RemoveUser(userName string) error {
+	if !UserExists(userName) {
+		return fmt.Errorf("User %s does not exist", userName)
+	}
+
+	if err := common.PropertyListRemove("auth", globalAppName, "users", userName); err != nil {
+		return err
+	}
+	for _, property := range []string{"keys", "roles"} {
+		pairs, err := getPairs(property)
+		if err != nil {
+			return err
+		}
+		filtered := [][2]string{}
+		for _, pair := range pairs {
+			if pair[0] != userName {
+				filtered = append(filtered, pair)
+			}
+		}
+		if err := writePairs(property, filtered); err != nil {
+			return err
+		}
+	}
+
+	grants, err := GetGrants()
+	if err != nil {
+		return err
+	}
+	filtered := []Grant{}
+	for _, grant := range grants {
+		if grant.Subject != userName {
+			filtered = append(filtered, grant)
+		}
+	}
+	return writeGrants(filtered)
+}
+
+// RemoveUserKey unmaps an ssh key name or fingerprint from a user
+func RemoveUserKey(userName string, key string) error {
+	return removePair("keys", userName, key)
+}
+
+// RemoveUserRole removes a role from a user
+func RemoveUserRole(userName string, role string) error {
+	return removePair("roles", userName, role)
+}
+
+// RenameAppGrants moves every grant for an app to a new app name, or removes them if the new name is empty
+func RenameAppGrants(oldAppName string, newAppName string) error {
+	grants, err := GetGrants()
+	if err != nil {
+		return err
+	}
+
+	updated := []Grant{}
+	for _, grant := range grants {
+		if grant.App == oldAppName {
+			if newAppName == "" {
+				continue
+			}
+			grant.App = newAppName
+		}
+		updated = append(updated, grant)
+	}
+	return writeGrants(updated)
+}
+
+// UserExists returns true if a user has been created
+func UserExists(userName string) bool {
+	names, err := common.PropertyListGet("auth", globalAppName, "users")
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if name == userName {
+			return true
+		}
+	}
+	return false
+}
+
+func addPair(property string, userName string, value string) error {
+	pairs, err := getPairs(property)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if pair[0] == userName && pair[1] == value {
+			return nil
+		}
+	}
+	return writePairs(property, append(pairs, [2]string{userName, value}))
+}
+
+func getPairs(property string) ([][2]string, error) {
+	lines, err := common.PropertyListGet("auth", globalAppName, property)
+	if err != nil {
+		return [][2]string{}, err
+	}
+
+	pairs := [][2]string{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		pairs = append(pairs, [2]string{parts[0], parts[1]})
+	}
+	return pairs, nil
+}
+
+func removePair(property string, userName string, value string) error {
+	if !UserExists(userName) {
+		return fmt.Errorf("User %s does not exist", userName)
+	}
+
+	pairs, err := getPairs(property)
+	if err != nil {
+		return err
+	}
+
+	filtered := [][2]string{}
+	for _, pair := range pairs {
+		if pair[0] == userName && pair[1] == value {
+			continue
+		}
+		filtered = append(filtered, pair)
+	}
+	if len(filtered) == len(pairs) {
+		return fmt.Errorf("User %s does not have %s %s", userName, strings.TrimSuffix(property, "s"), value)
+	}
+	return writePairs(property, filtered)
+}
+
+func validateGrant(grant Grant) error {
+	subject := strings.TrimPrefix(grant.Subject, "@")
+	if err := validateName("subject", subject); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(grant.Subject, "@") && !UserExists(grant.Subject) {
+		return fmt.Errorf("User %s does not exist", grant.Subject)
+	}
+
+	if grant.App != AllApps {
+		if err := common.VerifyAppName(grant.App); err != nil {
+			return err
+		}
+	}
+
+	for _, permission := range Permissions {
+		if grant.Permission == permission {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid permission %s, valid permissions: %s", grant.Permission, strings.Join(Permissions, ", "))
+}
+
+func validateName(kind string, name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("Invalid %s name '%s', names must begin with a lowercase alphanumeric character and contain only lowercase alphanumeric characters, periods, underscores and hyphens", kind, name)
+	}
+	return nil
+}
+
+func writeGrants(grants []Grant) error {
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].Subject != grants[j].Subject {
+			return grants[i].Subject < grants[j].Subject
+		}
+		if grants[i].App != grants[j].App {
+			return grants[i].App < grants[j].App
+		}
+		return grants[i].Permission < grants[j].Permission
+	})
+
+	var b strings.Builder
+	for _, grant := range grants {
+		fmt.Fprintf(&b, "%s %s %s\n", grant.Subject, grant.App, grant.Permission)
+	}
+	return common.PropertyWrite("auth", globalAppName, "grants", b.String())
+}
+
+func writePairs(property string, pairs [][2]string) error {
+	var b strings.Builder
+	for _, pair := range pairs {
+		fmt.Fprintf(&b, "%s %s\n", pair[0], pair[1])
+	}
+	return common.PropertyWrite("auth", globalAppName, property, b.String())
+}
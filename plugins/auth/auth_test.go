@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuthUsers(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(AddUser("alice", []string{"developers"})).To(Succeed())
+	Expect(AddUser("alice", []string{})).NotTo(Succeed())
+	Expect(AddUser("Bob", []string{})).NotTo(Succeed())
+	Expect(AddUser("bob", []string{"Admins"})).NotTo(Succeed())
+	Expect(AddUser("bob", []string{AdminRole})).To(Succeed())
+
+	Expect(AddUserKey("alice", "alice-laptop")).To(Succeed())
+	Expect(AddUserKey("alice", "SHA256:alicefingerprint")).To(Succeed())
+	Expect(AddUserKey("bob", "alice-laptop")).NotTo(Succeed())
+	Expect(AddUserKey("carol", "carol-laptop")).NotTo(Succeed())
+
+	alice, err := GetUser("alice")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(alice.Keys).To(Equal([]string{"alice-laptop", "SHA256:alicefingerprint"}))
+	Expect(alice.Roles).To(Equal([]string{"developers"}))
+	Expect(alice.IsAdmin()).To(BeFalse())
+
+	user, ok := FindUser("SHA256:alicefingerprint", "unknown")
+	Expect(ok).To(BeTrue())
+	Expect(user.Name).To(Equal("alice"))
+	user, ok = FindUser("", "alice-laptop")
+	Expect(ok).To(BeTrue())
+	Expect(user.Name).To(Equal("alice"))
+	_, ok = FindUser("SHA256:other", "other-laptop")
+	Expect(ok).To(BeFalse())
+
+	Expect(RemoveUserKey("alice", "alice-laptop")).To(Succeed())
+	Expect(RemoveUserKey("alice", "alice-laptop")).NotTo(Succeed())
+	_, ok = FindUser("", "alice-laptop")
+	Expect(ok).To(BeFalse())
+
+	Expect(AddUserRole("alice", AdminRole)).To(Succeed())
+	alice, _ = GetUser("alice")
+	Expect(alice.IsAdmin()).To(BeTrue())
+	Expect(RemoveUserRole("alice", AdminRole)).To(Succeed())
+
+	users, err := GetUsers()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(users).To(HaveLen(2))
+	Expect(users[0].Name).To(Equal("alice"))
+
+	Expect(RemoveUser("alice")).To(Succeed())
+	Expect(UserExists("alice")).To(BeFalse())
+	_, ok = FindUser("SHA256:alicefingerprint", "")
+	Expect(ok).To(BeFalse())
+}
+
+func TestAuthGrants(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	roots.CreateApps(t, "api", "web")
+
+	Expect(AddUser("alice", []string{"developers"})).To(Succeed())
+	Expect(AddGrant(Grant{Subject: "alice", App: "api", Permission: PermissionDeploy})).To(Succeed())
+	Expect(AddGrant(Grant{Subject: "alice", App: "api", Permission: PermissionDeploy})).To(Succeed())
+	Expect(AddGrant(Grant{Subject: "@developers", App: AllApps, Permission: PermissionReadOnly})).To(Succeed())
+	Expect(AddGrant(Grant{Subject: "alice", App: "missing", Permission: PermissionDeploy})).NotTo(Succeed())
+	Expect(AddGrant(Grant{Subject: "bob", App: "api", Permission: PermissionDeploy})).NotTo(Succeed())
+	Expect(AddGrant(Grant{Subject: "alice", App: "api", Permission: "owner"})).NotTo(Succeed())
+
+	grants, err := GetGrants()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(grants).To(HaveLen(2))
+
+	alice, _ := GetUser("alice")
+	Expect(HasPermission(alice, "api", PermissionDeploy)).To(BeTrue())
+	Expect(HasPermission(alice, "api", PermissionReadOnly)).To(BeTrue())
+	Expect(HasPermission(alice, "api", PermissionConfig)).To(BeFalse())
+	Expect(HasPermission(alice, "web", PermissionReadOnly)).To(BeTrue())
+	Expect(HasPermission(alice, "web", PermissionDeploy)).To(BeFalse())
+
+	Expect(RenameAppGrants("api", "api-v2")).To(Succeed())
+	Expect(HasPermission(alice, "api", PermissionDeploy)).To(BeFalse())
+	Expect(HasPermission(alice, "api-v2", PermissionDeploy)).To(BeTrue())
+
+	Expect(RemoveAppGrants("api-v2")).To(Succeed())
+	Expect(HasPermission(alice, "api-v2", PermissionDeploy)).To(BeFalse())
+
+	Expect(RemoveGrant(Grant{Subject: "@developers", App: AllApps, Permission: PermissionReadOnly})).To(Succeed())
+	Expect(RemoveGrant(Grant{Subject: "@developers", App: AllApps, Permission: PermissionReadOnly})).NotTo(Succeed())
+	Expect(HasPermission(alice, "web", PermissionReadOnly)).To(BeFalse())
+}
+
+func TestAuthEnable(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(IsEnabled()).To(BeFalse())
+	Expect(CommandEnable()).NotTo(Succeed())
+
+	Expect(AddUser("root-user", []string{AdminRole})).To(Succeed())
+	Expect(CommandEnable()).NotTo(Succeed())
+
+	Expect(AddUserKey("root-user", "admin")).To(Succeed())
+	Expect(CommandEnable()).To(Succeed())
+	Expect(IsEnabled()).To(BeTrue())
+
+	Expect(CommandDisable()).To(Succeed())
+	Expect(IsEnabled()).To(BeFalse())
+}
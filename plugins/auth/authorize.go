@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+var (
+	// publicCommands may be run by any mapped user
+	publicCommands = map[string]bool{
+		"":            true,
+		"help":        true,
+		"version":     true,
+		"apps:list":   true,
+		"auth:whoami": true,
+	}
+
+	// adminCommands require the admin role even when run against an app
+	adminCommands = map[string]bool{
+		"apps:apply":            true,
+		"apps:clone":            true,
+		"apps:create":           true,
+		"apps:destroy":          true,
+		"apps:export":           true,
+		"apps:rename":           true,
+		"docker-options:add":    true,
+		"docker-options:remove": true,
+		"logs:set":              true,
+		"storage:mount":         true,
+		"storage:unmount":       true,
+		"tags:promote":          true,
+	}
+
+	// adminPrefixes are plugin namespaces whose commands require the admin role
+	adminPrefixes = []string{"auth:", "events", "plugin", "ssh-keys"}
+
+	// deployCommands deploy, restart or run code within an app
+	deployCommands = map[string]bool{
+		"apps:deploy-image": true,
+		"cron:run":          true,
+		"deploy":            true,
+		"enter":             true,
+		"git-hook":          true,
+		"git-receive-pack":  true,
+		"git:sync":          true,
+		"ps:rebuild":        true,
+		"ps:restart":        true,
+		"ps:restore":        true,
+		"ps:scale":          true,
+		"ps:start":          true,
+		"ps:stop":           true,
+		"release":           true,
+		"repo:gc":           true,
+		"repo:purge-cache":  true,
+		"run":               true,
+		"tags:create":       true,
+		"tags:deploy":       true,
+		"tags:destroy":      true,
+		"tar:from":          true,
+		"tar:in":            true,
+	}
+
+	// readOnlyCommands only display information about an app
+	readOnlyCommands = map[string]bool{
+		"apps:locked":              true,
+		"cron:list":                true,
+		"cron:logs":                true,
+		"git-upload-pack":          true,
+		"logs":                     true,
+		"logs:failed":              true,
+		"logs:report":              true,
+		"notifications:deliveries": true,
+		"notifications:list":       true,
+		"ps:inspect":               true,
+		"tags":                     true,
+		"url":                      true,
+		"urls":                     true,
+	}
+
+	// internalCommands may be run without a mapped user by requests dokku itself makes, such as scheduled cron tasks
+	internalCommands = map[string]bool{
+		"cron:run": true,
+	}
+)
+
+// Request describes a single dokku command invocation to authorize
+type Request struct {
+	SSHUser     string
+	SSHName     string
+	Fingerprint string
+	AppName     string
+	Args        []string
+	// Internal is set for requests made by dokku itself rather than a user
+	Internal bool
+}
+
+// Authorize returns an error if the request is not allowed by the configured access control lists
+func Authorize(request Request) error {
+	if !IsEnabled() || request.SSHUser == "root" {
+		return nil
+	}
+
+	command := ""
+	if len(request.Args) > 0 {
+		command = request.Args[0]
+	}
+	if publicCommands[command] || strings.HasSuffix(command, ":help") {
+		return nil
+	}
+	if request.Internal && internalCommands[command] {
+		return nil
+	}
+
+	user, ok := FindUser(request.Fingerprint, request.SSHName)
+	if !ok {
+		identity := request.SSHName
+		if request.Fingerprint != "" {
+			identity = fmt.Sprintf("%s (%s)", request.SSHName, request.Fingerprint)
+		}
+		return fmt.Errorf("No dokku user is mapped to the ssh key %s", identity)
+	}
+	if user.IsAdmin() {
+		return nil
+	}
+
+	if requiresAdmin(command, request.Args) {
+		return fmt.Errorf("User %s is not allowed to run %s, the %s role is required", user.Name, command, AdminRole)
+	}
+
+	appName := RequestAppName(request)
+	if appName == "" || common.VerifyAppName(appName) != nil {
+		return fmt.Errorf("User %s is not allowed to run %s without an existing app, the %s role is required", user.Name, command, AdminRole)
+	}
+
+	permission := CommandPermission(command)
+	if !HasPermission(user, appName, permission) {
+		return fmt.Errorf("User %s is not allowed to run %s on %s, the %s permission is required", user.Name, command, appName, permission)
+	}
+	return nil
+}
+
+// IsAuthorizedInvocation returns whether the current process was started by an already authorized
+// dokku command. The authorized command and the pid of the dokku process that authorized it are
+// only trusted when that process is an ancestor of the current one
+func IsAuthorizedInvocation() bool {
+	if os.Getenv("DOKKU_AUTHORIZED_COMMAND") == "" {
+		return false
+	}
+
+	pid, err := strconv.Atoi(os.Getenv("DOKKU_AUTHORIZED_PID"))
+	if err != nil || pid <= 1 {
+		return false
+	}
+	return isAncestorProcess(pid, os.Getppid()) && isDokkuProcess(pid)
+}
+
+// CommandPermission returns the per-app permission required to run a command
+func CommandPermission(command string) string {
+	if deployCommands[command] {
+		return PermissionDeploy
+	}
+	if readOnlyCommands[command] || strings.HasSuffix(command, ":report") {
+		return PermissionReadOnly
+	}
+	return PermissionConfig
+}
+
+// RequestAppName returns the app a request targets, taken from --app, the git repository or the first positional argument
+func RequestAppName(request Request) string {
+	if request.AppName != "" {
+		return request.AppName
+	}
+	return common.CommandAppName(request.Args)
+}
+
+func requiresAdmin(command string, args []string) bool {
+	if adminCommands[command] || strings.HasSuffix(command, "-global") {
+		return true
+	}
+	for _, prefix := range adminPrefixes {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--global" || args[i] == "--all" {
+			return true
+		}
+	}
+	return false
+}
+
+func isAncestorProcess(ancestor int, pid int) bool {
+	for pid > 1 {
+		if pid == ancestor {
+			return true
+		}
+
+		b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return false
+		}
+
+		// the command name may contain spaces, so fields are read from after its closing paren
+		stat := string(b)
+		fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+		if len(fields) < 2 {
+			return false
+		}
+		if pid, err = strconv.Atoi(fields[1]); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+func isDokkuProcess(pid int) bool {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return false
+	}
+
+	// dokku is a bash script, so it is either the command or the script run by bash
+	args := strings.Split(strings.TrimRight(string(b), "\x00"), "\x00")
+	for i := 0; i < len(args) && i < 2; i++ {
+		if filepath.Base(args[i]) == "dokku" {
+			return true
+		}
+	}
+	return false
+}
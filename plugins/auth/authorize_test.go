@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func setupTestACL(t *testing.T, roots testutil.Roots) {
+	roots.CreateApps(t, "api", "web")
+	Expect(AddUser("admin", []string{AdminRole})).To(Succeed())
+	Expect(AddUserKey("admin", "admin")).To(Succeed())
+	Expect(AddUser("alice", []string{"developers"})).To(Succeed())
+	Expect(AddUserKey("alice", "SHA256:alice")).To(Succeed())
+	Expect(AddGrant(Grant{Subject: "alice", App: "api", Permission: PermissionDeploy})).To(Succeed())
+	Expect(AddGrant(Grant{Subject: "@developers", App: "web", Permission: PermissionConfig})).To(Succeed())
+	Expect(CommandEnable()).To(Succeed())
+}
+
+func aliceRequest(args ...string) Request {
+	return Request{SSHUser: "dokku", SSHName: "alice-laptop", Fingerprint: "SHA256:alice", Args: args}
+}
+
+func TestAuthAuthorizeDisabled(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(Authorize(Request{SSHUser: "dokku", SSHName: "unknown", Args: []string{"apps:destroy", "api"}})).To(Succeed())
+}
+
+func TestAuthAuthorizeUsers(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	setupTestACL(t, roots)
+
+	Expect(Authorize(Request{SSHUser: "root", Args: []string{"apps:destroy", "api"}})).To(Succeed())
+	Expect(Authorize(Request{SSHUser: "dokku", SSHName: "admin", Args: []string{"plugin:install"}})).To(Succeed())
+	Expect(Authorize(Request{SSHUser: "dokku", SSHName: "unknown", Args: []string{"ps:restart", "api"}})).NotTo(Succeed())
+	Expect(Authorize(Request{SSHUser: "dokku", SSHName: "unknown", Args: []string{"help"}})).To(Succeed())
+	Expect(Authorize(Request{SSHUser: "dokku", SSHName: "unknown", Args: []string{"config:help"}})).To(Succeed())
+}
+
+func TestAuthAuthorizePermissions(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	setupTestACL(t, roots)
+
+	Expect(Authorize(aliceRequest("git-receive-pack", "'api'"))).To(Succeed())
+	Expect(Authorize(aliceRequest("git-upload-pack", "'/api'"))).To(Succeed())
+	Expect(Authorize(aliceRequest("ps:restart", "api"))).To(Succeed())
+	Expect(Authorize(aliceRequest("logs", "api"))).To(Succeed())
+	Expect(Authorize(aliceRequest("config:set", "api", "KEY=value"))).NotTo(Succeed())
+
+	Expect(Authorize(aliceRequest("config:set", "--no-restart", "web", "KEY=value"))).To(Succeed())
+	Expect(Authorize(aliceRequest("domains:report", "web"))).To(Succeed())
+	Expect(Authorize(aliceRequest("git-receive-pack", "'web'"))).NotTo(Succeed())
+
+	Expect(Authorize(aliceRequest("ps:restart", "missing"))).NotTo(Succeed())
+	Expect(Authorize(aliceRequest("ps:restart"))).NotTo(Succeed())
+	Expect(Authorize(aliceRequest("ps:restart", "--all"))).NotTo(Succeed())
+	Expect(Authorize(aliceRequest("domains:add-global", "example.com"))).NotTo(Succeed())
+	Expect(Authorize(aliceRequest("apps:destroy", "api"))).NotTo(Succeed())
+	Expect(Authorize(aliceRequest("auth:grant", "alice", "web", "deploy"))).NotTo(Succeed())
+	Expect(Authorize(aliceRequest("auth:whoami"))).To(Succeed())
+
+	request := aliceRequest("ps:restart")
+	request.AppName = "api"
+	Expect(Authorize(request)).To(Succeed())
+
+	cronRequest := Request{SSHUser: "dokku", SSHName: "default", Args: []string{"cron:run", "web", "abc123"}}
+	Expect(Authorize(cronRequest)).NotTo(Succeed())
+	cronRequest.Internal = true
+	Expect(Authorize(cronRequest)).To(Succeed())
+	Expect(Authorize(Request{SSHUser: "dokku", SSHName: "default", Args: []string{"ps:restart", "web"}, Internal: true})).NotTo(Succeed())
+}
+
+func TestAuthCommandPermission(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(CommandPermission("git-receive-pack")).To(Equal(PermissionDeploy))
+	Expect(CommandPermission("certs:report")).To(Equal(PermissionReadOnly))
+	Expect(CommandPermission("config:set")).To(Equal(PermissionConfig))
+}
+
+func TestAuthIsAuthorizedInvocation(t *testing.T) {
+	RegisterTestingT(t)
+	defer os.Unsetenv("DOKKU_AUTHORIZED_COMMAND")
+	defer os.Unsetenv("DOKKU_AUTHORIZED_PID")
+
+	Expect(IsAuthorizedInvocation()).To(BeFalse())
+
+	os.Setenv("DOKKU_AUTHORIZED_COMMAND", "ps:restart api")
+	Expect(IsAuthorizedInvocation()).To(BeFalse())
+
+	// the parent of the test binary is an ancestor, but is not dokku
+	os.Setenv("DOKKU_AUTHORIZED_PID", strconv.Itoa(os.Getppid()))
+	Expect(isAncestorProcess(os.Getppid(), os.Getppid())).To(BeTrue())
+	Expect(IsAuthorizedInvocation()).To(BeFalse())
+
+	os.Setenv("DOKKU_AUTHORIZED_PID", strconv.Itoa(os.Getpid()))
+	Expect(isAncestorProcess(os.Getpid(), os.Getppid())).To(BeFalse())
+	Expect(IsAuthorizedInvocation()).To(BeFalse())
+
+	dir, err := ioutil.TempDir("", "dokku-bin")
+	Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "dokku")
+	Expect(ioutil.WriteFile(script, []byte("sleep 5\n"), 0755)).To(Succeed())
+
+	cmd := exec.Command("bash", script)
+	Expect(cmd.Start()).To(Succeed())
+	defer cmd.Process.Kill()
+	Expect(isDokkuProcess(cmd.Process.Pid)).To(BeTrue())
+	Expect(isDokkuProcess(os.Getpid())).To(BeFalse())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	columnize "github.com/ryanuber/columnize"
+)
+
+const (
+	helpHeader = `Usage: dokku auth[:COMMAND]
+
+Manages users, roles and per-app permissions
+
+Additional commands:`
+
+	helpContent = `
+    auth:disable, Disables access control enforcement
+    auth:enable, Enables access control enforcement
+    auth:grant [--all] <user|@role> [<app>] <permission>, Grants a user or role a permission on an app
+    auth:key-add <user> <key>, Maps an ssh key name or fingerprint to a user
+    auth:key-remove <user> <key>, Unmaps an ssh key name or fingerprint from a user
+    auth:list [--format json], Lists users along with their keys, roles and grants
+    auth:revoke [--all] <user|@role> [<app>] <permission>, Revokes a permission on an app from a user or role
+    auth:role-add <user> <role>, Adds a role to a user
+    auth:role-remove <user> <role>, Removes a role from a user
+    auth:user-add <user> [<role>...], Creates a user with an optional list of roles
+    auth:user-remove <user>, Removes a user along with their keys, roles and grants
+    auth:whoami, Displays the user mapped to the current ssh key
+`
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd := flag.Arg(0)
+	switch cmd {
+	case "auth", "auth:help":
+		usage()
+	case "help":
+		command := common.NewShellCmd(fmt.Sprintf("ps -o command= %d", os.Getppid()))
+		command.ShowOutput = false
+		output, err := command.Output()
+
+		if err == nil && strings.Contains(string(output), "--all") {
+			fmt.Print(helpContent)
+		} else {
+			fmt.Print("\n    auth, Manages users, roles and per-app permissions\n")
+		}
+	default:
+		dokkuNotImplementExitCode, err := strconv.Atoi(os.Getenv("DOKKU_NOT_IMPLEMENTED_EXIT"))
+		if err != nil {
+			fmt.Println("failed to retrieve DOKKU_NOT_IMPLEMENTED_EXIT environment variable")
+			dokkuNotImplementExitCode = 10
+		}
+		os.Exit(dokkuNotImplementExitCode)
+	}
+}
+
+func usage() {
+	config := columnize.DefaultConfig()
+	config.Delim = ","
+	config.Prefix = "    "
+	config.Empty = ""
+	content := strings.Split(helpContent, "\n")[1:]
+	fmt.Println(helpHeader)
+	fmt.Println(columnize.Format(content, config))
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// disables access control enforcement
+func main() {
+	flag.Parse()
+
+	if err := auth.CommandDisable(); err != nil {
+		common.LogFail(err.Error())
+	}
+}
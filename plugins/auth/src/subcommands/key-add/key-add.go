@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// maps an ssh key name or fingerprint to a user
+func main() {
+	flag.Parse()
+	userName := flag.Arg(1)
+	key := flag.Arg(2)
+
+	if err := auth.CommandKeyAdd(userName, key); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// unmaps an ssh key name or fingerprint from a user
+func main() {
+	flag.Parse()
+	userName := flag.Arg(1)
+	key := flag.Arg(2)
+
+	if err := auth.CommandKeyRemove(userName, key); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// lists users along with their keys, roles and grants
+func main() {
+	args := flag.NewFlagSet("auth:list", flag.ExitOnError)
+	format := args.String("format", "stdout", "format: [ stdout | json ] which format to output users as")
+	args.Parse(os.Args[2:])
+
+	if err := auth.CommandList(*format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// revokes a permission on an app from a user or role
+func main() {
+	args := flag.NewFlagSet("auth:revoke", flag.ExitOnError)
+	allApps := args.Bool("all", false, "--all: apply to every app")
+	args.Parse(os.Args[2:])
+	subject := args.Arg(0)
+	appName := args.Arg(1)
+	permission := args.Arg(2)
+
+	if err := auth.CommandRevoke(subject, appName, permission, *allApps); err != nil {
+		common.LogFail(err.Error())
+	}
+}
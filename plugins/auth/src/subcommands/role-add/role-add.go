@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// adds a role to a user
+func main() {
+	flag.Parse()
+	userName := flag.Arg(1)
+	role := flag.Arg(2)
+
+	if err := auth.CommandRoleAdd(userName, role); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// creates a user with an optional list of roles
+func main() {
+	args := flag.NewFlagSet("auth:user-add", flag.ExitOnError)
+	args.Parse(os.Args[2:])
+	userName := args.Arg(0)
+	roles := []string{}
+	if args.NArg() > 1 {
+		roles = args.Args()[1:]
+	}
+
+	if err := auth.CommandUserAdd(userName, roles); err != nil {
+		common.LogFail(err.Error())
+	}
+}
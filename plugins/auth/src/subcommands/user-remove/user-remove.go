@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// removes a user along with their keys, roles and grants
+func main() {
+	flag.Parse()
+	userName := flag.Arg(1)
+
+	if err := auth.CommandUserRemove(userName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
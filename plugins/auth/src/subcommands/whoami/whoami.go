@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// displays the user mapped to the current ssh key
+func main() {
+	flag.Parse()
+
+	if err := auth.CommandWhoami(os.Getenv("SSH_USER"), os.Getenv("SSH_NAME"), os.Getenv("FINGERPRINT")); err != nil {
+		common.LogFail(err.Error())
+	}
+}
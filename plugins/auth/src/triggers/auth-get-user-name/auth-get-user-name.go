@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/auth"
+)
+
+// outputs the name of the user mapped to an ssh key
+func main() {
+	flag.Parse()
+	fingerprint := flag.Arg(0)
+	sshName := flag.Arg(1)
+
+	if user, ok := auth.FindUser(fingerprint, sshName); ok {
+		fmt.Println(user.Name)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the auth plugin
+func main() {
+	if err := common.PropertySetup("auth"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the auth plugin: %s", err.Error()))
+	}
+}
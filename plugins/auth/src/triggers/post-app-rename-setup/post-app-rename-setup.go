@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// moves every grant for a renamed app to the new app name
+func main() {
+	flag.Parse()
+	oldAppName := flag.Arg(0)
+	newAppName := flag.Arg(1)
+
+	if err := auth.RenameAppGrants(oldAppName, newAppName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
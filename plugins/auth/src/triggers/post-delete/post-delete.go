@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// removes every grant for a deleted app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := auth.RemoveAppGrants(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
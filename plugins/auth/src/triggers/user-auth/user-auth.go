@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// denies commands not allowed by the access control lists
+func main() {
+	flag.Parse()
+
+	// internal dokku invocations were authorized as part of the original command
+	if auth.IsAuthorizedInvocation() {
+		return
+	}
+
+	request := auth.Request{
+		SSHUser:     flag.Arg(0),
+		SSHName:     flag.Arg(1),
+		Fingerprint: os.Getenv("FINGERPRINT"),
+		AppName:     os.Getenv("DOKKU_APP_NAME"),
+		Args:        []string{},
+		Internal:    os.Getenv("DOKKU_CRON_RUN") == "true",
+	}
+	if flag.NArg() > 2 {
+		request.Args = flag.Args()[2:]
+	}
+
+	if err := auth.Authorize(request); err != nil {
+		common.LogFail(err.Error())
+	}
+}
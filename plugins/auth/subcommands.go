@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandDisable implements auth:disable
+func CommandDisable() error {
+	if err := common.PropertyWrite("auth", globalAppName, "enabled", "false"); err != nil {
+		return err
+	}
+	common.LogInfo1("Access control disabled")
+	return nil
+}
+
+// CommandEnable implements auth:enable
+func CommandEnable() error {
+	users, err := GetUsers()
+	if err != nil {
+		return err
+	}
+
+	hasAdmin := false
+	for _, user := range users {
+		if user.IsAdmin() && len(user.Keys) > 0 {
+			hasAdmin = true
+			break
+		}
+	}
+	if !hasAdmin {
+		return errors.New("At least one user with the admin role and a mapped ssh key is required before enabling access control")
+	}
+
+	if err := common.PropertyWrite("auth", globalAppName, "enabled", "true"); err != nil {
+		return err
+	}
+	common.LogInfo1("Access control enabled")
+	return nil
+}
+
+// CommandGrant implements auth:grant
+func CommandGrant(subject string, appName string, permission string, allApps bool) error {
+	grant, err := parseGrant(subject, appName, permission, allApps)
+	if err != nil {
+		return err
+	}
+	if err := AddGrant(grant); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Granted %s on %s to %s", grant.Permission, displayApp(grant.App), grant.Subject))
+	return nil
+}
+
+// CommandKeyAdd implements auth:key-add
+func CommandKeyAdd(userName string, key string) error {
+	if userName == "" || key == "" {
+		return errors.New("Please specify a user and an ssh key name or fingerprint")
+	}
+	if err := AddUserKey(userName, key); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Mapped ssh key %s to %s", key, userName))
+	return nil
+}
+
+// CommandKeyRemove implements auth:key-remove
+func CommandKeyRemove(userName string, key string) error {
+	if userName == "" || key == "" {
+		return errors.New("Please specify a user and an ssh key name or fingerprint")
+	}
+	if err := RemoveUserKey(userName, key); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Unmapped ssh key %s from %s", key, userName))
+	return nil
+}
+
+// CommandList implements auth:list
+func CommandList(format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	users, err := GetUsers()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(users)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+		return nil
+	}
+
+	status := "disabled"
+	if IsEnabled() {
+		status = "enabled"
+	}
+	common.LogInfo2Quiet(fmt.Sprintf("Access control %s", status))
+	if len(users) == 0 {
+		common.LogVerbose("No users configured")
+		return nil
+	}
+
+	grants, err := GetGrants()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		common.LogInfo2Quiet(fmt.Sprintf("%s user information", user.Name))
+		common.LogVerbose(fmt.Sprintf("%s%s", right("Keys:", 31), strings.Join(user.Keys, " ")))
+		common.LogVerbose(fmt.Sprintf("%s%s", right("Roles:", 31), strings.Join(user.Roles, " ")))
+		common.LogVerbose(fmt.Sprintf("%s%s", right("Grants:", 31), strings.Join(formatGrants(user, grants), " ")))
+	}
+	return nil
+}
+
+// CommandRevoke implements auth:revoke
+func CommandRevoke(subject string, appName string, permission string, allApps bool) error {
+	grant, err := parseGrant(subject, appName, permission, allApps)
+	if err != nil {
+		return err
+	}
+	if err := RemoveGrant(grant); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Revoked %s on %s from %s", grant.Permission, displayApp(grant.App), grant.Subject))
+	return nil
+}
+
+// CommandRoleAdd implements auth:role-add
+func CommandRoleAdd(userName string, role string) error {
+	if userName == "" || role == "" {
+		return errors.New("Please specify a user and a role")
+	}
+	if err := AddUserRole(userName, role); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Added role %s to %s", role, userName))
+	return nil
+}
+
+// CommandRoleRemove implements auth:role-remove
+func CommandRoleRemove(userName string, role string) error {
+	if userName == "" || role == "" {
+		return errors.New("Please specify a user and a role")
+	}
+	if err := RemoveUserRole(userName, role); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Removed role %s from %s", role, userName))
+	return nil
+}
+
+// CommandUserAdd implements auth:user-add
+func CommandUserAdd(userName string, roles []string) error {
+	if userName == "" {
+		return errors.New("Please specify a user")
+	}
+	if err := AddUser(userName, roles); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Added user %s", userName))
+	return nil
+}
+
+// CommandUserRemove implements auth:user-remove
+func CommandUserRemove(userName string) error {
+	if userName == "" {
+		return errors.New("Please specify a user")
+	}
+	if err := RemoveUser(userName); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Removed user %s", userName))
+	return nil
+}
+
+// CommandWhoami implements auth:whoami
+func CommandWhoami(sshUser string, sshName string, fingerprint string) error {
+	if sshUser == "root" {
+		common.LogInfo2Quiet("root user information")
+		common.LogVerbose(fmt.Sprintf("%s%s", right("Roles:", 31), AdminRole))
+		return nil
+	}
+
+	user, ok := FindUser(fingerprint, sshName)
+	if !ok {
+		return fmt.Errorf("No dokku user is mapped to the ssh key %s", sshName)
+	}
+
+	grants, err := GetGrants()
+	if err != nil {
+		return err
+	}
+	common.LogInfo2Quiet(fmt.Sprintf("%s user information", user.Name))
+	common.LogVerbose(fmt.Sprintf("%s%s", right("Roles:", 31), strings.Join(user.Roles, " ")))
+	common.LogVerbose(fmt.Sprintf("%s%s", right("Grants:", 31), strings.Join(formatGrants(user, grants), " ")))
+	return nil
+}
+
+func displayApp(appName string) string {
+	if appName == AllApps {
+		return "all apps"
+	}
+	return appName
+}
+
+// formatGrants returns the grants held by a user directly or through their roles as app:permission pairs
+func formatGrants(user User, grants []Grant) []string {
+	subjects := map[string]bool{user.Name: true}
+	for _, role := range user.Roles {
+		subjects["@"+role] = true
+	}
+
+	formatted := []string{}
+	for _, grant := range grants {
+		if !subjects[grant.Subject] {
+			continue
+		}
+		app := grant.App
+		if app == AllApps {
+			app = "*"
+		}
+		entry := fmt.Sprintf("%s:%s", app, grant.Permission)
+		if grant.Subject != user.Name {
+			entry = fmt.Sprintf("%s(%s)", entry, grant.Subject)
+		}
+		formatted = append(formatted, entry)
+	}
+	return formatted
+}
+
+func parseGrant(subject string, appName string, permission string, allApps bool) (Grant, error) {
+	if allApps {
+		if permission != "" {
+			return Grant{}, errors.New("An app may not be specified along with --all")
+		}
+		permission = appName
+		appName = AllApps
+	}
+	if subject == "" || appName == "" || permission == "" {
+		return Grant{}, errors.New("Please specify a user or @role, an app or --all, and a permission")
+	}
+	return Grant{Subject: subject, App: appName, Permission: permission}, nil
+}
+
+func right(str string, length int) string {
+	if len(str) >= length {
+		return str + " "
+	}
+	return str + strings.Repeat(" ", length-len(str))
+}
@@ -0,0 +1,335 @@
+package certs
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// DefaultACMEServer is the directory url of the Let's Encrypt production environment
+	DefaultACMEServer = "https://acme-v02.api.letsencrypt.org/directory"
+
+	// DefaultRenewDays is the number of days ahead of expiry at which automatic certificates are renewed
+	DefaultRenewDays = 30
+
+	// globalAppName is the property namespace holding host-wide acme state
+	globalAppName = "_global_"
+
+	// issueTimeout bounds the time spent obtaining a single certificate
+	issueTimeout = 5 * time.Minute
+
+	// maxCommonNameLength is the maximum length of a certificate subject common name
+	maxCommonNameLength = 64
+)
+
+// AutoOptions contains the acme settings used to obtain certificates for an app
+type AutoOptions struct {
+	Email    string
+	Server   string
+	CABundle string
+}
+
+// GetAutoOptions returns the acme settings stored for an app
+func GetAutoOptions(appName string) AutoOptions {
+	return AutoOptions{
+		Email:    common.PropertyGet("certs", appName, "acme-email"),
+		Server:   common.PropertyGetDefault("certs", appName, "acme-server", DefaultACMEServer),
+		CABundle: common.PropertyGet("certs", appName, "acme-ca-bundle"),
+	}
+}
+
+// GetChallengePath returns the directory served by the proxy at /.well-known/acme-challenge/ for an app
+func GetChallengePath(appName string) string {
+	return strings.Join([]string{common.MustGetEnv("DOKKU_ROOT"), appName, "acme-challenge"}, "/")
+}
+
+// GetAutoDomains returns the domains of an app that can be validated via the http-01 challenge
+func GetAutoDomains(appName string) ([]string, error) {
+	seen := make(map[string]bool)
+	domainList := []string{}
+	for _, domain := range domains.GetAppDomains(appName) {
+		domain = strings.TrimSpace(domain)
+		if domain == "" || domains.IsRegex(domain) || seen[domain] {
+			continue
+		}
+		if domains.IsWildcard(domain) {
+			return []string{}, fmt.Errorf("Wildcard domains cannot be validated via the http-01 challenge: %s", domain)
+		}
+		seen[domain] = true
+		domainList = append(domainList, domain)
+	}
+
+	if len(domainList) == 0 {
+		return domainList, fmt.Errorf("No domains configured for %s, add one via domains:add", appName)
+	}
+	return domainList, nil
+}
+
+// IsAutoEnabled returns true if the certificate for an app is managed via acme
+func IsAutoEnabled(appName string) bool {
+	return common.PropertyGet("certs", appName, "auto") == "true"
+}
+
+// IssueCertificate obtains a certificate covering every domain of an app via the acme http-01 challenge
+// and installs it where certs:add would
+func IssueCertificate(appName string, options AutoOptions) error {
+	domainList, err := GetAutoDomains(appName)
+	if err != nil {
+		return err
+	}
+
+	// configs generated before the challenge location was added to the nginx template would not serve the challenge
+	if err := common.PlugnTrigger("proxy-build-config", appName); err != nil {
+		return err
+	}
+
+	common.LogInfo1(fmt.Sprintf("Requesting certificate for %s from %s", strings.Join(domainList, ", "), options.Server))
+	certPEM, keyPEM, err := ObtainCertificate(domainList, GetChallengePath(appName), options)
+	if err != nil {
+		return err
+	}
+
+	if err := installCertificate(appName, certPEM, keyPEM); err != nil {
+		return err
+	}
+	common.LogVerbose(fmt.Sprintf("Installed certificate for %s", appName))
+
+	if err := common.PlugnTrigger("post-certs-update", appName); err != nil {
+		return err
+	}
+	return common.PlugnTrigger("post-domains-update", appName)
+}
+
+// ObtainCertificate completes an acme order for the given domains, writing http-01 challenge responses
+// to challengePath, and returns the pem-encoded certificate chain and private key
+func ObtainCertificate(domainList []string, challengePath string, options AutoOptions) ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), issueTimeout)
+	defer cancel()
+
+	client, err := newACMEClient(ctx, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domainList...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to create certificate order: %s", err.Error())
+	}
+
+	defer os.RemoveAll(challengePath)
+	for _, authzURL := range order.AuthzURLs {
+		if err := completeHTTP01(ctx, client, challengePath, authzURL); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, nil, fmt.Errorf("Certificate order was not authorized: %s", err.Error())
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to generate certificate key: %s", err.Error())
+	}
+
+	csr, err := createCSR(domainList, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to finalize certificate order: %s", err.Error())
+	}
+
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to encode certificate key: %s", err.Error())
+	}
+	return certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// NeedsRenewal returns true if an app has no certificate or its certificate expires within the given number of days
+func NeedsRenewal(appName string, days int) bool {
+	if !IsSSLEnabled(appName) {
+		return true
+	}
+
+	cert, err := LoadAppCertificate(appName)
+	if err != nil {
+		return true
+	}
+	return time.Until(cert.Leaf.NotAfter) < time.Duration(days)*24*time.Hour
+}
+
+func completeHTTP01(ctx context.Context, client *acme.Client, challengePath string, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch authorization: %s", err.Error())
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("No http-01 challenge offered for %s", authz.Identifier.Value)
+	}
+
+	response, err := client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return fmt.Errorf("Unable to compute challenge response for %s: %s", authz.Identifier.Value, err.Error())
+	}
+
+	if err := os.MkdirAll(challengePath, 0755); err != nil {
+		return fmt.Errorf("Unable to create challenge directory: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(challengePath, challenge.Token), []byte(response), 0644); err != nil {
+		return fmt.Errorf("Unable to write challenge response: %s", err.Error())
+	}
+
+	common.LogVerbose(fmt.Sprintf("Validating %s", authz.Identifier.Value))
+	if _, err := client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("Unable to accept challenge for %s: %s", authz.Identifier.Value, err.Error())
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("Validation failed for %s, ensure the app is deployed and the domain resolves to this server: %s", authz.Identifier.Value, err.Error())
+	}
+	return nil
+}
+
+func createCSR(domainList []string, key crypto.Signer) ([]byte, error) {
+	template := &x509.CertificateRequest{DNSNames: domainList}
+	if len(domainList[0]) <= maxCommonNameLength {
+		template.Subject = pkix.Name{CommonName: domainList[0]}
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create certificate signing request: %s", err.Error())
+	}
+	return csr, nil
+}
+
+func installCertificate(appName string, certPEM []byte, keyPEM []byte) error {
+	sslPath := GetAppSSLPath(appName)
+	if err := os.MkdirAll(sslPath, 0750); err != nil {
+		return fmt.Errorf("Unable to create ssl directory: %s", err.Error())
+	}
+	if err := os.Chmod(sslPath, 0750); err != nil {
+		return fmt.Errorf("Unable to set ssl directory permissions: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(sslPath+"/server.crt", certPEM, 0640); err != nil {
+		return fmt.Errorf("Unable to write certificate: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(sslPath+"/server.key", keyPEM, 0640); err != nil {
+		return fmt.Errorf("Unable to write certificate key: %s", err.Error())
+	}
+	return nil
+}
+
+// loadAccountKey returns the account key for an acme server, generating and storing one if necessary
+func loadAccountKey(server string) (crypto.Signer, error) {
+	sum := sha256.Sum256([]byte(server))
+	property := "acme-account-key-" + hex.EncodeToString(sum[:8])
+
+	if keyPEM := common.PropertyGet("certs", globalAppName, property); keyPEM != "" {
+		block, _ := pem.Decode([]byte(keyPEM))
+		if block == nil {
+			return nil, errors.New("Unable to decode stored acme account key")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to generate acme account key: %s", err.Error())
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to encode acme account key: %s", err.Error())
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := common.PropertyWrite("certs", globalAppName, property, string(keyPEM)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newACMEClient(ctx context.Context, options AutoOptions) (*acme.Client, error) {
+	key, err := loadAccountKey(options.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &acme.Client{
+		Key:          key,
+		DirectoryURL: options.Server,
+		UserAgent:    "dokku",
+	}
+
+	if options.CABundle != "" {
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+
+		bundle, err := ioutil.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read ca bundle: %s", err.Error())
+		}
+		if !roots.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("No certificates found in ca bundle %s", options.CABundle)
+		}
+
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: roots},
+			},
+		}
+	}
+
+	account := &acme.Account{}
+	if options.Email != "" {
+		account.Contact = []string{"mailto:" + options.Email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("Unable to register acme account: %s", err.Error())
+	}
+	return client, nil
+}
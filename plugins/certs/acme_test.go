@@ -0,0 +1,123 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func writeTestVhosts(appName string, domainList ...string) {
+	appRoot := filepath.Join(os.Getenv("DOKKU_ROOT"), appName)
+	Expect(os.MkdirAll(appRoot, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(appRoot, "VHOST"), []byte(strings.Join(domainList, "\n")+"\n"), 0644)).To(Succeed())
+}
+
+func TestCertsAutoDomains(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	writeTestVhosts("auto-app", "www.example.com", "example.com", "www.example.com", "~^api\\d+\\.example\\.com$")
+	Expect(GetAutoDomains("auto-app")).To(Equal([]string{"www.example.com", "example.com"}))
+
+	writeTestVhosts("wildcard-app", "example.com", "*.example.com")
+	_, err := GetAutoDomains("wildcard-app")
+	Expect(err).To(HaveOccurred())
+
+	writeTestVhosts("empty-app")
+	_, err = GetAutoDomains("empty-app")
+	Expect(err).To(HaveOccurred())
+}
+
+func TestCertsCreateCSR(t *testing.T) {
+	RegisterTestingT(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	long := strings.Repeat("a", 60) + ".example.com"
+	der, err := createCSR([]string{long, "example.com"}, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	csr, err := x509.ParseCertificateRequest(der)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(csr.Subject.CommonName).To(BeEmpty())
+	Expect(csr.DNSNames).To(Equal([]string{long, "example.com"}))
+}
+
+func TestCertsNeedsRenewal(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	writeTestVhosts("renew-app", "example.com")
+	Expect(NeedsRenewal("renew-app", DefaultRenewDays)).To(BeTrue())
+
+	leaf := generateTestCert("example.com", nil, false, nil)
+	Expect(installCertificate("renew-app", leaf.certPEM, leaf.keyPEM)).To(Succeed())
+	Expect(NeedsRenewal("renew-app", 30)).To(BeTrue())
+	Expect(NeedsRenewal("renew-app", 5)).To(BeFalse())
+}
+
+// TestCertsCommandAutoACME runs certs:auto and certs:renew against a local acme test server such as pebble, for example:
+//
+//	ACME_TEST_DIRECTORY=https://localhost:14000/dir ACME_TEST_CA_BUNDLE=pebble.minica.pem \
+//	ACME_TEST_HTTP_ADDRESS=:5002 ACME_TEST_DOMAIN=test.dokku.me go test
+func TestCertsCommandAutoACME(t *testing.T) {
+	directory := os.Getenv("ACME_TEST_DIRECTORY")
+	if directory == "" {
+		t.Skip("ACME_TEST_DIRECTORY is not set")
+	}
+
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+
+	domain := os.Getenv("ACME_TEST_DOMAIN")
+	if domain == "" {
+		domain = "test.dokku.me"
+	}
+	address := os.Getenv("ACME_TEST_HTTP_ADDRESS")
+	if address == "" {
+		address = ":5002"
+	}
+
+	// the proxy is stood in for by a plugn stub recording triggers and a server for the challenge directory
+	triggersLog := roots.StubPlugn(t)
+
+	appName := "auto-app"
+	writeTestVhosts(appName, domain)
+	listener, err := net.Listen("tcp", address)
+	Expect(err).NotTo(HaveOccurred())
+	server := &http.Server{Handler: http.StripPrefix("/.well-known/acme-challenge/", http.FileServer(http.Dir(GetChallengePath(appName))))}
+	go server.Serve(listener)
+	defer server.Close()
+
+	Expect(CommandAuto(appName, "test@dokku.me", directory, os.Getenv("ACME_TEST_CA_BUNDLE"))).To(Succeed())
+	Expect(IsAutoEnabled(appName)).To(BeTrue())
+	Expect(GetChallengePath(appName)).NotTo(BeADirectory())
+
+	cert, err := LoadAppCertificate(appName)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.VerifyKey()).To(Succeed())
+	Expect(cert.Covers(domain)).To(BeTrue())
+	Expect(cert.Intermediates).NotTo(BeEmpty())
+
+	b, err := ioutil.ReadFile(triggersLog)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(strings.Split(strings.TrimSpace(string(b)), "\n")).To(Equal([]string{
+		"trigger proxy-build-config auto-app",
+		"trigger post-certs-update auto-app",
+		"trigger post-domains-update auto-app",
+	}))
+
+	Expect(CommandRenew(appName, false, DefaultRenewDays, true)).To(Succeed(), "the stored account key is reused on subsequent orders")
+}
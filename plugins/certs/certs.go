@@ -0,0 +1,318 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+const (
+	// dateFormat mirrors the date format output by openssl
+	dateFormat = "Jan _2 15:04:05 2006 GMT"
+)
+
+// Certificate is a parsed app certificate along with its intermediates and private key
+type Certificate struct {
+	Leaf          *x509.Certificate
+	Intermediates []*x509.Certificate
+	certPEM       []byte
+	keyPEM        []byte
+}
+
+// CertificateInfo contains the reportable details of an app's ssl certificate
+type CertificateInfo struct {
+	App           string     `json:"app"`
+	Dir           string     `json:"ssl-dir"`
+	Enabled       bool       `json:"ssl-enabled"`
+	Hostnames     []string   `json:"ssl-hostnames"`
+	ExpiresAt     *time.Time `json:"ssl-expires-at"`
+	Issuer        string     `json:"ssl-issuer"`
+	StartsAt      *time.Time `json:"ssl-starts-at"`
+	Subject       string     `json:"ssl-subject"`
+	Verified      string     `json:"ssl-verified"`
+	KeyMatches    bool       `json:"ssl-key-matches"`
+	ChainComplete bool       `json:"ssl-chain-complete"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// GetAppSSLPath returns the directory containing the ssl certificate and key for an app
+func GetAppSSLPath(appName string) string {
+	return strings.Join([]string{common.MustGetEnv("DOKKU_ROOT"), appName, "tls"}, "/")
+}
+
+// GetCertificateInfo returns the reportable details of an app's ssl certificate
+func GetCertificateInfo(appName string) (CertificateInfo, error) {
+	info := CertificateInfo{
+		App:       appName,
+		Dir:       GetAppSSLPath(appName),
+		Hostnames: []string{},
+	}
+	if !IsSSLEnabled(appName) {
+		return info, nil
+	}
+
+	info.Enabled = true
+	cert, err := LoadAppCertificate(appName)
+	if err != nil {
+		return info, err
+	}
+
+	info.Hostnames = cert.Hostnames()
+	info.ExpiresAt = &cert.Leaf.NotAfter
+	info.Issuer = formatName(cert.Leaf.Issuer, ", ")
+	info.StartsAt = &cert.Leaf.NotBefore
+	info.Subject = formatName(cert.Leaf.Subject, "; ")
+	info.Verified = "self signed"
+	if cert.IsVerified() {
+		info.Verified = "verified by a certificate authority"
+	}
+	info.KeyMatches = cert.VerifyKey() == nil
+	info.ChainComplete = cert.VerifyChain() == nil
+	return info, nil
+}
+
+// GetCertificateInfos returns the reportable details of the ssl certificate of every app. A
+// certificate that cannot be read is reported with its error rather than failing the whole list
+func GetCertificateInfos() ([]CertificateInfo, error) {
+	infos := []CertificateInfo{}
+	apps, err := common.DokkuApps()
+	if err != nil {
+		return infos, err
+	}
+
+	for _, appName := range apps {
+		info, err := GetCertificateInfo(appName)
+		if err != nil {
+			info.Error = err.Error()
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// GetUncoveredDomains returns the domains that are not covered by a certificate
+// when certPath is empty the certificate installed for the app is used,
+// and when no domains are specified the current domains of the app are checked
+func GetUncoveredDomains(appName string, certPath string, domainList []string) ([]string, error) {
+	if certPath == "" {
+		if !IsSSLEnabled(appName) {
+			return []string{}, nil
+		}
+		certPath = GetAppSSLPath(appName) + "/server.crt"
+	}
+
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return []string{}, fmt.Errorf("Unable to read certificate %s: %s", certPath, err.Error())
+	}
+
+	cert, err := ParseCertificate(certPEM, nil)
+	if err != nil {
+		return []string{}, err
+	}
+
+	if len(domainList) == 0 {
+		domainList = domains.GetAppDomains(appName)
+	}
+	return cert.UncoveredDomains(domainList), nil
+}
+
+// IsSSLEnabled returns true if an app has both a certificate and key installed
+func IsSSLEnabled(appName string) bool {
+	sslPath := GetAppSSLPath(appName)
+	return common.FileExists(sslPath+"/server.crt") && common.FileExists(sslPath+"/server.key")
+}
+
+// LoadAppCertificate reads and parses the certificate and key installed for an app
+func LoadAppCertificate(appName string) (*Certificate, error) {
+	sslPath := GetAppSSLPath(appName)
+	certPEM, err := ioutil.ReadFile(sslPath + "/server.crt")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read certificate for %s: %s", appName, err.Error())
+	}
+
+	keyPEM, err := ioutil.ReadFile(sslPath + "/server.key")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read key for %s: %s", appName, err.Error())
+	}
+
+	return ParseCertificate(certPEM, keyPEM)
+}
+
+// ParseCertificate parses a pem-encoded certificate chain and private key
+// the first certificate is treated as the leaf and the remainder as intermediates
+func ParseCertificate(certPEM []byte, keyPEM []byte) (*Certificate, error) {
+	cert := &Certificate{certPEM: certPEM, keyPEM: keyPEM}
+
+	rest := certPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse certificate: %s", err.Error())
+		}
+		if cert.Leaf == nil {
+			cert.Leaf = parsed
+		} else {
+			cert.Intermediates = append(cert.Intermediates, parsed)
+		}
+	}
+
+	if cert.Leaf == nil {
+		return nil, errors.New("No certificate found in pem data")
+	}
+	return cert, nil
+}
+
+// Hostnames returns the sorted unique dns subject alternative names of the certificate, falling
+// back to the common name when there are none, as clients ignore the common name otherwise
+func (c *Certificate) Hostnames() []string {
+	names := c.Leaf.DNSNames
+	if len(names) == 0 {
+		names = []string{c.Leaf.Subject.CommonName}
+	}
+
+	seen := make(map[string]bool)
+	hostnames := []string{}
+	for _, hostname := range names {
+		if hostname == "" || seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// Covers returns true if the domain matches one of the hostnames of the certificate
+// wildcard names cover exactly one label, and wildcard domains are only covered by an identical wildcard name
+func (c *Certificate) Covers(domain string) bool {
+	for _, hostname := range c.Hostnames() {
+		if domains.Matches(hostname, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// UncoveredDomains returns the domains that are not covered by the certificate
+// regular expression domains cannot be checked and are skipped
+func (c *Certificate) UncoveredDomains(domainList []string) []string {
+	uncovered := []string{}
+	for _, domain := range domainList {
+		domain = strings.TrimSpace(domain)
+		if domain == "" || domains.IsRegex(domain) {
+			continue
+		}
+		if !c.Covers(domain) {
+			uncovered = append(uncovered, domain)
+		}
+	}
+	return uncovered
+}
+
+// IsVerified returns true if the certificate chains up to a trusted system root
+func (c *Certificate) IsVerified() bool {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		return false
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range c.Intermediates {
+		intermediates.AddCert(intermediate)
+	}
+
+	_, err = c.Leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err == nil
+}
+
+// VerifyChain returns an error if any certificate is not signed by the next one in the file,
+// or if the last certificate is neither self-signed nor issued by a trusted system root
+func (c *Certificate) VerifyChain() error {
+	chain := append([]*x509.Certificate{c.Leaf}, c.Intermediates...)
+	for i := 0; i < len(chain)-1; i++ {
+		if err := chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return fmt.Errorf("Certificate for %s is not signed by the next certificate in the chain (%s)", chain[i].Subject.CommonName, chain[i+1].Subject.CommonName)
+		}
+	}
+
+	last := chain[len(chain)-1]
+	if isSelfSigned(last) {
+		return nil
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		return fmt.Errorf("Unable to load system certificate pool: %s", err.Error())
+	}
+	if _, err := last.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		return fmt.Errorf("Certificate chain is incomplete, missing the issuer of %s (%s)", last.Subject.CommonName, formatName(last.Issuer, ", "))
+	}
+	return nil
+}
+
+// VerifyKey returns an error if the private key does not match the certificate
+func (c *Certificate) VerifyKey() error {
+	if _, err := tls.X509KeyPair(c.certPEM, c.keyPEM); err != nil {
+		return fmt.Errorf("Private key does not match certificate: %s", err.Error())
+	}
+	return nil
+}
+
+func formatName(name pkix.Name, separator string) string {
+	var parts []string
+	add := func(key string, values []string) {
+		for _, value := range values {
+			parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	add("C", name.Country)
+	add("ST", name.Province)
+	add("L", name.Locality)
+	add("O", name.Organization)
+	add("OU", name.OrganizationalUnit)
+	if name.CommonName != "" {
+		add("CN", []string{name.CommonName})
+	}
+	return strings.Join(parts, separator)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(dateFormat)
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
@@ -0,0 +1,175 @@
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func generateTestCert(commonName string, dnsNames []string, isCA bool, parent *testCert) *testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"dokku"}},
+		DNSNames:              dnsNames,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}
+}
+
+func TestCertsParseCertificate(t *testing.T) {
+	RegisterTestingT(t)
+	leaf := generateTestCert("example.com", []string{"www.example.com", "example.com", "*.api.example.com"}, false, nil)
+
+	cert, err := ParseCertificate(leaf.certPEM, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.Intermediates).To(BeEmpty())
+	Expect(cert.Hostnames()).To(Equal([]string{"*.api.example.com", "example.com", "www.example.com"}))
+	Expect(formatName(cert.Leaf.Subject, "; ")).To(Equal("O=dokku; CN=example.com"))
+
+	leaf = generateTestCert("legacy.example.com", []string{"www.example.com"}, false, nil)
+	cert, err = ParseCertificate(leaf.certPEM, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.Hostnames()).To(Equal([]string{"www.example.com"}))
+	Expect(cert.Covers("legacy.example.com")).To(BeFalse())
+
+	leaf = generateTestCert("legacy.example.com", nil, false, nil)
+	cert, err = ParseCertificate(leaf.certPEM, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.Hostnames()).To(Equal([]string{"legacy.example.com"}))
+	Expect(cert.Covers("legacy.example.com")).To(BeTrue())
+
+	_, err = ParseCertificate([]byte("not a certificate"), leaf.keyPEM)
+	Expect(err).To(HaveOccurred())
+}
+
+func TestCertsVerifyKey(t *testing.T) {
+	RegisterTestingT(t)
+	leaf := generateTestCert("example.com", nil, false, nil)
+	other := generateTestCert("example.org", nil, false, nil)
+
+	cert, err := ParseCertificate(leaf.certPEM, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.VerifyKey()).To(Succeed())
+
+	cert, err = ParseCertificate(leaf.certPEM, other.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.VerifyKey()).NotTo(Succeed())
+}
+
+func TestCertsVerifyChain(t *testing.T) {
+	RegisterTestingT(t)
+	root := generateTestCert("Test Root", nil, true, nil)
+	intermediate := generateTestCert("Test Intermediate", nil, true, root)
+	leaf := generateTestCert("example.com", nil, false, intermediate)
+
+	selfSigned, err := ParseCertificate(root.certPEM, root.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(selfSigned.VerifyChain()).To(Succeed())
+
+	complete := append(append(append([]byte{}, leaf.certPEM...), intermediate.certPEM...), root.certPEM...)
+	cert, err := ParseCertificate(complete, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.Intermediates).To(HaveLen(2))
+	Expect(cert.VerifyChain()).To(Succeed())
+
+	cert, err = ParseCertificate(leaf.certPEM, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.VerifyChain()).NotTo(Succeed())
+
+	outOfOrder := append(append([]byte{}, leaf.certPEM...), root.certPEM...)
+	cert, err = ParseCertificate(outOfOrder, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.VerifyChain()).NotTo(Succeed())
+	Expect(cert.IsVerified()).To(BeFalse())
+}
+
+func TestCertsUncoveredDomains(t *testing.T) {
+	RegisterTestingT(t)
+	leaf := generateTestCert("example.com", []string{"example.com", "*.example.com", "*.wildcard.example.org"}, false, nil)
+
+	cert, err := ParseCertificate(leaf.certPEM, leaf.keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cert.Covers("example.com")).To(BeTrue())
+	Expect(cert.Covers("WWW.example.com")).To(BeTrue())
+	Expect(cert.Covers("*.wildcard.example.org")).To(BeTrue())
+	Expect(cert.Covers("a.b.example.com")).To(BeFalse())
+	Expect(cert.Covers("*.example.org")).To(BeFalse())
+
+	uncovered := cert.UncoveredDomains([]string{"example.com", "api.example.com", "a.b.example.com", "", "~^api\\d+\\.example\\.com$", "example.org"})
+	Expect(uncovered).To(Equal([]string{"a.b.example.com", "example.org"}))
+}
+
+func TestCertsGetCertificateInfos(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	_, err := GetCertificateInfos()
+	Expect(err).To(HaveOccurred())
+
+	leaf := generateTestCert("example.com", []string{"example.com"}, false, nil)
+	for appName, certPEM := range map[string][]byte{"valid": leaf.certPEM, "broken": []byte("not a certificate")} {
+		sslPath := GetAppSSLPath(appName)
+		Expect(os.MkdirAll(sslPath, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(sslPath, "server.crt"), certPEM, 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(sslPath, "server.key"), leaf.keyPEM, 0644)).To(Succeed())
+	}
+	Expect(os.MkdirAll(filepath.Join(os.Getenv("DOKKU_ROOT"), "plain"), 0755)).To(Succeed())
+
+	infos, err := GetCertificateInfos()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(infos).To(HaveLen(3))
+
+	Expect(infos[0].App).To(Equal("broken"))
+	Expect(infos[0].Enabled).To(BeTrue())
+	Expect(infos[0].Error).NotTo(BeEmpty())
+
+	Expect(infos[1].App).To(Equal("plain"))
+	Expect(infos[1].Enabled).To(BeFalse())
+	Expect(infos[1].Error).To(BeEmpty())
+
+	Expect(infos[2].App).To(Equal("valid"))
+	Expect(infos[2].Hostnames).To(Equal([]string{"example.com"}))
+	Expect(infos[2].Error).To(BeEmpty())
+}
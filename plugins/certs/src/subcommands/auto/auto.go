@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/certs"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// obtains a certificate for all app domains via acme
+func main() {
+	args := flag.NewFlagSet("certs:auto", flag.ExitOnError)
+	email := args.String("email", "", "email: the contact email for the acme account")
+	server := args.String("server", "", "server: the acme directory url, defaults to Let's Encrypt")
+	caBundle := args.String("ca-bundle", "", "ca-bundle: a pem file of additional roots to trust when connecting to the acme server")
+	args.Parse(os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := certs.CommandAuto(appName, *email, *server, *caBundle); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/certs"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// lists app certificates expiring within a number of days
+func main() {
+	args := flag.NewFlagSet("certs:expiring", flag.ExitOnError)
+	days := args.Int("days", 14, "days: the number of days within which a certificate is considered expiring")
+	format := args.String("format", "stdout", "format: [ stdout | json ] which format to output expiring certificates as")
+	args.Parse(os.Args[2:])
+
+	if err := certs.CommandExpiring(*days, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
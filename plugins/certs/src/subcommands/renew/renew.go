@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/certs"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// renews automatic certificates ahead of expiry
+func main() {
+	args := flag.NewFlagSet("certs:renew", flag.ExitOnError)
+	all := args.Bool("all", false, "--all: renew certificates for all apps with automatic certificates")
+	days := args.Int("days", certs.DefaultRenewDays, "days: renew certificates expiring within this number of days")
+	force := args.Bool("force", false, "--force: renew certificates regardless of expiry")
+	args.Parse(os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := certs.CommandRenew(appName, *all, *days, *force); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/certs"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// displays an ssl report for one or more apps
+func main() {
+	var appName, infoFlag string
+	format := "stdout"
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--format":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "--"):
+			infoFlag = arg
+		default:
+			appName = arg
+		}
+	}
+
+	if err := certs.CommandReport(appName, infoFlag, format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dokku/dokku/plugins/certs"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// outputs the domains of an app that are not covered by a certificate
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	certPath := flag.Arg(1)
+	domainList := []string{}
+	if flag.NArg() > 2 {
+		domainList = flag.Args()[2:]
+	}
+
+	uncovered, err := certs.GetUncoveredDomains(appName, certPath, domainList)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+	for _, domain := range uncovered {
+		fmt.Fprintln(os.Stdout, domain)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the certs plugin
+func main() {
+	if err := common.PropertySetup("certs"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the certs plugin: %s", err.Error()))
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// destroys the certs properties for a given app container
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := common.PropertyDestroy("certs", appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/certs"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// displays an ssl report for one or more apps
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := certs.ReportSingleApp(appName, ""); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,290 @@
+package certs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ExpiringCertificate is an app certificate that expires within the requested window
+type ExpiringCertificate struct {
+	App       string    `json:"app"`
+	ExpiresAt time.Time `json:"expires-at"`
+	DaysLeft  int       `json:"days-left"`
+	Hostnames []string  `json:"hostnames"`
+}
+
+// CommandAuto implements certs:auto
+func CommandAuto(appName string, email string, server string, caBundle string) error {
+	if appName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	options := GetAutoOptions(appName)
+	if email != "" {
+		options.Email = email
+	}
+	if server != "" {
+		options.Server = server
+	}
+	if caBundle != "" {
+		options.CABundle = caBundle
+	}
+
+	if err := IssueCertificate(appName, options); err != nil {
+		return err
+	}
+
+	properties := map[string]string{
+		"auto":           "true",
+		"acme-email":     options.Email,
+		"acme-server":    options.Server,
+		"acme-ca-bundle": options.CABundle,
+	}
+	for property, value := range properties {
+		if value == "" {
+			continue
+		}
+		if err := common.PropertyWrite("certs", appName, property, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandExpiring implements certs:expiring
+func CommandExpiring(days int, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+	if days < 0 {
+		return errors.New("Number of days must be zero or greater")
+	}
+
+	expiring := []ExpiringCertificate{}
+	apps, _ := common.DokkuApps()
+	deadline := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	for _, appName := range apps {
+		if !IsSSLEnabled(appName) {
+			continue
+		}
+
+		cert, err := LoadAppCertificate(appName)
+		if err != nil {
+			common.LogWarn(err.Error())
+			continue
+		}
+		if cert.Leaf.NotAfter.After(deadline) {
+			continue
+		}
+
+		expiring = append(expiring, ExpiringCertificate{
+			App:       appName,
+			ExpiresAt: cert.Leaf.NotAfter,
+			DaysLeft:  int(time.Until(cert.Leaf.NotAfter).Hours() / 24),
+			Hostnames: cert.Hostnames(),
+		})
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt)
+	})
+
+	if format == "json" {
+		b, err := json.Marshal(expiring)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+		return nil
+	}
+
+	if len(expiring) == 0 {
+		common.LogInfo1Quiet(fmt.Sprintf("No certificates expiring within %d days", days))
+		return nil
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("Certificates expiring within %d days", days))
+	for _, cert := range expiring {
+		common.LogVerbose(fmt.Sprintf("%s%s (%d days)", right(cert.App, 31), formatTime(&cert.ExpiresAt), cert.DaysLeft))
+	}
+	return nil
+}
+
+// CommandRenew implements certs:renew
+func CommandRenew(appName string, all bool, days int, force bool) error {
+	if days < 0 {
+		return errors.New("Number of days must be zero or greater")
+	}
+
+	var apps []string
+	if all {
+		allApps, _ := common.DokkuApps()
+		for _, appName := range allApps {
+			if IsAutoEnabled(appName) {
+				apps = append(apps, appName)
+			}
+		}
+		if len(apps) == 0 {
+			common.LogInfo1("No apps with automatic certificates found")
+			return nil
+		}
+	} else {
+		if appName == "" {
+			return errors.New("Please specify an app to run the command on or --all")
+		}
+		if err := common.VerifyAppName(appName); err != nil {
+			return err
+		}
+		if !IsAutoEnabled(appName) {
+			return fmt.Errorf("Automatic certificates are not enabled for %s, run certs:auto first", appName)
+		}
+		apps = []string{appName}
+	}
+
+	var failed []string
+	for _, appName := range apps {
+		if !force && !NeedsRenewal(appName, days) {
+			common.LogInfo1(fmt.Sprintf("Certificate for %s does not expire within %d days, skipping", appName, days))
+			continue
+		}
+
+		common.LogInfo1(fmt.Sprintf("Renewing certificate for %s", appName))
+		if err := IssueCertificate(appName, GetAutoOptions(appName)); err != nil {
+			common.LogWarn(err.Error())
+			failed = append(failed, appName)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Unable to renew certificates for: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// CommandReport implements certs:report
+func CommandReport(appName string, infoFlag string, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	if format == "json" {
+		if infoFlag != "" {
+			return errors.New("Info flags may not be combined with --format json")
+		}
+		return reportJSON(appName)
+	}
+
+	if len(appName) == 0 {
+		apps, err := common.DokkuApps()
+		if err != nil {
+			return err
+		}
+		for _, appName := range apps {
+			if err := ReportSingleApp(appName, infoFlag); err != nil {
+				common.LogWarn(err.Error())
+			}
+		}
+		return nil
+	}
+
+	return ReportSingleApp(appName, infoFlag)
+}
+
+// ReportSingleApp is an internal function that displays the ssl report for one app
+func ReportSingleApp(appName string, infoFlag string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	info, err := GetCertificateInfo(appName)
+	if err != nil {
+		return err
+	}
+
+	infoFlags := []struct {
+		flag  string
+		value string
+	}{
+		{"--ssl-dir", info.Dir},
+		{"--ssl-enabled", strconv.FormatBool(info.Enabled)},
+		{"--ssl-hostnames", strings.Join(info.Hostnames, " ")},
+		{"--ssl-expires-at", formatTime(info.ExpiresAt)},
+		{"--ssl-issuer", info.Issuer},
+		{"--ssl-starts-at", formatTime(info.StartsAt)},
+		{"--ssl-subject", info.Subject},
+		{"--ssl-verified", info.Verified},
+		{"--ssl-key-matches", boolIfEnabled(info.Enabled, info.KeyMatches)},
+		{"--ssl-chain-complete", boolIfEnabled(info.Enabled, info.ChainComplete)},
+	}
+
+	if len(infoFlag) == 0 {
+		common.LogInfo2Quiet(fmt.Sprintf("%s ssl information", appName))
+		for _, f := range infoFlags {
+			key := common.UcFirst(strings.Replace(strings.TrimPrefix(f.flag, "--"), "-", " ", -1))
+			common.LogVerbose(fmt.Sprintf("%s%s", right(fmt.Sprintf("%s:", key), 31), f.value))
+		}
+		return nil
+	}
+
+	var validFlags []string
+	for _, f := range infoFlags {
+		if infoFlag == f.flag {
+			fmt.Fprintln(os.Stdout, f.value)
+			return nil
+		}
+		validFlags = append(validFlags, f.flag)
+	}
+	return fmt.Errorf("Invalid flag passed, valid flags: %s", strings.Join(validFlags, ", "))
+}
+
+func boolIfEnabled(enabled bool, value bool) string {
+	if !enabled {
+		return ""
+	}
+	return strconv.FormatBool(value)
+}
+
+func reportJSON(appName string) error {
+	var v interface{}
+	if len(appName) > 0 {
+		if err := common.VerifyAppName(appName); err != nil {
+			return err
+		}
+		info, err := GetCertificateInfo(appName)
+		if err != nil {
+			return err
+		}
+		v = info
+	} else {
+		infos, err := GetCertificateInfos()
+		if err != nil {
+			return err
+		}
+		v = infos
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, string(b))
+	return nil
+}
+
+func right(str string, length int) string {
+	if len(str) >= length {
+		return str + " "
+	}
+	return str + strings.Repeat(" ", length-len(str))
+}
@@ -0,0 +1,153 @@
+package common
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MaskedValue replaces secrets in masked output
+const MaskedValue = "********"
+
+var (
+	// flagsWithValues are flags of dokku commands that consume the following argument
+	flagsWithValues = map[string]bool{
+		"--app":      true,
+		"--events":   true,
+		"--format":   true,
+		"--grep":     true,
+		"--index":    true,
+		"--label":    true,
+		"--limit":    true,
+		"--num":      true,
+		"--ps":       true,
+		"--run":      true,
+		"--secret":   true,
+		"--since":    true,
+		"--template": true,
+	}
+
+	// secretCommands are commands whose KEY=VALUE arguments are all secrets
+	secretCommands = map[string]bool{
+		"config:set":        true,
+		"config:set-global": true,
+	}
+
+	// secretFlags are flags whose values are secrets
+	secretFlags = map[string]bool{
+		"--password": true,
+		"--secret":   true,
+		"--token":    true,
+	}
+
+	// secretKeyWords mark an environment variable name as holding a secret
+	secretKeyWords = []string{"CREDENTIALS", "PASSWD", "PASSWORD", "SECRET", "TOKEN", "API_KEY", "ACCESS_KEY", "PRIVATE_KEY"}
+)
+
+// CommandAppName returns the app the arguments of a dokku command target, taken from
+// the git repository or the first positional argument
+func CommandAppName(args []string) string {
+	if len(args) < 2 {
+		return ""
+	}
+
+	command := args[0]
+	if command == "git-receive-pack" || command == "git-upload-pack" {
+		return strings.TrimPrefix(strings.Trim(args[1], "'"), "/")
+	}
+
+	for i := 1; i < len(args); i++ {
+		if flagsWithValues[args[i]] {
+			i++
+			continue
+		}
+		if strings.HasPrefix(args[i], "--") {
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+// IsSecretKey returns true if an environment variable name looks like it holds a secret
+func IsSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, word := range secretKeyWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskArgs returns a copy of the arguments of a dokku command with the values of secret flags,
+// secret KEY=VALUE assignments and url passwords masked
+func MaskArgs(command string, args []string) []string {
+	masked := make([]string, len(args))
+	maskNext := false
+	for i, arg := range args {
+		if maskNext {
+			masked[i] = MaskedValue
+			maskNext = false
+			continue
+		}
+
+		if secretFlags[arg] {
+			maskNext = true
+			masked[i] = arg
+			continue
+		}
+
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 && secretFlags[parts[0]] {
+			masked[i] = parts[0] + "=" + MaskedValue
+			continue
+		}
+
+		if secretCommands[command] && !strings.HasPrefix(arg, "-") && strings.Contains(arg, "=") {
+			masked[i] = strings.SplitN(arg, "=", 2)[0] + "=" + MaskedValue
+			continue
+		}
+
+		// arguments such as docker options may hold several space separated words
+		words := strings.Split(arg, " ")
+		for j, word := range words {
+			words[j] = maskWord(word)
+		}
+		masked[i] = strings.Join(words, " ")
+	}
+	return masked
+}
+
+// MaskURL returns a url with its password masked
+func MaskURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); !ok {
+		return value
+	}
+
+	// replace the password in place so the rest of the url keeps its original encoding
+	authority := value[strings.Index(value, "://")+3:]
+	if end := strings.IndexAny(authority, "/?#"); end != -1 {
+		authority = authority[:end]
+	}
+	userinfo := authority[:strings.LastIndex(authority, "@")]
+	prefix := value[:strings.Index(value, "://")+3]
+	return prefix + userinfo[:strings.Index(userinfo, ":")+1] + MaskedValue + value[len(prefix)+len(userinfo):]
+}
+
+func maskWord(word string) string {
+	if masked := MaskURL(word); masked != word {
+		return masked
+	}
+
+	parts := strings.SplitN(word, "=", 2)
+	if len(parts) == 2 && IsSecretKey(parts[0]) {
+		return parts[0] + "=" + MaskedValue
+	}
+	if len(parts) == 2 {
+		return parts[0] + "=" + MaskURL(parts[1])
+	}
+	return word
+}
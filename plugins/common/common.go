@@ -2,12 +2,16 @@ package common
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 	"unicode"
 
 	sh "github.com/codeskyblue/go-sh"
@@ -72,19 +76,19 @@ func GetDeployingAppImageName(appName, imageTag, imageRepo string) (imageName st
 		LogFail("(GetDeployingAppImageName) APP must not be empty")
 	}
 
-	b, err := sh.Command("plugn", "trigger", "deployed-app-repository", appName).Output()
+	b, err := PlugnTriggerOutput("deployed-app-repository", appName)
 	if err != nil {
 		LogFail(err.Error())
 	}
 	imageRemoteRepository := string(b[:])
 
-	b, err = sh.Command("plugn", "trigger", "deployed-app-image-tag", appName).Output()
+	b, err = PlugnTriggerOutput("deployed-app-image-tag", appName)
 	if err != nil {
 		LogFail(err.Error())
 	}
 	newImageTag := string(b[:])
 
-	b, err = sh.Command("plugn", "trigger", "deployed-app-image-repo", appName).Output()
+	b, err = PlugnTriggerOutput("deployed-app-image-repo", appName)
 	if err != nil {
 		LogFail(err.Error())
 	}
@@ -124,6 +128,43 @@ func ContainerIsRunning(containerID string) bool {
 	return strings.TrimSpace(string(b[:])) == "true"
 }
 
+// CopyFromImage copies a file from an image to a destination path. Relative source paths are
+// resolved against /app for herokuish images and the image working directory otherwise
+func CopyFromImage(image string, source string, destination string) error {
+	if !VerifyImage(image) {
+		return fmt.Errorf("Image %s does not exist", image)
+	}
+
+	if !strings.HasPrefix(source, "/") {
+		workDir := "/app"
+		if !IsImageHerokuishBased(image) {
+			var err error
+			if workDir, err = DockerInspect(image, "{{.Config.WorkingDir}}"); err != nil {
+				return err
+			}
+		}
+		if workDir != "" {
+			source = strings.TrimSuffix(workDir, "/") + "/" + source
+		}
+	}
+
+	createArgs := []interface{}{"create"}
+	if globalArgs := os.Getenv("DOKKU_GLOBAL_RUN_ARGS"); globalArgs != "" {
+		createArgs = append(createArgs, globalArgs)
+	}
+	b, err := sh.Command("docker", append(createArgs, image)...).Output()
+	if err != nil {
+		return fmt.Errorf("Unable to create container from image %s: %s", image, err.Error())
+	}
+	containerID := strings.TrimSpace(string(b))
+	defer sh.Command("docker", "rm", "-f", containerID).Output()
+
+	if _, err := sh.Command("docker", "cp", containerID+":"+source, destination).CombinedOutput(); err != nil {
+		return fmt.Errorf("Unable to copy %s from image %s", source, image)
+	}
+	return nil
+}
+
 // DirectoryExists returns if a path exists and is a directory
 func DirectoryExists(filePath string) bool {
 	fi, err := os.Stat(filePath)
@@ -175,6 +216,20 @@ func DokkuApps() (apps []string, err error) {
 	return
 }
 
+// ExitCode returns the exit code of a finished command from the error it returned,
+// or -1 if the command did not exit normally
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
+
 // FileToSlice reads in all the lines from a file into a string slice
 func FileToSlice(filePath string) (lines []string, err error) {
 	f, err := os.Open(filePath)
@@ -276,6 +331,30 @@ func MustGetEnv(key string) (val string) {
 	return
 }
 
+// ParseArgs parses a flagset, allowing flags to be specified before, between or after
+// positional arguments such as the app name. Positional arguments keep their order and
+// are available via args.Args() afterwards, and a "--" ends flag parsing as usual
+func ParseArgs(args *flag.FlagSet, arguments []string) error {
+	positional := []string{}
+	for {
+		if err := args.Parse(arguments); err != nil {
+			return err
+		}
+		if args.NArg() == 0 {
+			break
+		}
+
+		consumed := len(arguments) - args.NArg()
+		if consumed > 0 && arguments[consumed-1] == "--" {
+			positional = append(positional, args.Args()...)
+			break
+		}
+		positional = append(positional, args.Arg(0))
+		arguments = args.Args()[1:]
+	}
+	return args.Parse(append([]string{"--"}, positional...))
+}
+
 // ReadFirstLine gets the first line of a file that has contents and returns it
 // if there are no contents, an empty string is returned
 // will also return an empty string if the file does not exist
@@ -346,11 +425,39 @@ func VerifyImage(image string) bool {
 
 //PlugnTrigger fire the given plugn trigger with the given args
 func PlugnTrigger(triggerName string, args ...string) error {
+	return plugnTrigger(triggerName, args, func(session *sh.Session) error {
+		return session.Run()
+	})
+}
+
+// PlugnTriggerOutput fires the given plugn trigger with an empty stdin and returns its output
+func PlugnTriggerOutput(triggerName string, args ...string) ([]byte, error) {
+	var b []byte
+	err := plugnTrigger(triggerName, args, func(session *sh.Session) (err error) {
+		b, err = session.SetStdin(strings.NewReader("")).Output()
+		return err
+	})
+	return b, err
+}
+
+// plugnTrigger runs a trigger, recording the call when trigger tracing is enabled
+func plugnTrigger(triggerName string, args []string, run func(*sh.Session) error) error {
 	shellArgs := make([]interface{}, len(args)+2)
 	shellArgs[0] = "trigger"
 	shellArgs[1] = triggerName
 	for i, arg := range args {
 		shellArgs[i+2] = arg
 	}
-	return sh.Command("plugn", shellArgs...).Run()
+
+	if !TriggerTracingEnabled() {
+		return run(sh.Command("plugn", shellArgs...))
+	}
+
+	depth := triggerTraceDepth()
+	startedAt := time.Now()
+	err := run(sh.NewSession().SetEnv("DOKKU_TRACE_TRIGGERS_DEPTH", strconv.Itoa(depth+1)).Command("plugn", shellArgs...))
+	if traceErr := RecordTriggerTrace(NewTriggerTrace(triggerName, args, startedAt, ExitCode(err), depth)); traceErr != nil {
+		LogWarn(traceErr.Error())
+	}
+	return err
 }
@@ -1,8 +1,10 @@
 package common
 
 import (
+	"flag"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"strings"
 	"testing"
 
@@ -95,6 +97,13 @@ func TestCommonDokkuApps(t *testing.T) {
 	teardownTestApp()
 }
 
+func TestCommonExitCode(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(ExitCode(nil)).To(Equal(0))
+	Expect(ExitCode(exec.Command("sh", "-c", "exit 3").Run())).To(Equal(3))
+	Expect(ExitCode(exec.Command("/nonexistent").Run())).To(Equal(-1))
+}
+
 func TestCommonFileToSlice(t *testing.T) {
 	RegisterTestingT(t)
 	Expect(setupTestApp()).To(Succeed())
@@ -111,6 +120,27 @@ func TestCommonFileExists(t *testing.T) {
 	teardownTestApp()
 }
 
+func TestCommonParseArgs(t *testing.T) {
+	RegisterTestingT(t)
+	args := flag.NewFlagSet("test", flag.ContinueOnError)
+	name := args.String("name", "", "")
+	force := args.Bool("force", false, "")
+	Expect(ParseArgs(args, []string{"app", "--name", "value", "task", "--force"})).To(Succeed())
+	Expect(*name).To(Equal("value"))
+	Expect(*force).To(BeTrue())
+	Expect(args.Args()).To(Equal([]string{"app", "task"}))
+
+	args = flag.NewFlagSet("test", flag.ContinueOnError)
+	force = args.Bool("force", false, "")
+	Expect(ParseArgs(args, []string{"app", "--", "--force"})).To(Succeed())
+	Expect(*force).To(BeFalse())
+	Expect(args.Args()).To(Equal([]string{"app", "--force"}))
+
+	args = flag.NewFlagSet("test", flag.ContinueOnError)
+	args.SetOutput(ioutil.Discard)
+	Expect(ParseArgs(args, []string{"app", "--unknown"})).NotTo(Succeed())
+}
+
 func TestCommonReadFirstLine(t *testing.T) {
 	RegisterTestingT(t)
 	line := ReadFirstLine(testEnvFile)
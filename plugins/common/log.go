@@ -5,6 +5,11 @@ import (
 	"os"
 )
 
+// LogExclaim is the exclaim log formatter
+func LogExclaim(text string) {
+	fmt.Fprintln(os.Stdout, fmt.Sprintf(" !     %s", text))
+}
+
 // LogFail is the failure log formatter
 // prints text to stderr and exits with status 1
 func LogFail(text string) {
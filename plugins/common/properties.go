@@ -44,6 +44,20 @@ func CommandPropertySet(pluginName, appName, property, value string, properties
 	}
 }
 
+// PropertyClone clones the properties of a plugin from one app to another, overwriting existing values
+func PropertyClone(pluginName string, oldAppName string, newAppName string) error {
+	oldAppConfigRoot := getPluginAppPropertyPath(pluginName, oldAppName)
+	if _, err := os.Stat(oldAppConfigRoot); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := makePluginAppPropertyPath(pluginName, newAppName); err != nil {
+		return fmt.Errorf("Unable to create %s config directory for %s: %s", pluginName, newAppName, err.Error())
+	}
+
+	return clonePropertyDirectory(pluginName, oldAppName, newAppName, "")
+}
+
 // PropertyDelete deletes a property from the plugin properties for an app
 func PropertyDelete(pluginName string, appName string, property string) error {
 	propertyPath := getPropertyPath(pluginName, appName, property)
@@ -100,6 +114,7 @@ func PropertyGetAll(pluginName string, appName string) (map[string]string, error
 // PropertyGetDefault returns the value for a given property with a specified default value
 func PropertyGetDefault(pluginName, appName, property, defaultValue string) (val string) {
 	if !PropertyExists(pluginName, appName, property) {
+		val = defaultValue
 		return
 	}
 
@@ -153,7 +168,7 @@ func PropertyListAdd(pluginName string, appName string, property string, value s
 	}
 
 	file.Chmod(0600)
-	setPermissions(propertyPath, 0600)
+	SetPermissions(propertyPath, 0600)
 	return nil
 }
 
@@ -253,7 +268,7 @@ func PropertyListRemove(pluginName string, appName string, property string, valu
 	}
 
 	file.Chmod(0600)
-	setPermissions(propertyPath, 0600)
+	SetPermissions(propertyPath, 0600)
 
 	if !found {
 		return errors.New("Property not found, nothing was removed")
@@ -306,7 +321,7 @@ func PropertyListSet(pluginName string, appName string, property string, value s
 	}
 
 	file.Chmod(0600)
-	setPermissions(propertyPath, 0600)
+	SetPermissions(propertyPath, 0600)
 	return nil
 }
 
@@ -343,19 +358,88 @@ func PropertyWrite(pluginName string, appName string, property string, value str
 	}
 	defer file.Close()
 
-	fmt.Fprintf(file, value)
+	fmt.Fprint(file, value)
 	file.Chmod(0600)
-	setPermissions(propertyPath, 0600)
+	SetPermissions(propertyPath, 0600)
 	return nil
 }
 
+// PropertyPlugins returns the names of the plugins that hold properties for an app
+func PropertyPlugins(appName string) ([]string, error) {
+	pluginNames := []string{}
+	configRoot := path.Join(MustGetEnv("DOKKU_LIB_ROOT"), "config")
+	files, err := ioutil.ReadDir(configRoot)
+	if os.IsNotExist(err) {
+		return pluginNames, nil
+	}
+	if err != nil {
+		return pluginNames, fmt.Errorf("Unable to read plugin config directory: %s", err.Error())
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		if DirectoryExists(getPluginAppPropertyPath(file.Name(), appName)) {
+			pluginNames = append(pluginNames, file.Name())
+		}
+	}
+
+	return pluginNames, nil
+}
+
+// PropertyRename moves the properties of a plugin from one app to another
+func PropertyRename(pluginName string, oldAppName string, newAppName string) error {
+	if err := PropertyClone(pluginName, oldAppName, newAppName); err != nil {
+		return err
+	}
+	return PropertyDestroy(pluginName, oldAppName)
+}
+
 // PropertySetup creates the plugin config root
 func PropertySetup(pluginName string) (err error) {
 	pluginConfigRoot := getPluginConfigPath(pluginName)
 	if err = os.MkdirAll(pluginConfigRoot, 0755); err != nil {
 		return
 	}
-	return setPermissions(pluginConfigRoot, 0755)
+	return SetPermissions(pluginConfigRoot, 0755)
+}
+
+// clonePropertyDirectory copies the properties within a directory of the plugin properties for an app,
+// including nested directories such as those used by list properties
+func clonePropertyDirectory(pluginName string, oldAppName string, newAppName string, directory string) error {
+	files, err := ioutil.ReadDir(getPropertyPath(pluginName, oldAppName, directory))
+	if err != nil {
+		return fmt.Errorf("Unable to read %s config for %s: %s", pluginName, oldAppName, err.Error())
+	}
+
+	for _, file := range files {
+		property := path.Join(directory, file.Name())
+		propertyPath := getPropertyPath(pluginName, newAppName, property)
+		if file.IsDir() {
+			if err := os.MkdirAll(propertyPath, 0755); err != nil {
+				return fmt.Errorf("Unable to create %s config directory %s.%s: %s", pluginName, newAppName, property, err.Error())
+			}
+			SetPermissions(propertyPath, 0755)
+			if err := clonePropertyDirectory(pluginName, oldAppName, newAppName, property); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := ioutil.ReadFile(getPropertyPath(pluginName, oldAppName, property))
+		if err != nil {
+			return fmt.Errorf("Unable to read %s config value %s.%s: %s", pluginName, oldAppName, property, err.Error())
+		}
+
+		if err := ioutil.WriteFile(propertyPath, b, 0600); err != nil {
+			return fmt.Errorf("Unable to write %s config value %s.%s: %s", pluginName, newAppName, property, err.Error())
+		}
+		os.Chmod(propertyPath, 0600)
+		SetPermissions(propertyPath, 0600)
+	}
+
+	return nil
 }
 
 func getPropertyPath(pluginName string, appName string, property string) string {
@@ -379,11 +463,11 @@ func makePluginAppPropertyPath(pluginName string, appName string) (err error) {
 	if err = os.MkdirAll(pluginAppConfigRoot, 0755); err != nil {
 		return
 	}
-	return setPermissions(pluginAppConfigRoot, 0755)
+	return SetPermissions(pluginAppConfigRoot, 0755)
 }
 
-// setPermissions sets the proper owner and filemode for a given file
-func setPermissions(path string, fileMode os.FileMode) (err error) {
+// SetPermissions sets the proper owner and filemode for a given file
+func SetPermissions(path string, fileMode os.FileMode) (err error) {
 	if err = os.Chmod(path, fileMode); err != nil {
 		return err
 	}
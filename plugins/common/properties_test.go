@@ -0,0 +1,78 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommonPropertyWrite(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(PropertyWrite("domains", "--global", "index", "100% api.example.com %s")).To(Succeed())
+	Expect(PropertyGet("domains", "--global", "index")).To(Equal("100% api.example.com %s"))
+}
+
+func TestCommonPropertyGetDefault(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(PropertyGetDefault("certs", "api", "acme-server", "https://acme.example.com/directory")).To(Equal("https://acme.example.com/directory"))
+	Expect(PropertyWrite("certs", "api", "acme-server", "https://pebble:14000/dir")).To(Succeed())
+	Expect(PropertyGetDefault("certs", "api", "acme-server", "https://acme.example.com/directory")).To(Equal("https://pebble:14000/dir"))
+}
+
+func TestCommonPropertyClone(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(PropertyWrite("network", "old-app", "bind-all-interfaces", "true")).To(Succeed())
+	Expect(PropertyListAdd("buildpacks", "old-app", "buildpacks", "https://github.com/heroku/heroku-buildpack-nodejs", 0)).To(Succeed())
+	Expect(PropertyListAdd("buildpacks", "old-app", "buildpacks", "https://github.com/heroku/heroku-buildpack-ruby", 0)).To(Succeed())
+	Expect(PropertyWrite("network", "new-app", "bind-all-interfaces", "false")).To(Succeed())
+	nestedPath := getPropertyPath("cron", "old-app", "runs/task-1")
+	Expect(os.MkdirAll(nestedPath, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(nestedPath+"/run-1", []byte("output"), 0600)).To(Succeed())
+
+	Expect(PropertyClone("network", "old-app", "new-app")).To(Succeed())
+	Expect(PropertyClone("buildpacks", "old-app", "new-app")).To(Succeed())
+	Expect(PropertyClone("resource", "old-app", "new-app")).To(Succeed())
+	Expect(PropertyClone("cron", "old-app", "new-app")).To(Succeed())
+
+	Expect(PropertyGet("network", "new-app", "bind-all-interfaces")).To(Equal("true"))
+	Expect(PropertyGet("network", "old-app", "bind-all-interfaces")).To(Equal("true"))
+	buildpacks, err := PropertyListGet("buildpacks", "new-app", "buildpacks")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(buildpacks).To(Equal([]string{"https://github.com/heroku/heroku-buildpack-nodejs", "https://github.com/heroku/heroku-buildpack-ruby"}))
+	Expect(PropertyExists("resource", "new-app", "limit.web.memory")).To(BeFalse())
+	Expect(ioutil.ReadFile(getPropertyPath("cron", "new-app", "runs/task-1/run-1"))).To(Equal([]byte("output")))
+}
+
+func TestCommonPropertyRename(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(PropertyWrite("resource", "old-app", "limit.web.memory", "512m")).To(Succeed())
+	Expect(PropertyWrite("network", "old-app", "bind-all-interfaces", "true")).To(Succeed())
+	Expect(PropertyWrite("network", "other-app", "bind-all-interfaces", "false")).To(Succeed())
+
+	plugins, err := PropertyPlugins("old-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(plugins).To(Equal([]string{"network", "resource"}))
+
+	for _, plugin := range plugins {
+		Expect(PropertyRename(plugin, "old-app", "new-app")).To(Succeed())
+	}
+
+	Expect(PropertyGet("resource", "new-app", "limit.web.memory")).To(Equal("512m"))
+	Expect(PropertyGet("network", "new-app", "bind-all-interfaces")).To(Equal("true"))
+	Expect(PropertyGet("network", "other-app", "bind-all-interfaces")).To(Equal("false"))
+
+	plugins, err = PropertyPlugins("old-app")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(plugins).To(BeEmpty())
+}
@@ -0,0 +1,116 @@
+// Package testutil contains fixtures shared by the tests of go plugins
+//
+// It is kept out of the common package so that plugin binaries do not link the testing package
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+// Roots holds the temporary directories created for a test
+type Roots struct {
+	DokkuRoot string
+	LibRoot   string
+	BinDir    string
+
+	// environment holds the values of the environment variables set by SetupRoots before it was called
+	environment map[string]*string
+}
+
+// SetupRoots points DOKKU_ROOT, DOKKU_HOST_ROOT and DOKKU_LIB_ROOT at temporary directories,
+// runs as the current user and puts an empty directory for command stubs first on the PATH.
+// Teardown must be deferred to restore the environment
+func SetupRoots(t *testing.T) Roots {
+	t.Helper()
+	roots := Roots{
+		DokkuRoot: tempDir(t, "dokku-root"),
+		LibRoot:   tempDir(t, "dokku-lib-root"),
+		BinDir:    tempDir(t, "dokku-bin"),
+	}
+
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentGroup, err := user.LookupGroupId(currentUser.Gid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots.setenv(map[string]string{
+		"PATH":               roots.BinDir + ":" + os.Getenv("PATH"),
+		"DOKKU_ROOT":         roots.DokkuRoot,
+		"DOKKU_HOST_ROOT":    roots.DokkuRoot,
+		"DOKKU_LIB_ROOT":     roots.LibRoot,
+		"DOKKU_SYSTEM_USER":  currentUser.Username,
+		"DOKKU_SYSTEM_GROUP": currentGroup.Name,
+	})
+	return roots
+}
+
+// Teardown restores the environment and removes the directories created by SetupRoots
+func (r Roots) Teardown() {
+	for key, value := range r.environment {
+		if value == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *value)
+		}
+	}
+	os.RemoveAll(r.DokkuRoot)
+	os.RemoveAll(r.LibRoot)
+	os.RemoveAll(r.BinDir)
+}
+
+// CreateApps creates the app directory of each app
+func (r Roots) CreateApps(t *testing.T, appNames ...string) {
+	t.Helper()
+	for _, appName := range appNames {
+		if err := os.MkdirAll(filepath.Join(r.DokkuRoot, appName), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// WriteStub writes an executable script to the stub directory, replacing the command of the same name
+func (r Roots) WriteStub(t *testing.T, name string, script string) string {
+	t.Helper()
+	stubPath := filepath.Join(r.BinDir, name)
+	if err := ioutil.WriteFile(stubPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return stubPath
+}
+
+// StubPlugn replaces plugn with a script recording the arguments of every call, and returns the path of the log
+func (r Roots) StubPlugn(t *testing.T) string {
+	t.Helper()
+	logPath := filepath.Join(r.BinDir, "plugn.log")
+	r.WriteStub(t, "plugn", "#!/bin/sh\necho \"$*\" >> \""+logPath+"\"\n")
+	return logPath
+}
+
+func (r *Roots) setenv(environment map[string]string) {
+	r.environment = map[string]*string{}
+	for key, value := range environment {
+		if previous, ok := os.LookupEnv(key); ok {
+			r.environment[key] = &previous
+		} else {
+			r.environment[key] = nil
+		}
+		os.Setenv(key, value)
+	}
+}
+
+func tempDir(t *testing.T, prefix string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -0,0 +1,160 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TriggerTraceMaxFiles is the number of trigger traces kept
+	TriggerTraceMaxFiles = 20
+)
+
+// TriggerTrace is a single trigger call recorded while trigger tracing is enabled
+type TriggerTrace struct {
+	Trigger    string    `json:"trigger"`
+	Args       []string  `json:"args"`
+	Command    string    `json:"command"`
+	StartedAt  time.Time `json:"started-at"`
+	DurationMs int64     `json:"duration-ms"`
+	ExitCode   int       `json:"exit-code"`
+	Depth      int       `json:"depth"`
+}
+
+// NewTriggerTrace returns a trace of a trigger call that started at a given time and has just finished
+func NewTriggerTrace(triggerName string, args []string, startedAt time.Time, exitCode int, depth int) TriggerTrace {
+	return TriggerTrace{
+		Trigger:    triggerName,
+		Args:       args,
+		Command:    os.Getenv("DOKKU_TRACE_TRIGGERS_COMMAND"),
+		StartedAt:  startedAt,
+		DurationMs: time.Since(startedAt).Nanoseconds() / int64(time.Millisecond),
+		ExitCode:   exitCode,
+		Depth:      depth,
+	}
+}
+
+// GetTriggerTracesPath returns the directory trigger traces are written to
+func GetTriggerTracesPath() string {
+	return filepath.Join(MustGetEnv("DOKKU_LIB_ROOT"), "data", "plugin", "traces")
+}
+
+// TriggerTracingEnabled returns true if the current dokku command is recording trigger calls
+func TriggerTracingEnabled() bool {
+	return os.Getenv("DOKKU_TRACE_TRIGGERS_FILE") != ""
+}
+
+// RecordTriggerTrace appends a trigger call to the trace of the current dokku command
+func RecordTriggerTrace(trace TriggerTrace) error {
+	line, err := json.Marshal(trace)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	path := os.Getenv("DOKKU_TRACE_TRIGGERS_FILE")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return fmt.Errorf("Unable to create trigger trace directory: %s", err.Error())
+		}
+		SetPermissions(filepath.Dir(path), 0750)
+		pruneTriggerTraces(filepath.Dir(path), TriggerTraceMaxFiles-1)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("Unable to open trigger trace: %s", err.Error())
+	}
+	defer file.Close()
+	SetPermissions(path, 0640)
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("Unable to write trigger trace: %s", err.Error())
+	}
+	return nil
+}
+
+// ListTriggerTraces returns the ids of the recorded trigger traces, oldest first
+func ListTriggerTraces() ([]string, error) {
+	return listTriggerTraces(GetTriggerTracesPath())
+}
+
+// ReadTriggerTrace returns the trigger calls of a recorded trace, ordered by start time
+func ReadTriggerTrace(id string) ([]TriggerTrace, error) {
+	if id == "" || strings.ContainsAny(id, "/.") {
+		return []TriggerTrace{}, fmt.Errorf("Invalid trace id %s", id)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(GetTriggerTracesPath(), id+".jsonl"))
+	if os.IsNotExist(err) {
+		return []TriggerTrace{}, fmt.Errorf("Trace %s does not exist", id)
+	}
+	if err != nil {
+		return []TriggerTrace{}, err
+	}
+
+	traces := []TriggerTrace{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if line == "" {
+			continue
+		}
+		var trace TriggerTrace
+		if err := json.Unmarshal([]byte(line), &trace); err != nil {
+			continue
+		}
+		traces = append(traces, trace)
+	}
+
+	// calls are written as they finish, so nested calls appear before their parents
+	sort.SliceStable(traces, func(i, j int) bool {
+		if traces[i].StartedAt.Equal(traces[j].StartedAt) {
+			return traces[i].Depth < traces[j].Depth
+		}
+		return traces[i].StartedAt.Before(traces[j].StartedAt)
+	})
+	return traces, nil
+}
+
+func listTriggerTraces(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return []string{}, err
+	}
+
+	ids := []string{}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".jsonl") {
+			ids = append(ids, strings.TrimSuffix(file.Name(), ".jsonl"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// pruneTriggerTraces removes the oldest traces so that at most keep remain
+func pruneTriggerTraces(dir string, keep int) {
+	ids, err := listTriggerTraces(dir)
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(ids)-keep; i++ {
+		os.Remove(filepath.Join(dir, ids[i]+".jsonl"))
+	}
+}
+
+func triggerTraceDepth() int {
+	depth, err := strconv.Atoi(os.Getenv("DOKKU_TRACE_TRIGGERS_DEPTH"))
+	if err != nil {
+		return 0
+	}
+	return depth
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommonTriggerTrace(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+	defer os.Unsetenv("DOKKU_TRACE_TRIGGERS_FILE")
+	defer os.Unsetenv("DOKKU_TRACE_TRIGGERS_COMMAND")
+
+	Expect(TriggerTracingEnabled()).To(BeFalse())
+	Expect(ListTriggerTraces()).To(BeEmpty())
+
+	for i := 0; i < TriggerTraceMaxFiles+2; i++ {
+		os.Setenv("DOKKU_TRACE_TRIGGERS_FILE", filepath.Join(GetTriggerTracesPath(), time.Unix(int64(i), 0).UTC().Format("20060102-150405")+"-1.jsonl"))
+		Expect(RecordTriggerTrace(TriggerTrace{Trigger: "post-deploy"})).To(Succeed())
+	}
+	traceIDs, err := ListTriggerTraces()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(traceIDs).To(HaveLen(TriggerTraceMaxFiles))
+	Expect(traceIDs[0]).To(Equal("19700101-000002-1"))
+
+	os.Setenv("DOKKU_TRACE_TRIGGERS_FILE", filepath.Join(GetTriggerTracesPath(), "20261015-101112-42.jsonl"))
+	os.Setenv("DOKKU_TRACE_TRIGGERS_COMMAND", "ps:restart node-js-app")
+	Expect(TriggerTracingEnabled()).To(BeTrue())
+	startedAt := time.Now().Add(-time.Second)
+	Expect(RecordTriggerTrace(NewTriggerTrace("app-restart", []string{"node-js-app"}, startedAt.Add(10*time.Millisecond), 1, 1))).To(Succeed())
+	Expect(RecordTriggerTrace(NewTriggerTrace("post-deploy", []string{"node-js-app"}, startedAt, 0, 0))).To(Succeed())
+
+	traces, err := ReadTriggerTrace("20261015-101112-42")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(traces).To(HaveLen(2))
+	Expect(traces[0].Trigger).To(Equal("post-deploy"))
+	Expect(traces[0].Command).To(Equal("ps:restart node-js-app"))
+	Expect(traces[0].DurationMs).To(BeNumerically(">=", 1000))
+	Expect(traces[1].Trigger).To(Equal("app-restart"))
+	Expect(traces[1].ExitCode).To(Equal(1))
+	Expect(traces[1].Depth).To(Equal(1))
+
+	_, err = ReadTriggerTrace("../../etc/passwd")
+	Expect(err).To(HaveOccurred())
+	_, err = ReadTriggerTrace("20261015-000000-1")
+	Expect(err).To(HaveOccurred())
+}
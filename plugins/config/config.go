@@ -14,7 +14,7 @@ func Get(appName string, key string) (value string, ok bool) {
 	if err != nil {
 		return "", false
 	}
-	if err = validateKey(key); err != nil {
+	if err = ValidateKey(key); err != nil {
 		return "", false
 	}
 	return env.Get(key)
@@ -38,7 +38,7 @@ func SetMany(appName string, entries map[string]string, restart bool) (err error
 	}
 	keys := make([]string, 0, len(entries))
 	for k := range entries {
-		if err = validateKey(k); err != nil {
+		if err = ValidateKey(k); err != nil {
 			return
 		}
 	}
@@ -69,7 +69,7 @@ func UnsetMany(appName string, keys []string, restart bool) (err error) {
 	}
 	var changed = false
 	for _, k := range keys {
-		if err = validateKey(k); err != nil {
+		if err = ValidateKey(k); err != nil {
 			return
 		}
 	}
@@ -113,7 +113,8 @@ func loadAppOrGlobalEnv(appName string) (env *Env, err error) {
 	return LoadAppEnv(appName)
 }
 
-func validateKey(key string) error {
+// ValidateKey returns an error if the key is not a valid environment variable name
+func ValidateKey(key string) error {
 	r, _ := regexp.Compile("^[a-zA-Z_][a-zA-Z0-9_]*$")
 	if !r.MatchString(key) {
 		return fmt.Errorf("Invalid key name: '%s'", key)
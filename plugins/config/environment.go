@@ -299,7 +299,7 @@ func loadFromFile(name string, filename string) (env *Env, err error) {
 
 	dirty := false
 	for k := range envMap {
-		if err := validateKey(k); err != nil {
+		if err := ValidateKey(k); err != nil {
 			common.LogInfo1(fmt.Sprintf("Deleting invalid key %s from config for %s", k, name))
 			delete(envMap, k)
 			dirty = true
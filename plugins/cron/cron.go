@@ -0,0 +1,434 @@
+package cron
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	appjson "github.com/dokku/dokku/plugins/app-json"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// MaxRunLogs is the number of run logs kept for each task
+	MaxRunLogs = 10
+
+	crontabBlockStart = "# BEGIN dokku cron tasks, changes within this block are overwritten"
+	crontabBlockEnd   = "# END dokku cron tasks"
+	runIDFormat       = "20060102-150405"
+)
+
+// Task is a scheduled command registered from the cron section of an app's app.json
+type Task struct {
+	ID       string `json:"id"`
+	Schedule string `json:"schedule"`
+	Command  string `json:"command"`
+}
+
+// Run is a single execution of a task
+type Run struct {
+	ID        string    `json:"id"`
+	StartedAt time.Time `json:"started-at"`
+	ExitCode  *int      `json:"exit-code"`
+}
+
+// TasksFromAppJSON returns the tasks for the cron section of an app.json, skipping duplicates
+func TasksFromAppJSON(cronTasks []appjson.CronTask) []Task {
+	seen := map[string]bool{}
+	tasks := []Task{}
+	for _, cronTask := range cronTasks {
+		task := Task{
+			ID:       taskID(cronTask.Schedule, cronTask.Command),
+			Schedule: strings.TrimSpace(cronTask.Schedule),
+			Command:  cronTask.Command,
+		}
+		if seen[task.ID] {
+			continue
+		}
+		seen[task.ID] = true
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// GetTasks returns the tasks registered for an app
+func GetTasks(appName string) ([]Task, error) {
+	lines, err := common.PropertyListGet("cron", appName, "tasks")
+	if err != nil {
+		return []Task{}, err
+	}
+
+	tasks := []Task{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		var task Task
+		if err := json.Unmarshal([]byte(line), &task); err != nil {
+			return []Task{}, fmt.Errorf("Unable to read cron tasks for %s: %s", appName, err.Error())
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// GetTask returns a single task registered for an app
+func GetTask(appName string, taskID string) (Task, error) {
+	tasks, err := GetTasks(appName)
+	if err != nil {
+		return Task{}, err
+	}
+	for _, task := range tasks {
+		if task.ID == taskID {
+			return task, nil
+		}
+	}
+	return Task{}, fmt.Errorf("No cron task %s found for %s", taskID, appName)
+}
+
+// SetTasks replaces the tasks registered for an app
+func SetTasks(appName string, tasks []Task) error {
+	if len(tasks) == 0 {
+		if !common.PropertyExists("cron", appName, "tasks") {
+			return nil
+		}
+		return common.PropertyDelete("cron", appName, "tasks")
+	}
+
+	lines := []string{}
+	for _, task := range tasks {
+		b, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(b))
+	}
+	return common.PropertyWrite("cron", appName, "tasks", strings.Join(lines, "\n")+"\n")
+}
+
+// RegisterTasks registers the cron tasks of the image being deployed and updates the crontab
+func RegisterTasks(appName string, imageTag string) error {
+	image := common.GetDeployingAppImageName(appName, imageTag, "")
+	appJSON, err := appjson.GetAppJSON(image)
+	if err != nil {
+		return err
+	}
+
+	previousTasks, err := GetTasks(appName)
+	if err != nil {
+		return err
+	}
+	tasks := TasksFromAppJSON(appJSON.Cron)
+	if len(tasks) == 0 && len(previousTasks) == 0 {
+		return nil
+	}
+
+	if len(tasks) == 0 {
+		common.LogInfo1("Removing cron tasks")
+	} else {
+		common.LogInfo1(fmt.Sprintf("Registering %d cron task(s)", len(tasks)))
+	}
+	if err := SetTasks(appName, tasks); err != nil {
+		return err
+	}
+	if err := WriteCrontab(); err != nil {
+		return err
+	}
+	return pruneTaskData(appName, tasks)
+}
+
+// DestroyTasks removes the tasks and run logs of an app and updates the crontab
+func DestroyTasks(appName string) error {
+	if err := common.PropertyDestroy("cron", appName); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(getAppDataPath(appName)); err != nil {
+		return err
+	}
+	return WriteCrontab()
+}
+
+// RenameTasks moves the run logs of an app to a new app name
+func RenameTasks(oldAppName string, newAppName string) error {
+	oldPath := getAppDataPath(oldAppName)
+	if !common.DirectoryExists(oldPath) {
+		return nil
+	}
+	return os.Rename(oldPath, getAppDataPath(newAppName))
+}
+
+// GenerateCrontab returns the crontab block scheduling the tasks of every app
+func GenerateCrontab(appTasks map[string][]Task, dokkuBin string) string {
+	if len(appTasks) == 0 {
+		return ""
+	}
+
+	appNames := []string{}
+	for appName := range appTasks {
+		appNames = append(appNames, appName)
+	}
+	sort.Strings(appNames)
+
+	lines := []string{crontabBlockStart}
+	for _, appName := range appNames {
+		for _, task := range appTasks[appName] {
+			lines = append(lines, fmt.Sprintf("# %s: %s", appName, strings.Join(strings.Fields(task.Command), " ")))
+			lines = append(lines, fmt.Sprintf("%s DOKKU_CRON_RUN=true %s cron:run %s %s >/dev/null 2>&1", task.Schedule, dokkuBin, appName, task.ID))
+		}
+	}
+	lines = append(lines, crontabBlockEnd)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// WriteCrontab writes the tasks of every deployed app to the crontab of the dokku user,
+// leaving entries outside of the dokku block untouched
+func WriteCrontab() error {
+	appNames, _ := common.DokkuApps()
+	appTasks := map[string][]Task{}
+	for _, appName := range appNames {
+		if !common.IsDeployed(appName) {
+			continue
+		}
+		tasks, err := GetTasks(appName)
+		if err != nil {
+			return err
+		}
+		if len(tasks) > 0 {
+			appTasks[appName] = tasks
+		}
+	}
+
+	dokkuBin, err := exec.LookPath("dokku")
+	if err != nil {
+		dokkuBin = "/usr/bin/dokku"
+	}
+
+	existing, err := readCrontab()
+	if err != nil {
+		return err
+	}
+	updated := replaceCrontabBlock(existing, GenerateCrontab(appTasks, dokkuBin))
+	if updated == existing {
+		return nil
+	}
+
+	cmd := exec.Command("crontab", append(crontabUserArgs(), "-")...)
+	cmd.Stdin = strings.NewReader(updated)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Unable to write crontab: %s", strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// RunTask runs a task in a one-off container, writing its output to a new run log.
+// A task is not started while a previous run of the same task is still in progress
+func RunTask(appName string, taskID string) error {
+	task, err := GetTask(appName, taskID)
+	if err != nil {
+		return err
+	}
+
+	taskPath := getTaskDataPath(appName, task.ID)
+	if err := os.MkdirAll(taskPath, 0755); err != nil {
+		return fmt.Errorf("Unable to create cron log directory: %s", err.Error())
+	}
+	common.SetPermissions(taskPath, 0755)
+
+	lock, err := os.OpenFile(filepath.Join(taskPath, "lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to open cron lock: %s", err.Error())
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return fmt.Errorf("Cron task %s is still running, skipping", task.ID)
+	}
+
+	pruneRuns(taskPath, MaxRunLogs-1)
+	runID := time.Now().UTC().Format(runIDFormat)
+	logPath := filepath.Join(taskPath, runID+".log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("Unable to create cron log: %s", err.Error())
+	}
+	defer logFile.Close()
+	common.SetPermissions(logPath, 0640)
+
+	cmd := exec.Command("dokku", "--rm", "run", appName, "/bin/sh", "-c", task.Command)
+	cmd.Stdout = io.MultiWriter(os.Stdout, logFile)
+	cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
+	err = cmd.Run()
+
+	exitCode := common.ExitCode(err)
+	exitPath := filepath.Join(taskPath, runID+".exit")
+	if writeErr := ioutil.WriteFile(exitPath, []byte(strconv.Itoa(exitCode)+"\n"), 0640); writeErr != nil {
+		common.LogWarn(fmt.Sprintf("Unable to record exit code of cron task %s: %s", task.ID, writeErr.Error()))
+	}
+	common.SetPermissions(exitPath, 0640)
+
+	if err != nil {
+		return fmt.Errorf("Cron task %s exited with code %d", task.ID, exitCode)
+	}
+	return nil
+}
+
+// ListRuns returns the recorded runs of a task, oldest first
+func ListRuns(appName string, taskID string) ([]Run, error) {
+	return listRuns(getTaskDataPath(appName, taskID))
+}
+
+// ReadRunLog returns the output of a task run
+func ReadRunLog(appName string, taskID string, runID string) (string, error) {
+	if runID == "" || strings.ContainsAny(runID, "/.") {
+		return "", fmt.Errorf("Invalid run id %s", runID)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(getTaskDataPath(appName, taskID), runID+".log"))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("Run %s of cron task %s does not exist", runID, taskID)
+	}
+	return string(b), err
+}
+
+func crontabUserArgs() []string {
+	if os.Geteuid() != 0 {
+		return []string{}
+	}
+	systemUser := os.Getenv("DOKKU_SYSTEM_USER")
+	if systemUser == "" {
+		systemUser = "dokku"
+	}
+	return []string{"-u", systemUser}
+}
+
+func getAppDataPath(appName string) string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "cron", appName)
+}
+
+func getTaskDataPath(appName string, taskID string) string {
+	return filepath.Join(getAppDataPath(appName), taskID)
+}
+
+func listRuns(taskPath string) ([]Run, error) {
+	files, err := ioutil.ReadDir(taskPath)
+	if os.IsNotExist(err) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return []Run{}, err
+	}
+
+	runs := []Run{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".log") {
+			continue
+		}
+		runID := strings.TrimSuffix(file.Name(), ".log")
+		startedAt, err := time.Parse(runIDFormat, runID)
+		if err != nil {
+			continue
+		}
+
+		run := Run{ID: runID, StartedAt: startedAt}
+		if exitCode, err := strconv.Atoi(common.ReadFirstLine(filepath.Join(taskPath, runID+".exit"))); err == nil {
+			run.ExitCode = &exitCode
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, nil
+}
+
+// pruneRuns removes the oldest run logs of a task so that at most keep remain
+func pruneRuns(taskPath string, keep int) {
+	runs, err := listRuns(taskPath)
+	if err != nil || len(runs) <= keep {
+		return
+	}
+	for _, run := range runs[:len(runs)-keep] {
+		os.Remove(filepath.Join(taskPath, run.ID+".log"))
+		os.Remove(filepath.Join(taskPath, run.ID+".exit"))
+	}
+}
+
+// pruneTaskData removes the run logs of tasks that are no longer registered
+func pruneTaskData(appName string, tasks []Task) error {
+	files, err := ioutil.ReadDir(getAppDataPath(appName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	registered := map[string]bool{}
+	for _, task := range tasks {
+		registered[task.ID] = true
+	}
+	for _, file := range files {
+		if file.IsDir() && !registered[file.Name()] {
+			if err := os.RemoveAll(filepath.Join(getAppDataPath(appName), file.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readCrontab() (string, error) {
+	cmd := exec.Command("crontab", append(crontabUserArgs(), "-l")...)
+	b, err := cmd.Output()
+	if err == nil {
+		return string(b), nil
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		// crontab exits non-zero when the user does not have a crontab yet
+		return "", nil
+	}
+	return "", fmt.Errorf("Unable to read crontab: %s", err.Error())
+}
+
+// replaceCrontabBlock replaces the dokku block of a crontab, appending it if it is not present
+func replaceCrontabBlock(crontab string, block string) string {
+	lines := []string{}
+	inBlock := false
+	for _, line := range strings.Split(strings.TrimRight(crontab, "\n"), "\n") {
+		switch {
+		case line == crontabBlockStart:
+			inBlock = true
+		case line == crontabBlockEnd:
+			inBlock = false
+		case !inBlock:
+			lines = append(lines, line)
+		}
+	}
+
+	content := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if block == "" {
+		return content
+	}
+	if content != "" {
+		content += "\n"
+	}
+	return content + block
+}
+
+// taskID returns a stable id for a task, so run logs are kept across deploys
+func taskID(schedule string, command string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(schedule) + "\n" + command))
+	return fmt.Sprintf("%x", sum)[:10]
+}
@@ -0,0 +1,176 @@
+package cron
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	appjson "github.com/dokku/dokku/plugins/app-json"
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+var crontabPath string
+
+// setupTestRoots creates dokku directories along with dokku and crontab stubs. The dokku
+// stub prints its arguments and exits with $CRON_TEST_EXIT, and the crontab stub keeps
+// the crontab in a file
+func setupTestRoots(t *testing.T) func() {
+	roots := testutil.SetupRoots(t)
+	roots.WriteStub(t, "dokku", "#!/bin/sh\necho \"$*\"\nexit ${CRON_TEST_EXIT:-0}\n")
+
+	crontabPath = filepath.Join(roots.BinDir, "crontab.txt")
+	roots.WriteStub(t, "crontab", `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    -l) [ -f "`+crontabPath+`" ] || { echo "no crontab for user" >&2; exit 1; }; cat "`+crontabPath+`" ;;
+    -) cat > "`+crontabPath+`" ;;
+  esac
+done
+`)
+
+	return func() {
+		os.Unsetenv("CRON_TEST_EXIT")
+		roots.Teardown()
+	}
+}
+
+func createDeployedApp(appName string) {
+	appRoot := filepath.Join(os.Getenv("DOKKU_ROOT"), appName)
+	Expect(os.MkdirAll(appRoot, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(appRoot, "CONTAINER.web.1"), []byte("abc123\n"), 0644)).To(Succeed())
+}
+
+func readCrontabFile() string {
+	b, err := ioutil.ReadFile(crontabPath)
+	Expect(err).NotTo(HaveOccurred())
+	return string(b)
+}
+
+func TestCronTasksFromAppJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	tasks := TasksFromAppJSON([]appjson.CronTask{
+		{Command: "python task.py", Schedule: "*/5 * * * *"},
+		{Command: "python task.py", Schedule: " */5 * * * * "},
+		{Command: "python report.py", Schedule: "@daily"},
+	})
+	Expect(tasks).To(HaveLen(2))
+	Expect(tasks[0].ID).To(HaveLen(10))
+	Expect(tasks[0].Schedule).To(Equal("*/5 * * * *"))
+	Expect(tasks[1].ID).NotTo(Equal(tasks[0].ID))
+
+	again := TasksFromAppJSON([]appjson.CronTask{{Command: "python report.py", Schedule: "@daily"}})
+	Expect(again[0].ID).To(Equal(tasks[1].ID))
+}
+
+func TestCronSetTasks(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	tasks := TasksFromAppJSON([]appjson.CronTask{{Command: "python task.py --verbose", Schedule: "@hourly"}})
+	Expect(SetTasks("api", tasks)).To(Succeed())
+	Expect(GetTasks("api")).To(Equal(tasks))
+
+	task, err := GetTask("api", tasks[0].ID)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(task.Command).To(Equal("python task.py --verbose"))
+	_, err = GetTask("api", "missing")
+	Expect(err).To(HaveOccurred())
+
+	Expect(SetTasks("api", []Task{})).To(Succeed())
+	Expect(GetTasks("api")).To(BeEmpty())
+	Expect(SetTasks("api", []Task{})).To(Succeed())
+}
+
+func TestCronReplaceCrontabBlock(t *testing.T) {
+	RegisterTestingT(t)
+
+	block := GenerateCrontab(map[string][]Task{
+		"web": {{ID: "b", Schedule: "@daily", Command: "echo\nweb"}},
+		"api": {{ID: "a", Schedule: "*/5 * * * *", Command: "echo api"}},
+	}, "/usr/bin/dokku")
+	Expect(block).To(Equal(strings.Join([]string{
+		crontabBlockStart,
+		"# api: echo api",
+		"*/5 * * * * DOKKU_CRON_RUN=true /usr/bin/dokku cron:run api a >/dev/null 2>&1",
+		"# web: echo web",
+		"@daily DOKKU_CRON_RUN=true /usr/bin/dokku cron:run web b >/dev/null 2>&1",
+		crontabBlockEnd,
+	}, "\n") + "\n"))
+	Expect(GenerateCrontab(map[string][]Task{}, "/usr/bin/dokku")).To(Equal(""))
+
+	crontab := replaceCrontabBlock("MAILTO=ops@example.com\n0 * * * * backup\n", block)
+	Expect(crontab).To(Equal("MAILTO=ops@example.com\n0 * * * * backup\n\n" + block))
+	Expect(replaceCrontabBlock(crontab, block)).To(Equal(crontab))
+	Expect(replaceCrontabBlock(crontab, "")).To(Equal("MAILTO=ops@example.com\n0 * * * * backup\n"))
+	Expect(replaceCrontabBlock("", block)).To(Equal(block))
+	Expect(replaceCrontabBlock(block, "")).To(Equal(""))
+}
+
+func TestCronWriteCrontab(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	Expect(ioutil.WriteFile(crontabPath, []byte("0 * * * * backup\n"), 0644)).To(Succeed())
+	createDeployedApp("api")
+	Expect(os.MkdirAll(filepath.Join(os.Getenv("DOKKU_ROOT"), "undeployed"), 0755)).To(Succeed())
+
+	tasks := TasksFromAppJSON([]appjson.CronTask{{Command: "echo api", Schedule: "@hourly"}})
+	Expect(SetTasks("api", tasks)).To(Succeed())
+	Expect(SetTasks("undeployed", tasks)).To(Succeed())
+	Expect(WriteCrontab()).To(Succeed())
+
+	crontab := readCrontabFile()
+	Expect(crontab).To(HavePrefix("0 * * * * backup\n"))
+	Expect(crontab).To(ContainSubstring("cron:run api " + tasks[0].ID))
+	Expect(crontab).NotTo(ContainSubstring("undeployed"))
+
+	Expect(DestroyTasks("api")).To(Succeed())
+	Expect(readCrontabFile()).To(Equal("0 * * * * backup\n"))
+}
+
+func TestCronRunTask(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t)()
+
+	tasks := TasksFromAppJSON([]appjson.CronTask{{Command: "echo api", Schedule: "@hourly"}})
+	Expect(SetTasks("api", tasks)).To(Succeed())
+	taskID := tasks[0].ID
+
+	Expect(RunTask("api", taskID)).To(Succeed())
+	runs, err := ListRuns("api", taskID)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(runs).To(HaveLen(1))
+	Expect(*runs[0].ExitCode).To(Equal(0))
+
+	output, err := ReadRunLog("api", taskID, runs[0].ID)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(output).To(Equal("--rm run api /bin/sh -c echo api\n"))
+	_, err = ReadRunLog("api", taskID, "../lock")
+	Expect(err).To(HaveOccurred())
+
+	lock, err := os.Open(filepath.Join(getTaskDataPath("api", taskID), "lock"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)).To(Succeed())
+	Expect(RunTask("api", taskID)).To(MatchError(ContainSubstring("still running")))
+	lock.Close()
+
+	for i := 0; i < MaxRunLogs+2; i++ {
+		logPath := filepath.Join(getTaskDataPath("api", taskID), fmt.Sprintf("20000101-0000%02d.log", i))
+		Expect(ioutil.WriteFile(logPath, []byte{}, 0644)).To(Succeed())
+	}
+	os.Setenv("CRON_TEST_EXIT", "3")
+	Expect(RunTask("api", taskID)).To(MatchError(ContainSubstring("exited with code 3")))
+	runs, err = ListRuns("api", taskID)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(len(runs)).To(BeNumerically("<=", MaxRunLogs))
+	Expect(*runs[len(runs)-1].ExitCode).To(Equal(3))
+
+	Expect(pruneTaskData("api", []Task{})).To(Succeed())
+	Expect(ListRuns("api", taskID)).To(BeEmpty())
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/cron"
+)
+
+// lists the cron tasks registered for an app
+func main() {
+	args := flag.NewFlagSet("cron:list", flag.ExitOnError)
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := cron.CommandList(appName, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
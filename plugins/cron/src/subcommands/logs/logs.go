@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/cron"
+)
+
+// shows the output of a cron task run
+func main() {
+	args := flag.NewFlagSet("cron:logs", flag.ExitOnError)
+	runID := args.String("run", "", "--run: the run to show the output of, defaults to the latest run")
+	list := args.Bool("list", false, "--list: list the recorded runs instead of showing output")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+	taskID := args.Arg(1)
+
+	if err := cron.CommandLogs(appName, taskID, *runID, *list); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/cron"
+)
+
+// runs a cron task in a one-off container
+func main() {
+	flag.Parse()
+	appName := flag.Arg(1)
+	taskID := flag.Arg(2)
+
+	if err := cron.CommandRun(appName, taskID); err != nil {
+		common.LogFail(err.Error())
+	}
+}
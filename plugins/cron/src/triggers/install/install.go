@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the cron plugin
+func main() {
+	if err := common.PropertySetup("cron"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the cron plugin: %s", err.Error()))
+	}
+}
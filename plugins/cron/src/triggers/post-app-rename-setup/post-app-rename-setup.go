@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/cron"
+)
+
+// moves the cron run logs of an app to its new name
+func main() {
+	flag.Parse()
+	oldAppName := flag.Arg(0)
+	newAppName := flag.Arg(1)
+
+	if err := cron.RenameTasks(oldAppName, newAppName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
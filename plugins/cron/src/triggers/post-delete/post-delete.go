@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/cron"
+)
+
+// removes the cron tasks of an app from the crontab
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := cron.DestroyTasks(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
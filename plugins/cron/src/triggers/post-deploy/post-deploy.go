@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/cron"
+)
+
+// registers the cron tasks of the deployed image
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	imageTag := flag.Arg(3)
+
+	if err := cron.RegisterTasks(appName, imageTag); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+type taskListing struct {
+	Task
+	LastRun *Run `json:"last-run"`
+}
+
+// CommandList implements cron:list
+func CommandList(appName string, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	tasks, err := GetTasks(appName)
+	if err != nil {
+		return err
+	}
+
+	listings := []taskListing{}
+	for _, task := range tasks {
+		runs, err := ListRuns(appName, task.ID)
+		if err != nil {
+			return err
+		}
+		listing := taskListing{Task: task}
+		if len(runs) > 0 {
+			listing.LastRun = &runs[len(runs)-1]
+		}
+		listings = append(listings, listing)
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(listings)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("%s cron tasks", appName))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCHEDULE\tLAST RUN\tEXIT\tCOMMAND")
+	for _, listing := range listings {
+		lastRun, exitCode := "-", "-"
+		if listing.LastRun != nil {
+			lastRun = listing.LastRun.ID
+			exitCode = displayExitCode(*listing.LastRun)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", listing.ID, listing.Schedule, lastRun, exitCode, listing.Command)
+	}
+	return w.Flush()
+}
+
+// CommandLogs implements cron:logs
+func CommandLogs(appName string, taskID string, runID string, list bool) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if taskID == "" {
+		return errors.New("Please specify a cron task id")
+	}
+	if _, err := GetTask(appName, taskID); err != nil {
+		return err
+	}
+
+	runs, err := ListRuns(appName, taskID)
+	if err != nil {
+		return err
+	}
+
+	if list {
+		common.LogInfo2Quiet(fmt.Sprintf("Runs of cron task %s", taskID))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN\tSTARTED\tEXIT")
+		for _, run := range runs {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", run.ID, run.StartedAt.Format("2006-01-02 15:04:05 MST"), displayExitCode(run))
+		}
+		return w.Flush()
+	}
+
+	if runID == "" {
+		if len(runs) == 0 {
+			return fmt.Errorf("Cron task %s has not run yet", taskID)
+		}
+		runID = runs[len(runs)-1].ID
+	}
+
+	output, err := ReadRunLog(appName, taskID, runID)
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+	return nil
+}
+
+// CommandRun implements cron:run
+func CommandRun(appName string, taskID string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if taskID == "" {
+		return errors.New("Please specify a cron task id")
+	}
+	return RunTask(appName, taskID)
+}
+
+func displayExitCode(run Run) string {
+	if run.ExitCode == nil {
+		return "-"
+	}
+	return strconv.Itoa(*run.ExitCode)
+}
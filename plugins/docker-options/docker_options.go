@@ -0,0 +1,97 @@
+package dockeroptions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+var (
+	// Phases are the phases docker options may be added to
+	Phases = []string{"build", "deploy", "run"}
+)
+
+// AddDockerOptionToPhase adds a docker option to a phase, if it is not already present
+func AddDockerOptionToPhase(appName string, phase string, option string) error {
+	options, err := GetDockerOptionsForPhase(appName, phase)
+	if err != nil {
+		return err
+	}
+	return writeDockerOptionsForPhase(appName, phase, append(options, option))
+}
+
+// GetDockerOptionsForPhase returns the docker options set for a phase
+func GetDockerOptionsForPhase(appName string, phase string) ([]string, error) {
+	if err := validatePhase(phase); err != nil {
+		return []string{}, err
+	}
+
+	options, err := common.FileToSlice(getPhaseFilePath(appName, phase))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if options == nil {
+		options = []string{}
+	}
+	return options, err
+}
+
+// RemoveDockerOptionFromPhase removes a docker option from a phase
+func RemoveDockerOptionFromPhase(appName string, phase string, option string) error {
+	options, err := GetDockerOptionsForPhase(appName, phase)
+	if err != nil {
+		return err
+	}
+
+	remaining := []string{}
+	for _, existing := range options {
+		if existing != option {
+			remaining = append(remaining, existing)
+		}
+	}
+	return writeDockerOptionsForPhase(appName, phase, remaining)
+}
+
+func getPhaseFilePath(appName string, phase string) string {
+	return filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "DOCKER_OPTIONS_"+strings.ToUpper(phase))
+}
+
+func validatePhase(phase string) error {
+	for _, validPhase := range Phases {
+		if phase == validPhase {
+			return nil
+		}
+	}
+	return fmt.Errorf("Phase(s) must be one of [%s]", strings.Join(Phases, " "))
+}
+
+// writeDockerOptionsForPhase writes the unique, sorted options for a phase in the same format as docker-options:add
+func writeDockerOptionsForPhase(appName string, phase string, options []string) error {
+	unique := map[string]bool{}
+	lines := []string{}
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || unique[option] {
+			continue
+		}
+		unique[option] = true
+		lines = append(lines, option)
+	}
+	sort.Strings(lines)
+
+	contents := ""
+	if len(lines) > 0 {
+		contents = strings.Join(lines, "\n") + "\n"
+	}
+
+	phaseFilePath := getPhaseFilePath(appName, phase)
+	if err := ioutil.WriteFile(phaseFilePath, []byte(contents), 0644); err != nil {
+		return fmt.Errorf("Unable to write docker options for %s: %s", phase, err.Error())
+	}
+	return common.SetPermissions(phaseFilePath, 0644)
+}
@@ -0,0 +1,36 @@
+package dockeroptions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestDockerOptionsPhases(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	roots.CreateApps(t, "my-app")
+
+	Expect(GetDockerOptionsForPhase("my-app", "deploy")).To(Equal([]string{}))
+	Expect(AddDockerOptionToPhase("my-app", "deploy", "-v /var/log/app:/app/log")).To(Succeed())
+	Expect(AddDockerOptionToPhase("my-app", "deploy", "--restart=on-failure:10")).To(Succeed())
+	Expect(AddDockerOptionToPhase("my-app", "deploy", "-v /var/log/app:/app/log")).To(Succeed())
+	Expect(GetDockerOptionsForPhase("my-app", "deploy")).To(Equal([]string{"--restart=on-failure:10", "-v /var/log/app:/app/log"}))
+	Expect(GetDockerOptionsForPhase("my-app", "run")).To(Equal([]string{}))
+
+	b, err := ioutil.ReadFile(filepath.Join(os.Getenv("DOKKU_ROOT"), "my-app", "DOCKER_OPTIONS_DEPLOY"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("--restart=on-failure:10\n-v /var/log/app:/app/log\n"))
+
+	Expect(RemoveDockerOptionFromPhase("my-app", "deploy", "--restart=on-failure:10")).To(Succeed())
+	Expect(GetDockerOptionsForPhase("my-app", "deploy")).To(Equal([]string{"-v /var/log/app:/app/log"}))
+
+	Expect(AddDockerOptionToPhase("my-app", "release", "--rm")).NotTo(Succeed())
+	_, err = GetDockerOptionsForPhase("my-app", "release")
+	Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,215 @@
+package domains
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"golang.org/x/net/idna"
+)
+
+const (
+	// maxDomainLength is the maximum length of a fully qualified domain name as per RFC 1035
+	maxDomainLength = 253
+
+	// punycodePrefix is the ACE prefix marking an IDNA-encoded label
+	punycodePrefix = "xn--"
+)
+
+var (
+	labelRegex = regexp.MustCompile(`(?i)^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+	// regexDomainRegex allow-lists the characters of a regular expression server name, as it is
+	// interpolated into the nginx config unquoted. A $ is only allowed as the trailing anchor so
+	// that nginx variables can not be referenced.
+	regexDomainRegex = regexp.MustCompile(`^~[a-zA-Z0-9._\-*+?^()|\[\]\\:<>]+\$?$`)
+)
+
+// Conflict represents a single domain claimed by more than one app
+type Conflict struct {
+	Domain string   `json:"domain"`
+	Apps   []string `json:"apps"`
+}
+
+// DetectConflicts returns every domain that is claimed by more than one app in the host-wide index
+func DetectConflicts() ([]Conflict, error) {
+	entries, err := IndexEntries()
+	if err != nil {
+		return []Conflict{}, err
+	}
+
+	appDomains := make(map[string][]string)
+	for _, entry := range entries {
+		appDomains[entry.App] = append(appDomains[entry.App], entry.Domain)
+	}
+
+	return FindConflicts(appDomains), nil
+}
+
+// FindConflicts returns every domain that appears in the domain list of more than one app
+func FindConflicts(appDomains map[string][]string) []Conflict {
+	claims := make(map[string]map[string]bool)
+	for appName, domains := range appDomains {
+		for _, domain := range domains {
+			normalized, err := Normalize(domain)
+			if err != nil {
+				normalized = domain
+			}
+			if claims[normalized] == nil {
+				claims[normalized] = make(map[string]bool)
+			}
+			claims[normalized][appName] = true
+		}
+	}
+
+	conflicts := []Conflict{}
+	for domain, apps := range claims {
+		if len(apps) < 2 {
+			continue
+		}
+
+		conflict := Conflict{Domain: domain}
+		for appName := range apps {
+			conflict.Apps = append(conflict.Apps, appName)
+		}
+		sort.Strings(conflict.Apps)
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Domain < conflicts[j].Domain
+	})
+	return conflicts
+}
+
+// GetAppDomains returns the list of domains stored in the VHOST file for an app
+func GetAppDomains(appName string) []string {
+	vhostPath := strings.Join([]string{common.MustGetEnv("DOKKU_ROOT"), appName, "VHOST"}, "/")
+	domains, err := common.FileToSlice(vhostPath)
+	if err != nil {
+		return []string{}
+	}
+	return domains
+}
+
+// IsRegex returns true if the domain is an nginx-style regular expression server name
+func IsRegex(domain string) bool {
+	return strings.HasPrefix(domain, "~")
+}
+
+// IsWildcard returns true if the domain is a wildcard domain such as *.example.com
+func IsWildcard(domain string) bool {
+	return strings.HasPrefix(domain, "*.")
+}
+
+// Matches returns true if the hostname is covered by the domain pattern
+// wildcards cover exactly one label, as with TLS certificates and nginx server names
+func Matches(pattern, hostname string) bool {
+	if IsRegex(pattern) {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "~"))
+		if err != nil {
+			return false
+		}
+		return re.MatchString(hostname)
+	}
+
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if pattern == hostname {
+		return true
+	}
+	if !IsWildcard(pattern) {
+		return false
+	}
+
+	suffix := strings.TrimPrefix(pattern, "*")
+	if !strings.HasSuffix(hostname, suffix) {
+		return false
+	}
+
+	label := strings.TrimSuffix(hostname, suffix)
+	return label != "" && !strings.Contains(label, ".")
+}
+
+// Normalize validates a domain and returns its canonical lowercase ascii form
+// internationalized domains are converted to punycode
+func Normalize(domain string) (string, error) {
+	domain = strings.TrimSpace(domain)
+	if IsRegex(domain) {
+		return domain, ValidateDomain(domain)
+	}
+
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	ascii, err := ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("%s is invalid: %s", domain, err.Error())
+	}
+	if err := ValidateDomain(ascii); err != nil {
+		return "", err
+	}
+	return ascii, nil
+}
+
+// ToASCII converts a possibly internationalized domain into its punycode representation
+func ToASCII(domain string) (string, error) {
+	if strings.HasPrefix(domain, "*.") {
+		ascii, err := idna.Lookup.ToASCII(strings.TrimPrefix(domain, "*."))
+		return "*." + ascii, err
+	}
+	return idna.Lookup.ToASCII(domain)
+}
+
+// ToUnicode converts a punycode-encoded domain into its unicode representation
+func ToUnicode(domain string) (string, error) {
+	if strings.HasPrefix(domain, "*.") {
+		decoded, err := idna.Lookup.ToUnicode(strings.TrimPrefix(domain, "*."))
+		return "*." + decoded, err
+	}
+	return idna.Lookup.ToUnicode(domain)
+}
+
+// ValidateDomain returns an error if the domain is not a valid RFC 1123 hostname,
+// wildcard domain or regular expression server name
+func ValidateDomain(domain string) error {
+	if domain == "" {
+		return errors.New("Domain must not be empty")
+	}
+
+	if IsRegex(domain) {
+		if !regexDomainRegex.MatchString(domain) {
+			return fmt.Errorf("%s is invalid: regular expressions may only contain alphanumeric characters, hyphens, underscores, the metacharacters .*+?^()|[]\\:<> and a trailing $ anchor", domain)
+		}
+		if _, err := regexp.Compile(strings.TrimPrefix(domain, "~")); err != nil {
+			return fmt.Errorf("%s is invalid: %s", domain, err.Error())
+		}
+		return nil
+	}
+
+	if len(domain) > maxDomainLength {
+		return fmt.Errorf("%s is invalid: domains must not be longer than %d characters", domain, maxDomainLength)
+	}
+
+	labels := strings.Split(domain, ".")
+	if labels[0] == "*" {
+		if len(labels) < 3 {
+			return fmt.Errorf("%s is invalid: wildcards must be followed by at least two labels", domain)
+		}
+		labels = labels[1:]
+	}
+
+	for _, label := range labels {
+		if !labelRegex.MatchString(label) {
+			return fmt.Errorf("%s is invalid: label '%s' must be 1-63 alphanumeric characters or hyphens and must not begin or end with a hyphen", domain, label)
+		}
+		if strings.HasPrefix(strings.ToLower(label), punycodePrefix) {
+			if _, err := idna.Lookup.ToUnicode(label); err != nil {
+				return fmt.Errorf("%s is invalid: label '%s' is not valid punycode", domain, label)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package domains
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDomainsNormalize(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(Normalize("Www.Example.COM")).To(Equal("www.example.com"))
+	Expect(Normalize("example.com.")).To(Equal("example.com"))
+	Expect(Normalize("a--domain.with--hyphens")).To(Equal("a--domain.with--hyphens"))
+	Expect(Normalize("*.example.com")).To(Equal("*.example.com"))
+	Expect(Normalize("bücher.example")).To(Equal("xn--bcher-kva.example"))
+	Expect(Normalize("*.bücher.example")).To(Equal("*.xn--bcher-kva.example"))
+	Expect(Normalize("~^(www\\.)?example\\.com$")).To(Equal("~^(www\\.)?example\\.com$"))
+	Expect(Normalize("~^api[0-9]+\\.example\\.com$")).To(Equal("~^api[0-9]+\\.example\\.com$"))
+	Expect(Normalize("~^(?P<sub>[a-z_-]+)\\.example\\.com")).To(Equal("~^(?P<sub>[a-z_-]+)\\.example\\.com"))
+}
+
+func TestDomainsNormalizeInvalid(t *testing.T) {
+	RegisterTestingT(t)
+	invalidDomains := []string{
+		"",
+		"-example.com",
+		"example-.com",
+		"exa_mple.com",
+		"ex*ample.com",
+		"www.*.example.com",
+		"*.com",
+		"example..com",
+		"~^(example\\.com$",
+		"~^example .com$",
+		"~^example\\.com$;",
+		"~^example\\.com; return 302 http://evil$",
+		"~^api\\d{2}\\.example\\.com$",
+		"~^example\\.com$}",
+		"~^\"example\\.com$",
+		"~^'example\\.com$",
+		"~^$host\\.example\\.com$",
+		"~^example\\.com$#",
+	}
+	for _, domain := range invalidDomains {
+		_, err := Normalize(domain)
+		Expect(err).To(HaveOccurred(), domain)
+	}
+}
+
+func TestDomainsPunycode(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(ToASCII("münchen.de")).To(Equal("xn--mnchen-3ya.de"))
+	Expect(ToASCII("例え.テスト")).To(Equal("xn--r8jz45g.xn--zckzah"))
+	Expect(ToUnicode("xn--mnchen-3ya.de")).To(Equal("münchen.de"))
+	Expect(ToUnicode("xn--r8jz45g.xn--zckzah")).To(Equal("例え.テスト"))
+	Expect(ToUnicode("example.com")).To(Equal("example.com"))
+}
+
+func TestDomainsMatches(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(Matches("example.com", "example.com")).To(BeTrue())
+	Expect(Matches("example.com", "EXAMPLE.com")).To(BeTrue())
+	Expect(Matches("*.example.com", "www.example.com")).To(BeTrue())
+	Expect(Matches("*.example.com", "example.com")).To(BeFalse())
+	Expect(Matches("*.example.com", "a.b.example.com")).To(BeFalse())
+	Expect(Matches("*.example.com", "www.example.org")).To(BeFalse())
+	Expect(Matches("~^api\\d+\\.example\\.com$", "api1.example.com")).To(BeTrue())
+	Expect(Matches("~^api\\d+\\.example\\.com$", "www.example.com")).To(BeFalse())
+}
+
+func TestDomainsFindConflicts(t *testing.T) {
+	RegisterTestingT(t)
+	conflicts := FindConflicts(map[string][]string{
+		"app-a": {"api.example.com", "a.example.com"},
+		"app-b": {"API.example.com", "b.example.com"},
+		"app-c": {"api.example.com", "*.example.com"},
+	})
+	Expect(conflicts).To(HaveLen(1))
+	Expect(conflicts[0].Domain).To(Equal("api.example.com"))
+	Expect(conflicts[0].Apps).To(Equal([]string{"app-a", "app-b", "app-c"}))
+}
@@ -0,0 +1,199 @@
+package domains
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// indexAppName is the property namespace holding the host-wide domain index
+	indexAppName = "_global_"
+
+	// indexProperty is the property list containing one "domain app" pair per line
+	indexProperty = "index"
+)
+
+// IndexEntry is a single domain claim held by an app
+type IndexEntry struct {
+	Domain string `json:"domain"`
+	App    string `json:"app"`
+}
+
+// IndexClaim records the domains for an app in the host-wide index
+// if any domain is already claimed by another app, an error is returned unless force is set,
+// in which case the domain is claimed anyway and the conflicts are returned
+func IndexClaim(appName string, domains []string, force bool) (conflicts []Conflict, err error) {
+	err = withIndexLock(func() error {
+		entries, err := IndexEntries()
+		if err != nil {
+			return err
+		}
+
+		claims := make(map[string][]string)
+		for _, entry := range entries {
+			claims[entry.Domain] = append(claims[entry.Domain], entry.App)
+		}
+
+		for _, domain := range domains {
+			var apps []string
+			for _, claimant := range claims[domain] {
+				if claimant != appName {
+					apps = append(apps, claimant)
+				}
+			}
+			if len(apps) == 0 {
+				continue
+			}
+
+			sort.Strings(apps)
+			conflicts = append(conflicts, Conflict{Domain: domain, Apps: append(apps, appName)})
+		}
+
+		if len(conflicts) > 0 && !force {
+			var messages []string
+			for _, conflict := range conflicts {
+				messages = append(messages, fmt.Sprintf("%s (used by %s)", conflict.Domain, strings.Join(conflict.Apps[:len(conflict.Apps)-1], ", ")))
+			}
+			return fmt.Errorf("Domains already in use by other apps: %s. Use --force to add them anyway", strings.Join(messages, ", "))
+		}
+
+		for _, domain := range domains {
+			if !entryExists(entries, domain, appName) {
+				entries = append(entries, IndexEntry{Domain: domain, App: appName})
+			}
+		}
+		return writeIndex(entries)
+	})
+	return
+}
+
+// IndexEntries returns all domain claims stored in the host-wide index
+func IndexEntries() ([]IndexEntry, error) {
+	lines, err := common.PropertyListGet("domains", indexAppName, indexProperty)
+	if err != nil {
+		return []IndexEntry{}, err
+	}
+
+	entries := []IndexEntry{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		entries = append(entries, IndexEntry{Domain: parts[0], App: parts[1]})
+	}
+	return entries, nil
+}
+
+// IndexRebuild recreates the host-wide index from the domains of every app
+func IndexRebuild() error {
+	return withIndexLock(func() error {
+		entries := []IndexEntry{}
+		apps, err := common.DokkuApps()
+		if err != nil {
+			return writeIndex(entries)
+		}
+
+		for _, appName := range apps {
+			entries = append(entries, appEntries(appName)...)
+		}
+		return writeIndex(entries)
+	})
+}
+
+// IndexReleaseApp removes every domain claimed by an app from the host-wide index
+func IndexReleaseApp(appName string) error {
+	return withIndexLock(func() error {
+		entries, err := IndexEntries()
+		if err != nil {
+			return err
+		}
+		return writeIndex(withoutApp(entries, appName))
+	})
+}
+
+// IndexSyncApp replaces the domains claimed by an app in the host-wide index with the app's current domains
+func IndexSyncApp(appName string) error {
+	return withIndexLock(func() error {
+		entries, err := IndexEntries()
+		if err != nil {
+			return err
+		}
+		return writeIndex(append(withoutApp(entries, appName), appEntries(appName)...))
+	})
+}
+
+func appEntries(appName string) []IndexEntry {
+	entries := []IndexEntry{}
+	for _, domain := range GetAppDomains(appName) {
+		normalized, err := Normalize(domain)
+		if err != nil {
+			normalized = domain
+		}
+		if !entryExists(entries, normalized, appName) {
+			entries = append(entries, IndexEntry{Domain: normalized, App: appName})
+		}
+	}
+	return entries
+}
+
+func entryExists(entries []IndexEntry, domain string, appName string) bool {
+	for _, entry := range entries {
+		if entry.Domain == domain && entry.App == appName {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutApp(entries []IndexEntry, appName string) []IndexEntry {
+	filtered := []IndexEntry{}
+	for _, entry := range entries {
+		if entry.App != appName {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// withIndexLock runs a function while holding an exclusive lock on the host-wide index
+func withIndexLock(fn func() error) error {
+	if err := common.PropertyTouch("domains", indexAppName, "index.lock"); err != nil {
+		return err
+	}
+
+	lockPath := filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "config", "domains", indexAppName, "index.lock")
+	file, err := os.Open(lockPath)
+	if err != nil {
+		return fmt.Errorf("Unable to open domain index lock: %s", err.Error())
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("Unable to lock domain index: %s", err.Error())
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+func writeIndex(entries []IndexEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Domain == entries[j].Domain {
+			return entries[i].App < entries[j].App
+		}
+		return entries[i].Domain < entries[j].Domain
+	})
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "%s %s\n", entry.Domain, entry.App)
+	}
+	return common.PropertyWrite("domains", indexAppName, indexProperty, b.String())
+}
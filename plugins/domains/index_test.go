@@ -0,0 +1,46 @@
+package domains
+
+import (
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestDomainsIndexClaim(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	conflicts, err := IndexClaim("app-a", []string{"api.example.com", "a.example.com"}, false)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(conflicts).To(BeEmpty())
+
+	_, err = IndexClaim("app-a", []string{"api.example.com"}, false)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = IndexClaim("app-b", []string{"b.example.com", "api.example.com"}, false)
+	Expect(err).To(HaveOccurred())
+
+	entries, err := IndexEntries()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(entries).To(Equal([]IndexEntry{
+		{Domain: "a.example.com", App: "app-a"},
+		{Domain: "api.example.com", App: "app-a"},
+	}))
+
+	conflicts, err = IndexClaim("app-b", []string{"b.example.com", "api.example.com"}, true)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(conflicts).To(Equal([]Conflict{{Domain: "api.example.com", Apps: []string{"app-a", "app-b"}}}))
+
+	conflicts, err = DetectConflicts()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(conflicts).To(Equal([]Conflict{{Domain: "api.example.com", Apps: []string{"app-a", "app-b"}}}))
+
+	Expect(IndexReleaseApp("app-a")).To(Succeed())
+	entries, err = IndexEntries()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(entries).To(Equal([]IndexEntry{
+		{Domain: "api.example.com", App: "app-b"},
+		{Domain: "b.example.com", App: "app-b"},
+	}))
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+// lists domains that are claimed by more than one app
+func main() {
+	args := flag.NewFlagSet("domains:conflicts", flag.ExitOnError)
+	format := args.String("format", "stdout", "format: [ stdout | json ] which format to output conflicts as")
+	args.Parse(os.Args[2:])
+
+	if err := domains.CommandConflicts(*format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+// claims domains for an app in the host-wide domain index
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	force := common.ToBool(flag.Arg(1))
+
+	if err := common.VerifyAppName(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+	if flag.NArg() < 3 {
+		return
+	}
+	appDomains := flag.Args()[2:]
+
+	conflicts, err := domains.IndexClaim(appName, appDomains, force)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+
+	for _, conflict := range conflicts {
+		common.LogWarn(fmt.Sprintf("%s is also used by %s", conflict.Domain, strings.Join(conflict.Apps[:len(conflict.Apps)-1], ", ")))
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+// syncs the host-wide domain index with the domains of one or all apps
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if appName == "" {
+		if err := domains.IndexRebuild(); err != nil {
+			common.LogFail(err.Error())
+		}
+		return
+	}
+
+	if err := domains.IndexSyncApp(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
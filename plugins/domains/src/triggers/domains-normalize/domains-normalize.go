@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+// validates each domain and writes its normalized form to stdout
+func main() {
+	flag.Parse()
+
+	for _, domain := range flag.Args() {
+		normalized, err := domains.Normalize(domain)
+		if err != nil {
+			common.LogFail(err.Error())
+		}
+		fmt.Fprintln(os.Stdout, normalized)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+// moves the domains claimed by a renamed app to its new name
+func main() {
+	flag.Parse()
+	oldAppName := flag.Arg(0)
+	newAppName := flag.Arg(1)
+
+	if err := domains.IndexReleaseApp(oldAppName); err != nil {
+		common.LogFail(err.Error())
+	}
+	if err := domains.IndexSyncApp(newAppName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
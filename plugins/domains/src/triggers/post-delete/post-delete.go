@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/domains"
+)
+
+// releases the domains claimed by a deleted app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := domains.IndexReleaseApp(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
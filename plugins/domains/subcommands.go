@@ -0,0 +1,42 @@
+package domains
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandConflicts implements domains:conflicts
+func CommandConflicts(format string) error {
+	if format != "stdout" && format != "json" {
+		return fmt.Errorf("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	conflicts, err := DetectConflicts()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(conflicts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+		return nil
+	}
+
+	if len(conflicts) == 0 {
+		common.LogInfo1Quiet("No domain conflicts found")
+		return nil
+	}
+
+	common.LogInfo2Quiet("Domain conflicts")
+	for _, conflict := range conflicts {
+		common.LogVerbose(fmt.Sprintf("%s: %s", conflict.Domain, strings.Join(conflict.Apps, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+var errContainerNotFound = errors.New("No such container")
+
+// dockerClient is a minimal client for the parts of the docker engine api used to read logs
+type dockerClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// logsRequest describes the log lines requested from a container
+type logsRequest struct {
+	follow bool
+	since  time.Time
+	until  time.Time
+	tail   int
+}
+
+type containerInspect struct {
+	ID     string `json:"Id"`
+	Config struct {
+		Tty bool `json:"Tty"`
+	} `json:"Config"`
+}
+
+// newDockerClient returns a client for the docker daemon specified by DOCKER_HOST
+func newDockerClient() (*dockerClient, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	parts := strings.SplitN(host, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid DOCKER_HOST %s", host)
+	}
+
+	switch parts[0] {
+	case "unix":
+		socket := parts[1]
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &dockerClient{baseURL: "http://docker", http: &http.Client{Transport: transport}}, nil
+	case "tcp", "http":
+		tlsConfig, err := dockerTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			return &dockerClient{baseURL: "http://" + parts[1], http: &http.Client{}}, nil
+		}
+		transport := &http.Transport{TLSClientConfig: tlsConfig}
+		return &dockerClient{baseURL: "https://" + parts[1], http: &http.Client{Transport: transport}}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported DOCKER_HOST scheme %s", parts[0])
+	}
+}
+
+// dockerTLSConfig returns the tls config of a tcp docker daemon when DOCKER_TLS_VERIFY is set, reading the
+// ca, certificate and key from DOCKER_CERT_PATH in the same way as the docker cli
+func dockerTLSConfig() (*tls.Config, error) {
+	if os.Getenv("DOCKER_TLS_VERIFY") == "" {
+		return nil, nil
+	}
+
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		certPath = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+
+	ca, err := ioutil.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the docker daemon ca certificate: %s", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("Invalid docker daemon ca certificate in %s", filepath.Join(certPath, "ca.pem"))
+	}
+
+	certificate, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load the docker client certificate: %s", err.Error())
+	}
+
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func (c *dockerClient) get(path string, query url.Values) (*http.Response, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	response, err := c.http.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to the docker daemon: %s", err.Error())
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, errContainerNotFound
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		var message struct {
+			Message string `json:"message"`
+		}
+		b, _ := ioutil.ReadAll(response.Body)
+		if json.Unmarshal(b, &message) != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(b))
+		}
+		return nil, fmt.Errorf("Docker daemon responded with %d: %s", response.StatusCode, message.Message)
+	}
+	return response, nil
+}
+
+func (c *dockerClient) inspect(containerID string) (containerInspect, error) {
+	var container containerInspect
+	response, err := c.get("/containers/"+url.PathEscape(containerID)+"/json", nil)
+	if err != nil {
+		return container, err
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(&container)
+	return container, err
+}
+
+// logs reads the log lines of a container, calling handle with the stream and contents of each line
+func (c *dockerClient) logs(containerID string, request logsRequest, handle func(stream string, line string) error) error {
+	container, err := c.inspect(containerID)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("stdout", "1")
+	query.Set("stderr", "1")
+	query.Set("timestamps", "1")
+	query.Set("tail", "all")
+	if request.tail >= 0 {
+		query.Set("tail", strconv.Itoa(request.tail))
+	}
+	if request.follow {
+		query.Set("follow", "1")
+	}
+	if !request.since.IsZero() {
+		query.Set("since", formatUnixTime(request.since))
+	}
+	if !request.until.IsZero() {
+		query.Set("until", formatUnixTime(request.until))
+	}
+
+	response, err := c.get("/containers/"+url.PathEscape(containerID)+"/logs", query)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if container.Config.Tty {
+		return splitLines(response.Body, "stdout", handle)
+	}
+	return demultiplex(response.Body, handle)
+}
+
+// demultiplex splits a stream of stdout and stderr frames, as sent by docker for containers without a tty, into lines
+func demultiplex(r io.Reader, handle func(stream string, line string) error) error {
+	header := make([]byte, 8)
+	pending := map[string]string{}
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		stream := "stdout"
+		if header[0] == 2 {
+			stream = "stderr"
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+
+		lines := strings.Split(pending[stream]+string(payload), "\n")
+		pending[stream] = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			if err := handle(stream, strings.TrimSuffix(line, "\r")); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, stream := range []string{"stdout", "stderr"} {
+		if pending[stream] != "" {
+			if err := handle(stream, pending[stream]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func splitLines(r io.Reader, stream string, handle func(stream string, line string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if handleErr := handle(stream, strings.TrimRight(line, "\r\n")); handleErr != nil {
+				return handleErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func formatUnixTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
@@ -0,0 +1,410 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+)
+
+// DefaultNum is the number of lines displayed when no number is specified
+const DefaultNum = 100
+
+var colors = []int{36, 33, 32, 35, 31}
+
+// Options controls which log lines are displayed and how they are formatted
+type Options struct {
+	// ProcessType limits output to containers of a single process type
+	ProcessType string
+	// Num is the number of lines to display, or all lines when negative
+	Num int
+	// Follow continues streaming new lines until all containers stop
+	Follow bool
+	// Quiet displays the raw log message without colors, time and names
+	Quiet bool
+	// Since only displays lines logged after a duration ago or a timestamp
+	Since string
+	// Grep only displays lines matching a regular expression
+	Grep string
+	// Format is either empty for text output or json
+	Format string
+}
+
+// Container is an app container whose logs may be displayed
+type Container struct {
+	ID          string
+	ProcessType string
+	Index       int
+}
+
+// Line is a single log line emitted by an app container
+type Line struct {
+	App         string    `json:"app"`
+	ProcessType string    `json:"process-type"`
+	Index       int       `json:"index"`
+	ContainerID string    `json:"container-id"`
+	Stream      string    `json:"stream"`
+	Timestamp   time.Time `json:"timestamp"`
+	Message     string    `json:"message"`
+
+	color int
+}
+
+// lineBuffer keeps the most recent lines added to it, up to a limit, or every line when the limit is negative
+type lineBuffer struct {
+	limit int
+	lines []Line
+	next  int
+}
+
+// GetScheduler returns the scheduler of an app
+func GetScheduler(appName string) string {
+	return config.GetWithDefault(appName, "DOKKU_SCHEDULER", config.GetWithDefault("", "DOKKU_SCHEDULER", "docker-local"))
+}
+
+// GetAppContainers returns the running containers of an app, optionally limited to a process type
+func GetAppContainers(appName string, processType string) ([]Container, error) {
+	pattern := "CONTAINER.*"
+	if processType != "" {
+		pattern = "CONTAINER." + processType + ".*"
+	}
+
+	files, err := filepath.Glob(filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, pattern))
+	if err != nil {
+		return []Container{}, err
+	}
+
+	containers := []Container{}
+	for _, file := range files {
+		container, ok := parseContainerName(strings.TrimPrefix(filepath.Base(file), "CONTAINER."))
+		if !ok {
+			continue
+		}
+		container.ID = common.ReadFirstLine(file)
+		if container.ID == "" {
+			continue
+		}
+		containers = append(containers, container)
+	}
+	sortContainers(containers)
+	return containers, nil
+}
+
+// GetFailedContainers returns the containers retired after a failed deploy of an app. Containers
+// that have since been removed are dropped from the list and reported as missing
+func GetFailedContainers(appName string) (containers []Container, missing []string, err error) {
+	containers = []Container{}
+	missing = []string{}
+	filename := getFailedContainersFile(appName)
+	if !common.FileExists(filename) {
+		return
+	}
+
+	lines, err := common.FileToSlice(filename)
+	if err != nil {
+		return
+	}
+
+	client, err := newDockerClient()
+	if err != nil {
+		return
+	}
+
+	kept := []string{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		container := Container{ID: fields[0]}
+		if len(fields) > 1 {
+			container, _ = parseContainerName(fields[1])
+			container.ID = fields[0]
+		}
+
+		if _, inspectErr := client.inspect(container.ID); inspectErr == errContainerNotFound {
+			missing = append(missing, container.ID)
+			continue
+		} else if inspectErr != nil {
+			err = inspectErr
+			return
+		}
+
+		kept = append(kept, line)
+		containers = append(containers, container)
+	}
+
+	if len(missing) > 0 {
+		contents := ""
+		if len(kept) > 0 {
+			contents = strings.Join(kept, "\n") + "\n"
+		}
+		if err = writeFile(filename, contents); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ParseSince converts a duration such as 10m or a RFC3339 or unix timestamp into the time it refers to
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration < 0 {
+			return time.Time{}, fmt.Errorf("Invalid --since value %s, the duration must be positive", value)
+		}
+		return now.Add(-duration), nil
+	}
+	if timestamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return timestamp, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid --since value %s, specify a duration such as 10m or a timestamp such as 2006-01-02T15:04:05Z", value)
+}
+
+// Display writes the log lines of the given containers to w, ordered by the time they were logged.
+// When following, lines logged afterwards are written as they arrive until every container stops
+func Display(w io.Writer, appName string, containers []Container, options Options) error {
+	if options.Format != "" && options.Format != "json" {
+		return fmt.Errorf("Invalid --format value %s, valid formats include: json", options.Format)
+	}
+
+	var grep *regexp.Regexp
+	if options.Grep != "" {
+		var err error
+		if grep, err = regexp.Compile(options.Grep); err != nil {
+			return fmt.Errorf("Invalid --grep expression: %s", err.Error())
+		}
+	}
+
+	now := time.Now()
+	request := logsRequest{tail: options.Num}
+	if options.Since != "" {
+		since, err := ParseSince(options.Since, now)
+		if err != nil {
+			return err
+		}
+		request.since = since
+	}
+	if options.Follow {
+		request.until = now
+	}
+	if grep != nil {
+		// the requested number of lines applies to matching lines, so every line is streamed
+		// and only the most recent matches are kept
+		request.tail = -1
+	}
+
+	client, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+
+	lines, err := collectLines(client, appName, containers, request, grep, options.Num)
+	if err != nil {
+		return err
+	}
+	if options.Num >= 0 && len(lines) > options.Num {
+		lines = lines[len(lines)-options.Num:]
+	}
+	for _, line := range lines {
+		if err := writeLine(w, line, options); err != nil {
+			return err
+		}
+	}
+
+	if !options.Follow {
+		return nil
+	}
+	return followLines(client, appName, containers, logsRequest{follow: true, since: now, tail: -1}, func(line Line) error {
+		if grep != nil && !grep.MatchString(line.Message) {
+			return nil
+		}
+		return writeLine(w, line, options)
+	})
+}
+
+// collectLines fetches the logs of all containers concurrently and merges them in the order they were logged.
+// Lines not matching grep are dropped as they are read, and at most limit lines are kept per container
+func collectLines(client *dockerClient, appName string, containers []Container, request logsRequest, grep *regexp.Regexp, limit int) ([]Line, error) {
+	results := make([][]Line, len(containers))
+	errs := make([]error, len(containers))
+	var wg sync.WaitGroup
+	for i, container := range containers {
+		wg.Add(1)
+		go func(i int, container Container) {
+			defer wg.Done()
+			buffer := newLineBuffer(limit)
+			errs[i] = client.logs(container.ID, request, func(stream string, raw string) error {
+				line := newLine(appName, container, i, stream, raw)
+				if grep == nil || grep.MatchString(line.Message) {
+					buffer.add(line)
+				}
+				return nil
+			})
+			results[i] = buffer.ordered()
+		}(i, container)
+	}
+	wg.Wait()
+
+	lines := []Line{}
+	for i, err := range errs {
+		if err == errContainerNotFound {
+			continue
+		}
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, results[i]...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+	return lines, nil
+}
+
+// followLines streams the logs of all containers, calling handle for each line as it is logged
+func followLines(client *dockerClient, appName string, containers []Container, request logsRequest, handle func(Line) error) error {
+	lines := make(chan Line)
+	done := make(chan error, len(containers))
+	for i, container := range containers {
+		go func(i int, container Container) {
+			done <- client.logs(container.ID, request, func(stream string, raw string) error {
+				lines <- newLine(appName, container, i, stream, raw)
+				return nil
+			})
+		}(i, container)
+	}
+
+	running := len(containers)
+	for running > 0 {
+		select {
+		case line := <-lines:
+			if err := handle(line); err != nil {
+				return err
+			}
+		case err := <-done:
+			running--
+			if err != nil && err != errContainerNotFound {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newLine(appName string, container Container, position int, stream string, raw string) Line {
+	line := Line{
+		App:         appName,
+		ProcessType: container.ProcessType,
+		Index:       container.Index,
+		ContainerID: container.ID,
+		Stream:      stream,
+		Message:     raw,
+		color:       colors[position%len(colors)],
+	}
+
+	// lines are requested with timestamps, which docker prefixes to each line
+	parts := strings.SplitN(raw, " ", 2)
+	if timestamp, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+		line.Timestamp = timestamp
+		line.Message = ""
+		if len(parts) > 1 {
+			line.Message = parts[1]
+		}
+	}
+	return line
+}
+
+func writeLine(w io.Writer, line Line, options Options) error {
+	var err error
+	switch {
+	case options.Format == "json":
+		var b []byte
+		if b, err = json.Marshal(line); err == nil {
+			_, err = fmt.Fprintln(w, string(b))
+		}
+	case options.Quiet:
+		_, err = fmt.Fprintln(w, line.Message)
+	default:
+		name := line.ContainerID
+		if line.ProcessType != "" {
+			name = fmt.Sprintf("%s.%d", line.ProcessType, line.Index)
+		}
+		_, err = fmt.Fprintf(w, "\x1b[%dm%s app[%s]:\x1b[0m %s\n", line.color, line.Timestamp.Format(time.RFC3339Nano), name, line.Message)
+	}
+	return err
+}
+
+// parseContainerName splits a process name such as web.1 into its process type and index
+func parseContainerName(name string) (Container, bool) {
+	position := strings.LastIndex(name, ".")
+	if position <= 0 {
+		return Container{}, false
+	}
+	index, err := strconv.Atoi(name[position+1:])
+	if err != nil {
+		return Container{}, false
+	}
+	return Container{ProcessType: name[:position], Index: index}, true
+}
+
+func sortContainers(containers []Container) {
+	sort.SliceStable(containers, func(i, j int) bool {
+		if containers[i].ProcessType != containers[j].ProcessType {
+			return containers[i].ProcessType < containers[j].ProcessType
+		}
+		return containers[i].Index < containers[j].Index
+	})
+}
+
+func getFailedContainersFile(appName string) string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "scheduler-docker-local", appName, "failed-containers")
+}
+
+func writeFile(filename string, contents string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(contents)
+	return err
+}
+
+func newLineBuffer(limit int) *lineBuffer {
+	return &lineBuffer{limit: limit, lines: []Line{}}
+}
+
+func (b *lineBuffer) add(line Line) {
+	if b.limit < 0 || len(b.lines) < b.limit {
+		b.lines = append(b.lines, line)
+		return
+	}
+	if b.limit == 0 {
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % b.limit
+}
+
+// ordered returns the kept lines, oldest first
+func (b *lineBuffer) ordered() []Line {
+	return append(append([]Line{}, b.lines[b.next:]...), b.lines[:b.next]...)
+}
@@ -0,0 +1,305 @@
+package logs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+type fakeContainer struct {
+	tty    bool
+	frames [][]byte
+}
+
+var (
+	requests      []url.Values
+	requestsMutex sync.Mutex
+	dockerLogPath string
+)
+
+// dockerStub records its arguments and tracks whether a container was started by run
+const dockerStub = `#!/bin/sh
+dir="$(dirname "$0")"
+echo "$*" >> "$dir/docker.log"
+case "$1" in
+  inspect) [ -f "$dir/container" ] || exit 1; echo running ;;
+  run) touch "$dir/container"; echo abc123 ;;
+  container) rm -f "$dir/container" ;;
+esac
+`
+
+// setupTestRoots creates dokku directories, a docker cli stub and a fake docker daemon serving the given containers
+func setupTestRoots(t *testing.T, containers map[string]fakeContainer) func() {
+	roots := testutil.SetupRoots(t)
+	dockerLogPath = filepath.Join(roots.BinDir, "docker.log")
+	roots.WriteStub(t, "docker", dockerStub)
+
+	requests = []url.Values{}
+	listener, err := net.Listen("unix", filepath.Join(roots.BinDir, "docker.sock"))
+	Expect(err).NotTo(HaveOccurred())
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")
+		container, ok := containers[parts[0]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"message": "No such container: %s"}`, parts[0])
+			return
+		}
+		if parts[1] == "json" {
+			fmt.Fprintf(w, `{"Id": "%s", "Config": {"Tty": %t}}`, parts[0], container.tty)
+			return
+		}
+		requestsMutex.Lock()
+		requests = append(requests, r.URL.Query())
+		requestsMutex.Unlock()
+		for _, frame := range container.frames {
+			w.Write(frame)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+
+	os.Setenv("DOCKER_HOST", "unix://"+filepath.Join(roots.BinDir, "docker.sock"))
+	return func() {
+		server.Close()
+		os.Unsetenv("DOCKER_HOST")
+		roots.Teardown()
+	}
+}
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, []byte(payload)...)
+}
+
+func writeContainerFile(appName string, name string, containerID string) {
+	appRoot := filepath.Join(os.Getenv("DOKKU_ROOT"), appName)
+	Expect(os.MkdirAll(appRoot, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(appRoot, "CONTAINER."+name), []byte(containerID+"\n"), 0644)).To(Succeed())
+}
+
+var testContainers = map[string]fakeContainer{
+	"abc": {frames: [][]byte{
+		frame(1, "2019-01-01T00:00:01.000000000Z listening on 5000\n"),
+		frame(2, "2019-01-01T00:00:03.000000000Z GET /health 500\n2019-01-01T00:00:04"),
+		frame(2, ".000000000Z GET /health 200\n"),
+	}},
+	"def": {tty: true, frames: [][]byte{
+		[]byte("2019-01-01T00:00:02.000000000Z worker started\r\n"),
+	}},
+}
+
+func TestLogsParseSince(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	Expect(ParseSince("10m", now)).To(Equal(now.Add(-10 * time.Minute)))
+	Expect(ParseSince("2019-01-01T11:00:00Z", now)).To(Equal(now.Add(-time.Hour)))
+	Expect(ParseSince("1546340400", now)).To(BeTemporally("==", now.Add(-time.Hour)))
+	_, err := ParseSince("-10m", now)
+	Expect(err).To(HaveOccurred())
+	_, err = ParseSince("yesterday", now)
+	Expect(err).To(HaveOccurred())
+}
+
+func TestLogsDemultiplex(t *testing.T) {
+	RegisterTestingT(t)
+
+	stream := bytes.Join([][]byte{
+		frame(1, "one\ntw"),
+		frame(2, "error\n"),
+		frame(1, "o\nthree"),
+	}, nil)
+	lines := []string{}
+	Expect(demultiplex(bytes.NewReader(stream), func(stream string, line string) error {
+		lines = append(lines, stream+": "+line)
+		return nil
+	})).To(Succeed())
+	Expect(lines).To(Equal([]string{"stdout: one", "stderr: error", "stdout: two", "stdout: three"}))
+
+	Expect(demultiplex(bytes.NewReader(frame(1, "one")[:10]), func(string, string) error { return nil })).NotTo(Succeed())
+}
+
+func TestLogsGetAppContainers(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t, testContainers)()
+
+	writeContainerFile("api", "web.10", "ghi")
+	writeContainerFile("api", "web.2", "abc")
+	writeContainerFile("api", "worker.1", "def")
+	writeContainerFile("api", "web", "invalid")
+
+	containers, err := GetAppContainers("api", "")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(containers).To(Equal([]Container{
+		{ID: "abc", ProcessType: "web", Index: 2},
+		{ID: "ghi", ProcessType: "web", Index: 10},
+		{ID: "def", ProcessType: "worker", Index: 1},
+	}))
+
+	containers, err = GetAppContainers("api", "worker")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(containers).To(HaveLen(1))
+}
+
+func TestLogsDisplay(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t, testContainers)()
+
+	containers := []Container{
+		{ID: "abc", ProcessType: "web", Index: 1},
+		{ID: "def", ProcessType: "worker", Index: 1},
+		{ID: "removed", ProcessType: "web", Index: 2},
+	}
+
+	var out bytes.Buffer
+	Expect(Display(&out, "api", containers, Options{Num: DefaultNum, Quiet: true})).To(Succeed())
+	Expect(out.String()).To(Equal("listening on 5000\nworker started\nGET /health 500\nGET /health 200\n"))
+	Expect(requests[0].Get("tail")).To(Equal("100"))
+	Expect(requests[0].Get("timestamps")).To(Equal("1"))
+
+	out.Reset()
+	Expect(Display(&out, "api", containers, Options{Num: 1, Grep: "health", Since: "2018-12-31T00:00:00Z"})).To(Succeed())
+	Expect(out.String()).To(Equal("\x1b[36m2019-01-01T00:00:04Z app[web.1]:\x1b[0m GET /health 200\n"))
+	Expect(requests[len(requests)-1].Get("tail")).To(Equal("all"))
+	Expect(requests[len(requests)-1].Get("since")).To(Equal("1546214400.000000000"))
+
+	out.Reset()
+	Expect(Display(&out, "api", containers[1:2], Options{Num: -1, Format: "json"})).To(Succeed())
+	var line Line
+	Expect(json.Unmarshal(out.Bytes(), &line)).To(Succeed())
+	Expect(line).To(Equal(Line{
+		App:         "api",
+		ProcessType: "worker",
+		Index:       1,
+		ContainerID: "def",
+		Stream:      "stdout",
+		Timestamp:   time.Date(2019, 1, 1, 0, 0, 2, 0, time.UTC),
+		Message:     "worker started",
+	}))
+
+	Expect(Display(&out, "api", containers, Options{Format: "yaml"})).To(MatchError(ContainSubstring("Invalid --format")))
+	Expect(Display(&out, "api", containers, Options{Grep: "("})).To(MatchError(ContainSubstring("Invalid --grep")))
+}
+
+func TestLogsGetFailedContainers(t *testing.T) {
+	RegisterTestingT(t)
+	defer setupTestRoots(t, testContainers)()
+
+	containers, missing, err := GetFailedContainers("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(containers).To(BeEmpty())
+	Expect(missing).To(BeEmpty())
+
+	filename := getFailedContainersFile("api")
+	Expect(os.MkdirAll(filepath.Dir(filename), 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filename, []byte("abc web.1\nremoved web.2\ndef worker.1\n"), 0644)).To(Succeed())
+
+	containers, missing, err = GetFailedContainers("api")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(containers).To(Equal([]Container{
+		{ID: "abc", ProcessType: "web", Index: 1},
+		{ID: "def", ProcessType: "worker", Index: 1},
+	}))
+	Expect(missing).To(Equal([]string{"removed"}))
+	b, err := ioutil.ReadFile(filename)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("abc web.1\ndef worker.1\n"))
+}
+
+func TestLogsLineBuffer(t *testing.T) {
+	RegisterTestingT(t)
+
+	messages := func(lines []Line) []string {
+		result := []string{}
+		for _, line := range lines {
+			result = append(result, line.Message)
+		}
+		return result
+	}
+
+	buffer := newLineBuffer(2)
+	for _, message := range []string{"a", "b", "c", "d", "e"} {
+		buffer.add(Line{Message: message})
+	}
+	Expect(messages(buffer.ordered())).To(Equal([]string{"d", "e"}))
+
+	buffer = newLineBuffer(-1)
+	for _, message := range []string{"a", "b", "c"} {
+		buffer.add(Line{Message: message})
+	}
+	Expect(messages(buffer.ordered())).To(Equal([]string{"a", "b", "c"}))
+
+	buffer = newLineBuffer(0)
+	buffer.add(Line{Message: "a"})
+	Expect(buffer.ordered()).To(BeEmpty())
+}
+
+func TestLogsDockerClientTLS(t *testing.T) {
+	RegisterTestingT(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "OK")
+	}))
+	defer server.Close()
+	certPath, err := ioutil.TempDir("", "docker-certs")
+	Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(certPath)
+	defer os.Unsetenv("DOCKER_HOST")
+	defer os.Unsetenv("DOCKER_TLS_VERIFY")
+	defer os.Unsetenv("DOCKER_CERT_PATH")
+
+	os.Setenv("DOCKER_HOST", "tcp://"+server.Listener.Addr().String())
+	client, err := newDockerClient()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(client.baseURL).To(HavePrefix("http://"))
+
+	os.Setenv("DOCKER_TLS_VERIFY", "1")
+	os.Setenv("DOCKER_CERT_PATH", certPath)
+	_, err = newDockerClient()
+	Expect(err).To(MatchError(ContainSubstring("ca certificate")))
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	Expect(ioutil.WriteFile(filepath.Join(certPath, "ca.pem"), ca, 0600)).To(Succeed())
+	_, err = newDockerClient()
+	Expect(err).To(MatchError(ContainSubstring("client certificate")))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ioutil.WriteFile(filepath.Join(certPath, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(certPath, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)).To(Succeed())
+
+	client, err = newDockerClient()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(client.baseURL).To(HavePrefix("https://"))
+	response, err := client.get("/_ping", nil)
+	Expect(err).NotTo(HaveOccurred())
+	response.Body.Close()
+}
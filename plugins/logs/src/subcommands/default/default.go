@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// display recent log output
+func main() {
+	options := logs.Options{}
+	args := flag.NewFlagSet("logs", flag.ExitOnError)
+	args.IntVar(&options.Num, "num", logs.DefaultNum, "--num: the number of lines to display, -1 displays all lines")
+	args.IntVar(&options.Num, "n", logs.DefaultNum, "-n: alias for --num")
+	args.StringVar(&options.ProcessType, "ps", "", "--ps: only display logs from the given process")
+	args.StringVar(&options.ProcessType, "p", "", "-p: alias for --ps")
+	args.BoolVar(&options.Follow, "tail", false, "--tail: continually stream logs")
+	args.BoolVar(&options.Follow, "t", false, "-t: alias for --tail")
+	args.BoolVar(&options.Quiet, "quiet", false, "--quiet: display raw logs without colors, time and names")
+	args.BoolVar(&options.Quiet, "q", false, "-q: alias for --quiet")
+	args.StringVar(&options.Since, "since", "", "--since: only display logs newer than a duration such as 10m or a timestamp")
+	args.StringVar(&options.Grep, "grep", "", "--grep: only display logs matching a regular expression")
+	args.StringVar(&options.Format, "format", "", "--format: display logs as json")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := logs.CommandDefault(appName, options); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// shows the last failed deploy logs
+func main() {
+	options := logs.Options{}
+	args := flag.NewFlagSet("logs:failed", flag.ExitOnError)
+	args.IntVar(&options.Num, "num", -1, "--num: the number of lines to display, -1 displays all lines")
+	args.IntVar(&options.Num, "n", -1, "-n: alias for --num")
+	args.BoolVar(&options.Quiet, "quiet", false, "--quiet: display raw logs without colors, time and names")
+	args.BoolVar(&options.Quiet, "q", false, "-q: alias for --quiet")
+	args.StringVar(&options.Since, "since", "", "--since: only display logs newer than a duration such as 10m or a timestamp")
+	args.StringVar(&options.Grep, "grep", "", "--grep: only display logs matching a regular expression")
+	args.StringVar(&options.Format, "format", "", "--format: display logs as json")
+	common.ParseArgs(args, os.Args[2:])
+	appName := args.Arg(0)
+
+	if err := logs.CommandFailed(appName, options); err != nil {
+		common.LogFail(err.Error())
+	}
+}
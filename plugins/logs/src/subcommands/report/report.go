@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// displays a logs report for one or more apps
+func main() {
+	flag.Parse()
+	appName := flag.Arg(1)
+	infoFlag := flag.Arg(2)
+
+	if err := logs.CommandReport(appName, infoFlag); err != nil {
+		common.LogFail(err.Error())
+	}
+}
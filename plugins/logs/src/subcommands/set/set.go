@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// set or clear a logs property for an app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(1)
+	property := flag.Arg(2)
+	value := flag.Arg(3)
+
+	if err := logs.CommandSet(appName, property, value); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// outputs the labels and log driver options of an app container
+func main() {
+	stdin, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+
+	flag.Parse()
+	appName := flag.Arg(0)
+	processType := flag.Arg(3)
+	containerIndex := flag.Arg(4)
+
+	fmt.Printf(" --label=%s=%s ", logs.AppLabel, appName)
+	if sink, ok, _ := logs.GetSink(appName); ok {
+		for _, arg := range sink.DockerArgs(appName, processType, containerIndex) {
+			fmt.Printf(" %s ", arg)
+		}
+	}
+
+	fmt.Print(string(stdin))
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the logs plugin
+func main() {
+	if err := common.PropertySetup("logs"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the logs plugin: %s", err.Error()))
+	}
+}
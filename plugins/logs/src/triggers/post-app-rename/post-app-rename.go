@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// removes the vector sidecar left behind under the old name of a renamed app
+func main() {
+	flag.Parse()
+	oldAppName := flag.Arg(0)
+
+	if err := logs.RemoveVectorContainer(oldAppName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
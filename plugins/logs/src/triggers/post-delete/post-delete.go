@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// removes the vector sidecar and logs properties of a deleted app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := logs.RemoveVectorContainer(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+	if err := common.PropertyDestroy("logs", appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// starts the vector sidecar of an app if it is not running
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	sink, ok, err := logs.GetSink(appName)
+	if err != nil {
+		common.LogWarn(err.Error())
+		return
+	}
+	if !ok || sink.UsesLogDriver() || logs.GetVectorContainerStatus(appName) == "running" {
+		return
+	}
+	if err := logs.StartVectorContainer(appName, sink); err != nil {
+		common.LogFail(err.Error())
+	}
+}
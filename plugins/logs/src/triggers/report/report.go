@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
+)
+
+// displays a logs report for an app
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	if err := logs.ReportSingleApp(appName, ""); err != nil {
+		common.LogFail(err.Error())
+	}
+}
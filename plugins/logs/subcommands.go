@@ -0,0 +1,188 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandDefault displays recent log output of an app
+func CommandDefault(appName string, options Options) error {
+	if appName == "" {
+		return errors.New("Please specify an app to run the command on")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if !common.IsDeployed(appName) {
+		return fmt.Errorf("App %s has not been deployed", appName)
+	}
+
+	scheduler := GetScheduler(appName)
+	if scheduler != "docker-local" {
+		// the flags are passed as "true" or empty and the newer options are appended, keeping the original arguments
+		return common.PlugnTrigger("scheduler-logs", scheduler, appName, options.ProcessType, flagArg(options.Follow), flagArg(options.Quiet), strconv.Itoa(options.Num), options.Since, options.Grep, options.Format)
+	}
+
+	containers, err := GetAppContainers(appName, options.ProcessType)
+	if err != nil {
+		return err
+	}
+	return Display(os.Stdout, appName, containers, options)
+}
+
+// CommandFailed displays the logs of the containers retired after the last failed deploy of an app
+func CommandFailed(appName string, options Options) error {
+	if appName != "" {
+		if err := common.VerifyAppName(appName); err != nil {
+			return err
+		}
+		return displayFailed(appName, options)
+	}
+
+	apps, err := common.DokkuApps()
+	if err != nil {
+		return err
+	}
+	for _, appName := range apps {
+		if err := displayFailed(appName, options); err != nil {
+			common.LogWarn(err.Error())
+		}
+	}
+	return nil
+}
+
+func displayFailed(appName string, options Options) error {
+	if options.Format != "json" {
+		common.LogInfo2Quiet(fmt.Sprintf("%s failed deploy logs", appName))
+	}
+
+	scheduler := GetScheduler(appName)
+	if scheduler != "docker-local" {
+		return common.PlugnTrigger("scheduler-logs-failed", scheduler, appName)
+	}
+
+	containers, missing, err := GetFailedContainers(appName)
+	if err != nil {
+		return err
+	}
+	for _, containerID := range missing {
+		common.LogWarn(fmt.Sprintf("App container %s no longer running", containerID))
+	}
+	if len(containers) == 0 {
+		common.LogWarn("No failed containers found")
+		return nil
+	}
+	return Display(os.Stdout, appName, containers, options)
+}
+
+// CommandReport displays a logs report for one or more apps
+func CommandReport(appName string, infoFlag string) error {
+	if strings.HasPrefix(appName, "--") {
+		infoFlag = appName
+		appName = ""
+	}
+
+	if appName != "" {
+		return ReportSingleApp(appName, infoFlag)
+	}
+
+	apps, err := common.DokkuApps()
+	if err != nil {
+		return err
+	}
+	for _, appName := range apps {
+		if err := ReportSingleApp(appName, infoFlag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandSet sets or clears a logs property for an app, applying the change to its vector sidecar
+func CommandSet(appName string, property string, value string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if property == "vector-sink" && value != "" {
+		if _, err := ParseSink(value); err != nil {
+			return err
+		}
+	}
+
+	previous, hadSink, _ := GetSink(appName)
+	common.CommandPropertySet("logs", appName, property, value, DefaultProperties)
+	if err := ApplySink(appName); err != nil {
+		return err
+	}
+
+	current, hasSink, err := GetSink(appName)
+	if err != nil {
+		return err
+	}
+	if (hadSink && previous.UsesLogDriver()) || (hasSink && current.UsesLogDriver()) {
+		if common.IsDeployed(appName) {
+			common.LogVerbose(fmt.Sprintf("Restart %s to apply the log driver change to its running containers", appName))
+		}
+	}
+	return nil
+}
+
+// ReportSingleApp displays the logs report for an app
+func ReportSingleApp(appName string, infoFlag string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	infoFlags := map[string]string{
+		"--logs-vector-sink":      "",
+		"--logs-shipper":          "none",
+		"--logs-vector-container": "",
+	}
+	sink, ok, err := GetSink(appName)
+	if err != nil {
+		return err
+	}
+	if ok {
+		infoFlags["--logs-vector-sink"] = sink.String()
+		infoFlags["--logs-shipper"] = sink.Shipper()
+		if !sink.UsesLogDriver() {
+			infoFlags["--logs-vector-container"] = GetVectorContainerStatus(appName)
+		}
+	}
+
+	flags := []string{}
+	for flag := range infoFlags {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+
+	if infoFlag == "" {
+		common.LogInfo2Quiet(fmt.Sprintf("%s logs information", appName))
+		for _, flag := range flags {
+			key := common.UcFirst(strings.Replace(strings.TrimPrefix(flag, "--"), "-", " ", -1))
+			common.LogVerbose(fmt.Sprintf("%-31s%s", key+":", infoFlags[flag]))
+		}
+		return nil
+	}
+
+	value, ok := infoFlags[infoFlag]
+	if !ok {
+		return fmt.Errorf("Invalid flag passed, valid flags: %s", strings.Join(flags, ", "))
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// flagArg formats a boolean flag as a shell trigger argument, "true" when set and empty otherwise
+func flagArg(value bool) string {
+	if value {
+		return "true"
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package logs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// VectorImage is the image vector sidecars are run with unless DOKKU_VECTOR_IMAGE is set
+	VectorImage = "timberio/vector:0.10.0-alpine"
+
+	// AppLabel is the container label holding the name of the app a container belongs to
+	AppLabel = "com.dokku.app-name"
+)
+
+var (
+	// DefaultProperties is a map of all valid logs properties with corresponding default property values
+	DefaultProperties = map[string]string{
+		"vector-sink": "",
+	}
+
+	syslogSchemes = map[string]string{
+		"syslog":     "udp",
+		"syslog+udp": "udp",
+		"syslog+tcp": "tcp",
+		"syslog+tls": "tcp+tls",
+	}
+)
+
+// Sink is a destination the logs of an app are shipped to
+type Sink struct {
+	URI    *url.URL
+	Scheme string
+}
+
+// ParseSink validates a sink uri. syslog uris are shipped by the docker syslog log driver, while
+// tcp, udp, http and https uris are shipped by a vector sidecar
+func ParseSink(value string) (Sink, error) {
+	uri, err := url.Parse(value)
+	if err != nil {
+		return Sink{}, fmt.Errorf("Invalid vector-sink %s: %s", value, err.Error())
+	}
+
+	sink := Sink{URI: uri, Scheme: strings.ToLower(uri.Scheme)}
+	if uri.Hostname() == "" {
+		return sink, fmt.Errorf("Invalid vector-sink %s, a host must be specified", value)
+	}
+
+	switch sink.Scheme {
+	case "syslog", "syslog+udp", "syslog+tcp", "syslog+tls", "http", "https":
+	case "tcp", "udp":
+		if uri.Port() == "" {
+			return sink, fmt.Errorf("Invalid vector-sink %s, a port must be specified", value)
+		}
+	default:
+		return sink, fmt.Errorf("Invalid vector-sink scheme %s, valid schemes include: syslog, syslog+udp, syslog+tcp, syslog+tls, tcp, udp, http, https", uri.Scheme)
+	}
+	return sink, nil
+}
+
+// GetSink returns the sink the logs of an app are shipped to, if any
+func GetSink(appName string) (Sink, bool, error) {
+	value := common.PropertyGet("logs", appName, "vector-sink")
+	if value == "" {
+		return Sink{}, false, nil
+	}
+	sink, err := ParseSink(value)
+	return sink, err == nil, err
+}
+
+// UsesLogDriver returns whether the sink is shipped to by the docker log driver of app containers
+func (s Sink) UsesLogDriver() bool {
+	_, ok := syslogSchemes[s.Scheme]
+	return ok
+}
+
+// Shipper returns a description of what ships logs to the sink
+func (s Sink) Shipper() string {
+	if s.UsesLogDriver() {
+		return "syslog log driver"
+	}
+	return "vector sidecar"
+}
+
+// String returns the sink uri with any password masked
+func (s Sink) String() string {
+	uri := *s.URI
+	if _, ok := uri.User.Password(); ok {
+		uri.User = url.UserPassword(uri.User.Username(), "xxxxx")
+	}
+	return uri.String()
+}
+
+// DockerArgs returns the docker run arguments for an app container shipping its logs to the sink
+func (s Sink) DockerArgs(appName string, processType string, containerIndex string) []string {
+	if !s.UsesLogDriver() {
+		return []string{}
+	}
+
+	address := s.URI.Host
+	if s.URI.Port() == "" {
+		address = net.JoinHostPort(s.URI.Hostname(), "514")
+	}
+	tag := appName
+	for _, part := range []string{processType, containerIndex} {
+		if part != "" {
+			tag += "." + part
+		}
+	}
+	return []string{
+		"--log-driver=syslog",
+		fmt.Sprintf("--log-opt=syslog-address=%s://%s", syslogSchemes[s.Scheme], address),
+		"--log-opt=tag=" + tag,
+	}
+}
+
+// VectorConfig returns the configuration of a vector sidecar shipping the logs of an app to the sink
+func (s Sink) VectorConfig(appName string) string {
+	lines := []string{
+		"# generated by dokku, changes will be overwritten",
+		"[sources.app]",
+		`type = "docker"`,
+		fmt.Sprintf("include_labels = [%s]", strconv.Quote(AppLabel+"="+appName)),
+		"",
+		"[sinks.collector]",
+	}
+
+	if s.Scheme == "http" || s.Scheme == "https" {
+		lines = append(lines,
+			`type = "http"`,
+			`inputs = ["app"]`,
+			fmt.Sprintf("uri = %s", strconv.Quote(s.URI.String())),
+		)
+	} else {
+		lines = append(lines,
+			`type = "socket"`,
+			`inputs = ["app"]`,
+			fmt.Sprintf("mode = %s", strconv.Quote(s.Scheme)),
+			fmt.Sprintf("address = %s", strconv.Quote(s.URI.Host)),
+		)
+	}
+	lines = append(lines, `encoding.codec = "json"`)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ApplySink starts, restarts or removes the vector sidecar of an app to match its configured sink
+func ApplySink(appName string) error {
+	sink, ok, err := GetSink(appName)
+	if err != nil {
+		return err
+	}
+	if !ok || sink.UsesLogDriver() || !common.IsDeployed(appName) {
+		return RemoveVectorContainer(appName)
+	}
+	return StartVectorContainer(appName, sink)
+}
+
+// StartVectorContainer (re)creates the vector sidecar shipping the logs of an app to a sink
+func StartVectorContainer(appName string, sink Sink) error {
+	if err := removeVectorContainer(appName); err != nil {
+		return err
+	}
+
+	configDir := filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "vector")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	common.SetPermissions(configDir, 0755)
+
+	// the sink uri may embed credentials, so the config is only readable by its owner
+	configPath := filepath.Join(configDir, "vector.toml")
+	if err := ioutil.WriteFile(configPath, []byte(sink.VectorConfig(appName)), 0600); err != nil {
+		return fmt.Errorf("Unable to write vector config: %s", err.Error())
+	}
+	common.SetPermissions(configPath, 0600)
+
+	image := os.Getenv("DOKKU_VECTOR_IMAGE")
+	if image == "" {
+		image = VectorImage
+	}
+
+	configHostDir := filepath.Join(common.MustGetEnv("DOKKU_HOST_ROOT"), appName, "vector")
+	args := []string{"run", "--detach", "--name", vectorContainerName(appName), "--restart", "unless-stopped"}
+	args = append(args, strings.Fields(os.Getenv("DOKKU_GLOBAL_RUN_ARGS"))...)
+	args = append(args,
+		"--label", "com.dokku.vector-app="+appName,
+		"--volume", "/var/run/docker.sock:/var/run/docker.sock:ro",
+		"--volume", configHostDir+":/etc/vector:ro",
+		image, "--config", "/etc/vector/vector.toml",
+	)
+
+	if output, err := exec.Command("docker", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("Unable to start vector container: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RemoveVectorContainer removes the vector sidecar of an app and its config, if any
+func RemoveVectorContainer(appName string) error {
+	if err := removeVectorContainer(appName); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "vector"))
+}
+
+// GetVectorContainerStatus returns the state of the vector sidecar of an app, or missing when it does not exist
+func GetVectorContainerStatus(appName string) string {
+	status, err := common.DockerInspect(vectorContainerName(appName), "{{.State.Status}}")
+	if err != nil || status == "" {
+		return "missing"
+	}
+	return status
+}
+
+func vectorContainerName(appName string) string {
+	return "dokku.vector." + appName
+}
+
+func removeVectorContainer(appName string) error {
+	if GetVectorContainerStatus(appName) == "missing" {
+		return nil
+	}
+	if output, err := exec.Command("docker", "container", "rm", "--force", vectorContainerName(appName)).CombinedOutput(); err != nil {
+		return fmt.Errorf("Unable to remove vector container: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
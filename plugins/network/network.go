@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -73,14 +74,14 @@ func BuildConfig(appName string) {
 			port := GetContainerPort(appName, processType, isHerokuishContainer, containerID)
 
 			if ipAddress != "" {
-				_, err := sh.Command("plugn", "trigger", "network-write-ipaddr", appName, processType, containerIndexString, ipAddress).Output()
+				_, err := common.PlugnTriggerOutput("network-write-ipaddr", appName, processType, containerIndexString, ipAddress)
 				if err != nil {
 					common.LogWarn(err.Error())
 				}
 			}
 
 			if port != "" {
-				_, err := sh.Command("plugn", "trigger", "network-write-port", appName, processType, containerIndexString, port).Output()
+				_, err := common.PlugnTriggerOutput("network-write-port", appName, processType, containerIndexString, port)
 				if err != nil {
 					common.LogWarn(err.Error())
 				}
@@ -203,6 +204,38 @@ func PostAppCloneSetup(appName string) bool {
 	return success
 }
 
+// SetProperty validates and sets a network property for an app, clearing it when the value is empty
+func SetProperty(appName string, property string, value string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if err := ValidateProperty(property, value); err != nil {
+		return err
+	}
+
+	if value == "" {
+		return common.PropertyDelete("network", appName, property)
+	}
+	return common.PropertyWrite("network", appName, property, value)
+}
+
+// ValidateProperty returns an error if a property is not a network property or the value is invalid for it
+func ValidateProperty(property string, value string) error {
+	if _, ok := DefaultProperties[property]; !ok {
+		properties := []string{}
+		for p := range DefaultProperties {
+			properties = append(properties, p)
+		}
+		sort.Strings(properties)
+		return fmt.Errorf("Invalid property specified, valid properties include: %s", strings.Join(properties, ", "))
+	}
+
+	if property == "bind-all-interfaces" && value != "" && value != "true" && value != "false" {
+		return fmt.Errorf("Invalid value specified for %s, valid values include: true, false", property)
+	}
+	return nil
+}
+
 // ReportSingleApp is an internal function that displays the app report for one or more apps
 func ReportSingleApp(appName, infoFlag string) {
 	if err := common.VerifyAppName(appName); err != nil {
@@ -10,3 +10,11 @@ func TestNetworkGetDefaultValue(t *testing.T) {
 	RegisterTestingT(t)
 	Expect(GetDefaultValue("bind-all-interfaces")).To(Equal("false"))
 }
+
+func TestNetworkValidateProperty(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(ValidateProperty("bind-all-interfaces", "true")).To(Succeed())
+	Expect(ValidateProperty("bind-all-interfaces", "")).To(Succeed())
+	Expect(ValidateProperty("bind-all-interfaces", "yes")).NotTo(Succeed())
+	Expect(ValidateProperty("attach-post-create", "network")).To(MatchError(ContainSubstring("Invalid property specified")))
+}
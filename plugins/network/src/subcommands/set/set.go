@@ -17,6 +17,9 @@ func main() {
 	if property == "bind-all-interfaces" && value == "" {
 		value = "false"
 	}
+	if err := network.ValidateProperty(property, value); err != nil {
+		common.LogFail(err.Error())
+	}
 
 	common.CommandPropertySet("network", appName, property, value, network.DefaultProperties)
 }
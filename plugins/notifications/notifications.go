@@ -0,0 +1,520 @@
+package notifications
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// GlobalAppName is the property namespace holding webhooks delivered for every app
+	GlobalAppName = "_global_"
+
+	// MaxAttempts is the number of times a delivery is attempted before giving up
+	MaxAttempts = 3
+
+	// MaxDeliveryRecords is the number of delivery records kept per app
+	MaxDeliveryRecords = 500
+
+	// RequestTimeout is the timeout for a single delivery attempt
+	RequestTimeout = 10 * time.Second
+)
+
+var (
+	// Events is the list of events a webhook may subscribe to
+	Events = []string{"check-failed", "post-config-update", "post-delete", "post-deploy", "test"}
+
+	// retryDelay is the delay before the first retry, doubled for every subsequent attempt
+	retryDelay = 2 * time.Second
+
+	webhookNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// Webhook is a target notified of dokku events
+type Webhook struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+	Global bool     `json:"global"`
+}
+
+// Payload is the json body posted to a webhook
+type Payload struct {
+	ID        string            `json:"id"`
+	Event     string            `json:"event"`
+	App       string            `json:"app"`
+	Timestamp time.Time         `json:"timestamp"`
+	Hostname  string            `json:"hostname"`
+	Data      map[string]string `json:"data"`
+}
+
+// Job is a payload queued for delivery to a webhook
+type Job struct {
+	Webhook Webhook `json:"webhook"`
+	Payload Payload `json:"payload"`
+}
+
+// Delivery is the recorded outcome of posting a payload to a webhook
+type Delivery struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	App        string    `json:"app"`
+	Webhook    string    `json:"webhook"`
+	URL        string    `json:"url"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status-code"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error"`
+	DurationMs int64     `json:"duration-ms"`
+}
+
+// AddWebhook registers a webhook for an app or, when appName is the global namespace, for every app
+func AddWebhook(appName string, webhook Webhook) error {
+	if !webhookNameRegex.MatchString(webhook.Name) {
+		return fmt.Errorf("Invalid webhook name '%s', names must begin with a lowercase alphanumeric character and contain only lowercase alphanumeric characters, underscores and hyphens", webhook.Name)
+	}
+	if err := ValidateURL(webhook.URL); err != nil {
+		return err
+	}
+	if err := ValidateEvents(webhook.Events); err != nil {
+		return err
+	}
+	if WebhookExists(appName, webhook.Name) {
+		return fmt.Errorf("A webhook named %s already exists", webhook.Name)
+	}
+
+	webhook.Global = appName == GlobalAppName
+	b, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	if err := common.PropertyWrite("notifications", appName, "webhook-"+webhook.Name, string(b)); err != nil {
+		return err
+	}
+	return common.PropertyListAdd("notifications", appName, "webhooks", webhook.Name, 0)
+}
+
+// DestroyApp removes the webhooks and delivery log for an app
+func DestroyApp(appName string) error {
+	if err := common.PropertyDestroy("notifications", appName); err != nil {
+		return err
+	}
+	return os.RemoveAll(strings.TrimSuffix(getDeliveryLogPath(appName), "/deliveries.log"))
+}
+
+// GenerateSecret returns a random hex encoded signing secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Unable to generate secret: %s", err.Error())
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetDeliveries returns the most recent deliveries for an app, oldest first
+func GetDeliveries(appName string, limit int) ([]Delivery, error) {
+	deliveries := []Delivery{}
+	file, err := os.Open(getDeliveryLogPath(appName))
+	if os.IsNotExist(err) {
+		return deliveries, nil
+	}
+	if err != nil {
+		return deliveries, fmt.Errorf("Unable to read delivery log: %s", err.Error())
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var delivery Delivery
+		if err := json.Unmarshal(scanner.Bytes(), &delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := scanner.Err(); err != nil {
+		return deliveries, fmt.Errorf("Unable to read delivery log: %s", err.Error())
+	}
+
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[len(deliveries)-limit:]
+	}
+	return deliveries, nil
+}
+
+// GetWebhook returns a named webhook for an app
+func GetWebhook(appName string, name string) (Webhook, error) {
+	var webhook Webhook
+	if !WebhookExists(appName, name) {
+		return webhook, fmt.Errorf("No webhook named %s found", name)
+	}
+	if err := json.Unmarshal([]byte(common.PropertyGet("notifications", appName, "webhook-"+name)), &webhook); err != nil {
+		return webhook, fmt.Errorf("Unable to parse webhook %s: %s", name, err.Error())
+	}
+	return webhook, nil
+}
+
+// GetWebhooks returns the webhooks registered for an app
+func GetWebhooks(appName string) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	names, err := common.PropertyListGet("notifications", appName, "webhooks")
+	if err != nil {
+		return webhooks, err
+	}
+
+	for _, name := range names {
+		webhook, err := GetWebhook(appName, name)
+		if err != nil {
+			return webhooks, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+// Notify queues an event for delivery to every subscribed app and global webhook. Deliveries are
+// made by a background process so that retries never delay the command that triggered them
+func Notify(event string, appName string, data map[string]string) {
+	webhooks, err := GetWebhooks(GlobalAppName)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to read global webhooks: %s", err.Error()))
+	}
+	if appName != "" {
+		appWebhooks, err := GetWebhooks(appName)
+		if err != nil {
+			common.LogWarn(fmt.Sprintf("Unable to read webhooks for %s: %s", appName, err.Error()))
+		}
+		webhooks = append(webhooks, appWebhooks...)
+	}
+
+	jobs := []Job{}
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			jobs = append(jobs, Job{Webhook: webhook, Payload: NewPayload(event, appName, data)})
+		}
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	if err := deliverInBackground(jobs); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to queue %s notifications, delivering them now: %s", event, err.Error()))
+		deliverJobs(jobs)
+	}
+}
+
+// DeliverQueue delivers the jobs queued in a file by Notify, removing the file once it has been read
+func DeliverQueue(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	os.Remove(path)
+
+	jobs := []Job{}
+	if err := json.Unmarshal(b, &jobs); err != nil {
+		return fmt.Errorf("Unable to read notification queue %s: %s", path, err.Error())
+	}
+	deliverJobs(jobs)
+	return nil
+}
+
+// Deliver posts a payload to a webhook, retrying network errors and retryable status codes, and records the delivery
+func Deliver(webhook Webhook, payload Payload) Delivery {
+	startedAt := time.Now()
+	delivery := Delivery{
+		ID:      payload.ID,
+		Time:    startedAt.UTC(),
+		Event:   payload.Event,
+		App:     payload.App,
+		Webhook: webhook.Name,
+		URL:     MaskURL(webhook.URL),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	client := &http.Client{Timeout: RequestTimeout}
+	delay := retryDelay
+	for delivery.Attempts < MaxAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(delay)
+			delay = delay * 2
+		}
+		delivery.Attempts++
+
+		statusCode, err := post(client, webhook, payload, body)
+		delivery.StatusCode = statusCode
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Error = fmt.Sprintf("Unexpected status code %d", statusCode)
+		}
+		if err == nil && !isRetryable(statusCode) {
+			break
+		}
+	}
+	delivery.DurationMs = int64(time.Since(startedAt) / time.Millisecond)
+
+	logAppName := payload.App
+	if webhook.Global || logAppName == "" {
+		logAppName = GlobalAppName
+	}
+	// deliveries made in the background may finish after their app has been destroyed
+	if logAppName != GlobalAppName && common.VerifyAppName(logAppName) != nil {
+		return delivery
+	}
+	if err := recordDelivery(logAppName, delivery); err != nil {
+		common.LogWarn(err.Error())
+	}
+	return delivery
+}
+
+// NewPayload builds the payload for an event
+func NewPayload(event string, appName string, data map[string]string) Payload {
+	id := make([]byte, 16)
+	rand.Read(id)
+	hostname, _ := os.Hostname()
+	if data == nil {
+		data = map[string]string{}
+	}
+	return Payload{
+		ID:        hex.EncodeToString(id),
+		Event:     event,
+		App:       appName,
+		Timestamp: time.Now().UTC(),
+		Hostname:  hostname,
+		Data:      data,
+	}
+}
+
+// RemoveWebhook removes a named webhook from an app
+func RemoveWebhook(appName string, name string) error {
+	if !WebhookExists(appName, name) {
+		return fmt.Errorf("No webhook named %s found", name)
+	}
+	if err := common.PropertyListRemove("notifications", appName, "webhooks", name); err != nil {
+		return err
+	}
+	return common.PropertyDelete("notifications", appName, "webhook-"+name)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscribes returns true if the webhook should receive an event
+func (w Webhook) Subscribes(event string) bool {
+	if event == "test" || len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEvents returns an error if any event is unknown
+func ValidateEvents(events []string) error {
+	for _, event := range events {
+		valid := false
+		for _, e := range Events {
+			if e == event {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("Invalid event %s, valid events include: %s", event, strings.Join(Events, ", "))
+		}
+	}
+	return nil
+}
+
+// MaskURL returns a webhook url with its userinfo and query string masked, as either may carry a token
+func MaskURL(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return webhookURL
+	}
+
+	masked := u.Scheme + "://"
+	if u.User != nil {
+		masked += common.MaskedValue + "@"
+	}
+	masked += u.Host + u.EscapedPath()
+	if u.RawQuery != "" {
+		masked += "?" + common.MaskedValue
+	}
+	return masked
+}
+
+// ValidateURL returns an error if a webhook url is not an absolute http or https url
+func ValidateURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("Invalid webhook url %s, the url must begin with http:// or https://", webhookURL)
+	}
+	return nil
+}
+
+// WebhookExists returns true if a named webhook is registered for an app
+func WebhookExists(appName string, name string) bool {
+	return common.PropertyExists("notifications", appName, "webhook-"+name)
+}
+
+// deliverInBackground queues jobs in a file and starts a detached deliver process for them
+func deliverInBackground(jobs []Job) error {
+	deliverBin := getDeliverBin()
+	if !common.FileExists(deliverBin) {
+		return fmt.Errorf("%s does not exist", deliverBin)
+	}
+
+	queueDir := filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "notifications", "queue")
+	if err := os.MkdirAll(queueDir, 0755); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+	queueFile, err := ioutil.TempFile(queueDir, "jobs-")
+	if err != nil {
+		return err
+	}
+	_, err = queueFile.Write(b)
+	if closeErr := queueFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(queueFile.Name())
+		return err
+	}
+
+	cmd := exec.Command(deliverBin, queueFile.Name())
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		os.Remove(queueFile.Name())
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+func deliverJobs(jobs []Job) {
+	for _, job := range jobs {
+		delivery := Deliver(job.Webhook, job.Payload)
+		if !delivery.Success {
+			common.LogWarn(fmt.Sprintf("Unable to deliver %s notification to %s: %s", job.Payload.Event, job.Webhook.Name, delivery.Error))
+		}
+	}
+}
+
+// getDeliverBin returns the path to the deliver binary, which is installed alongside the plugin triggers
+func getDeliverBin() string {
+	return filepath.Join(filepath.Dir(os.Args[0]), "deliver")
+}
+
+func getDeliveryLogPath(appName string) string {
+	return strings.Join([]string{common.MustGetEnv("DOKKU_LIB_ROOT"), "data", "notifications", appName, "deliveries.log"}, "/")
+}
+
+func isRetryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func post(client *http.Client, webhook Webhook, payload Payload, body []byte) (int, error) {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dokku-notifications")
+	req.Header.Set("X-Dokku-Delivery", payload.ID)
+	req.Header.Set("X-Dokku-Event", payload.Event)
+	if webhook.Secret != "" {
+		req.Header.Set("X-Dokku-Signature", "sha256="+Sign(webhook.Secret, body))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = MaskURL(webhook.URL)
+		}
+		return 0, err
+	}
+	defer res.Body.Close()
+	ioutil.ReadAll(res.Body)
+	return res.StatusCode, nil
+}
+
+// recordDelivery appends a delivery to the app delivery log, keeping the most recent records
+func recordDelivery(appName string, delivery Delivery) error {
+	path := getDeliveryLogPath(appName)
+	if err := os.MkdirAll(strings.TrimSuffix(path, "/deliveries.log"), 0755); err != nil {
+		return fmt.Errorf("Unable to create delivery log directory: %s", err.Error())
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0664)
+	if err != nil {
+		return fmt.Errorf("Unable to lock delivery log: %s", err.Error())
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("Unable to lock delivery log: %s", err.Error())
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+	common.SetPermissions(path+".lock", 0664)
+
+	deliveries, err := GetDeliveries(appName, MaxDeliveryRecords-1)
+	if err != nil {
+		return err
+	}
+	deliveries = append(deliveries, delivery)
+
+	var b bytes.Buffer
+	for _, d := range deliveries {
+		line, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	if err := ioutil.WriteFile(path, b.Bytes(), 0640); err != nil {
+		return fmt.Errorf("Unable to write delivery log: %s", err.Error())
+	}
+	common.SetPermissions(path, 0640)
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	columnize "github.com/ryanuber/columnize"
+)
+
+const (
+	helpHeader = `Usage: dokku notifications[:COMMAND]
+
+Manages webhooks notified of app events
+
+Additional commands:`
+
+	helpContent = `
+    notifications:add [--global] [--events <event,...>] [--secret <secret>] [<app>] <name> <url>, Registers a webhook for an app or for every app
+    notifications:deliveries [--global] [--limit <n>] [--format json] [<app>], Lists recent webhook deliveries
+    notifications:list [--global] [--format json] [<app>], Lists the webhooks for an app or the global webhooks
+    notifications:remove [--global] [<app>] <name>, Removes a webhook
+    notifications:test [--global] [<app>] <name>, Sends a test notification to a webhook
+`
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd := flag.Arg(0)
+	switch cmd {
+	case "notifications", "notifications:help":
+		usage()
+	case "help":
+		command := common.NewShellCmd(fmt.Sprintf("ps -o command= %d", os.Getppid()))
+		command.ShowOutput = false
+		output, err := command.Output()
+
+		if err == nil && strings.Contains(string(output), "--all") {
+			fmt.Print(helpContent)
+		} else {
+			fmt.Print("\n    notifications, Manages webhooks notified of app events\n")
+		}
+	default:
+		dokkuNotImplementExitCode, err := strconv.Atoi(os.Getenv("DOKKU_NOT_IMPLEMENTED_EXIT"))
+		if err != nil {
+			fmt.Println("failed to retrieve DOKKU_NOT_IMPLEMENTED_EXIT environment variable")
+			dokkuNotImplementExitCode = 10
+		}
+		os.Exit(dokkuNotImplementExitCode)
+	}
+}
+
+func usage() {
+	config := columnize.DefaultConfig()
+	config.Delim = ","
+	config.Prefix = "    "
+	config.Empty = ""
+	content := strings.Split(helpContent, "\n")[1:]
+	fmt.Println(helpHeader)
+	fmt.Println(columnize.Format(content, config))
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// delivers the webhook notifications queued by a trigger, detached from the triggering command
+func main() {
+	flag.Parse()
+	queueFile := flag.Arg(0)
+
+	if err := notifications.DeliverQueue(queueFile); err != nil {
+		common.LogFail(err.Error())
+	}
+}
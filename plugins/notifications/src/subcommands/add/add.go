@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// registers a webhook for an app or for every app
+func main() {
+	args := flag.NewFlagSet("notifications:add", flag.ExitOnError)
+	global := args.Bool("global", false, "--global: notify for every app")
+	events := args.String("events", "", "--events: comma separated list of events to deliver")
+	secret := args.String("secret", "", "--secret: secret used to sign payloads")
+	args.Parse(os.Args[2:])
+
+	positional := args.Args()
+	appName := ""
+	if !*global && len(positional) > 0 {
+		appName, positional = positional[0], positional[1:]
+	}
+	name, webhookURL := "", ""
+	if len(positional) > 0 {
+		name = positional[0]
+	}
+	if len(positional) > 1 {
+		webhookURL = positional[1]
+	}
+
+	if err := notifications.CommandAdd(appName, *global, name, webhookURL, *events, *secret); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// lists recent webhook deliveries for an app or the global webhooks
+func main() {
+	args := flag.NewFlagSet("notifications:deliveries", flag.ExitOnError)
+	global := args.Bool("global", false, "--global: list global webhook deliveries")
+	limit := args.Int("limit", 20, "--limit: maximum number of deliveries to display")
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	args.Parse(os.Args[2:])
+
+	if err := notifications.CommandDeliveries(args.Arg(0), *global, *limit, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
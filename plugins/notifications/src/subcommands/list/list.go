@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// lists the webhooks for an app or the global webhooks
+func main() {
+	args := flag.NewFlagSet("notifications:list", flag.ExitOnError)
+	global := args.Bool("global", false, "--global: list global webhooks")
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	args.Parse(os.Args[2:])
+
+	if err := notifications.CommandList(args.Arg(0), *global, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
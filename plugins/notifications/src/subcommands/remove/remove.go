@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// removes a webhook
+func main() {
+	args := flag.NewFlagSet("notifications:remove", flag.ExitOnError)
+	global := args.Bool("global", false, "--global: remove a global webhook")
+	args.Parse(os.Args[2:])
+
+	appName, name := args.Arg(0), args.Arg(1)
+	if *global {
+		appName, name = "", args.Arg(0)
+	}
+
+	if err := notifications.CommandRemove(appName, *global, name); err != nil {
+		common.LogFail(err.Error())
+	}
+}
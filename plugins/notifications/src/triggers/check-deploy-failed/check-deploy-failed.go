@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// notifies webhooks of a container that failed to start during a deploy
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	notifications.Notify("check-failed", appName, map[string]string{
+		"container-id":    flag.Arg(1),
+		"process-type":    flag.Arg(2),
+		"container-index": flag.Arg(3),
+	})
+}
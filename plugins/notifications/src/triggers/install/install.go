@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the notifications plugin
+func main() {
+	if err := common.PropertySetup("notifications"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the notifications plugin: %s", err.Error()))
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// notifies webhooks of config changes, sending only the changed key names
+func main() {
+	// arguments are read directly as the app name is --global for global config changes
+	args := os.Args[1:]
+	if len(args) < 2 {
+		return
+	}
+
+	appName := args[0]
+	if appName == "--global" {
+		appName = ""
+	}
+
+	notifications.Notify("post-config-update", appName, map[string]string{
+		"operation": args[1],
+		"keys":      strings.Join(args[2:], ","),
+	})
+}
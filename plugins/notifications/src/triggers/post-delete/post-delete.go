@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// notifies webhooks of a deleted app and removes its webhooks
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	notifications.Notify("post-delete", appName, map[string]string{
+		"image-tag": flag.Arg(1),
+	})
+	if err := notifications.DestroyApp(appName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
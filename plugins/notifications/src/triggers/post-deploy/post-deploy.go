@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/notifications"
+)
+
+// notifies webhooks of a successful deploy
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+
+	notifications.Notify("post-deploy", appName, map[string]string{
+		"port":      flag.Arg(1),
+		"ip":        flag.Arg(2),
+		"image-tag": flag.Arg(3),
+	})
+}
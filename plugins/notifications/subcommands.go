@@ -0,0 +1,207 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandAdd implements notifications:add
+func CommandAdd(appName string, global bool, name string, webhookURL string, events string, secret string) error {
+	appName, err := getAppName(appName, global)
+	if err != nil {
+		return err
+	}
+	if name == "" || webhookURL == "" {
+		return errors.New("Please specify a webhook name and url")
+	}
+
+	generated := false
+	if secret == "" {
+		if secret, err = GenerateSecret(); err != nil {
+			return err
+		}
+		generated = true
+	}
+
+	webhook := Webhook{
+		Name:   name,
+		URL:    webhookURL,
+		Events: parseEvents(events),
+		Secret: secret,
+	}
+	if err := AddWebhook(appName, webhook); err != nil {
+		return err
+	}
+
+	common.LogInfo1(fmt.Sprintf("Added webhook %s for %s", name, displayApp(appName)))
+	if generated {
+		common.LogVerbose(fmt.Sprintf("Signing secret: %s", secret))
+	}
+	return nil
+}
+
+// CommandDeliveries implements notifications:deliveries
+func CommandDeliveries(appName string, global bool, limit int, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+	appName, err := getAppName(appName, global)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := GetDeliveries(appName, limit)
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		deliveries[i].URL = MaskURL(deliveries[i].URL)
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(deliveries)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("%s webhook deliveries", displayApp(appName)))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tWEBHOOK\tEVENT\tAPP\tATTEMPTS\tSTATUS\tRESULT")
+	for _, delivery := range deliveries {
+		result := "ok"
+		if !delivery.Success {
+			result = delivery.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", delivery.Time.Format(time.RFC3339), delivery.Webhook, delivery.Event, delivery.App, delivery.Attempts, delivery.StatusCode, result)
+	}
+	return w.Flush()
+}
+
+// CommandList implements notifications:list
+func CommandList(appName string, global bool, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+	appName, err := getAppName(appName, global)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := GetWebhooks(appName)
+	if err != nil {
+		return err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+		webhooks[i].URL = MaskURL(webhooks[i].URL)
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(webhooks)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("%s webhooks", displayApp(appName)))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tEVENTS")
+	for _, webhook := range webhooks {
+		events := "all"
+		if len(webhook.Events) > 0 {
+			events = strings.Join(webhook.Events, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", webhook.Name, webhook.URL, events)
+	}
+	return w.Flush()
+}
+
+// CommandRemove implements notifications:remove
+func CommandRemove(appName string, global bool, name string) error {
+	appName, err := getAppName(appName, global)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("Please specify a webhook name")
+	}
+	if err := RemoveWebhook(appName, name); err != nil {
+		return err
+	}
+	common.LogInfo1(fmt.Sprintf("Removed webhook %s from %s", name, displayApp(appName)))
+	return nil
+}
+
+// CommandTest implements notifications:test
+func CommandTest(appName string, global bool, name string) error {
+	appName, err := getAppName(appName, global)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("Please specify a webhook name")
+	}
+
+	webhook, err := GetWebhook(appName, name)
+	if err != nil {
+		return err
+	}
+
+	payloadApp := appName
+	if global {
+		payloadApp = ""
+	}
+	common.LogInfo1(fmt.Sprintf("Sending test notification to %s", MaskURL(webhook.URL)))
+	delivery := Deliver(webhook, NewPayload("test", payloadApp, map[string]string{}))
+	if !delivery.Success {
+		return fmt.Errorf("Delivery failed after %d attempts: %s", delivery.Attempts, delivery.Error)
+	}
+	common.LogVerbose(fmt.Sprintf("Delivered %s with status code %d", delivery.ID, delivery.StatusCode))
+	return nil
+}
+
+func displayApp(appName string) string {
+	if appName == GlobalAppName {
+		return "global"
+	}
+	return appName
+}
+
+// getAppName returns the property namespace for a command, verifying the app when not global
+func getAppName(appName string, global bool) (string, error) {
+	if global {
+		if appName != "" {
+			return "", errors.New("An app may not be specified along with --global")
+		}
+		return GlobalAppName, nil
+	}
+	if appName == "" {
+		return "", errors.New("Please specify an app or --global")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return "", err
+	}
+	return appName, nil
+}
+
+func parseEvents(events string) []string {
+	parsed := []string{}
+	for _, event := range strings.Split(events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			parsed = append(parsed, event)
+		}
+	}
+	return parsed
+}
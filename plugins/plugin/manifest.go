@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Manifest is the plugin.toml descriptor of a plugin
+type Manifest struct {
+	Name         string
+	Description  string
+	Version      string
+	Dokku        Constraint
+	Dependencies map[string]Constraint
+}
+
+// manifestFile is the layout of a plugin.toml file
+type manifestFile struct {
+	Plugin struct {
+		Description  string            `toml:"description"`
+		Version      string            `toml:"version"`
+		Dokku        string            `toml:"dokku"`
+		Dependencies map[string]string `toml:"dependencies"`
+	} `toml:"plugin"`
+}
+
+// DependencyNames returns the names of the plugins a plugin depends on, sorted
+func (m Manifest) DependencyNames() []string {
+	names := []string{}
+	for name := range m.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadManifest reads the plugin.toml descriptor of a plugin directory
+func ReadManifest(pluginName string, pluginPath string) (Manifest, error) {
+	manifest := Manifest{
+		Name:         pluginName,
+		Dependencies: map[string]Constraint{},
+	}
+
+	filename := filepath.Join(pluginPath, "plugin.toml")
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return manifest, nil
+	}
+
+	var file manifestFile
+	if _, err := toml.DecodeFile(filename, &file); err != nil {
+		return manifest, fmt.Errorf("Unable to read plugin.toml for %s: %s", pluginName, err.Error())
+	}
+
+	var err error
+	manifest.Description = file.Plugin.Description
+	manifest.Version = file.Plugin.Version
+	if manifest.Dokku, err = ParseConstraint(file.Plugin.Dokku); err != nil {
+		return manifest, fmt.Errorf("Invalid dokku constraint for %s: %s", pluginName, err.Error())
+	}
+	for dependency, value := range file.Plugin.Dependencies {
+		if manifest.Dependencies[dependency], err = ParseConstraint(value); err != nil {
+			return manifest, fmt.Errorf("Invalid constraint on %s for %s: %s", dependency, pluginName, err.Error())
+		}
+	}
+	return manifest, nil
+}
@@ -0,0 +1,326 @@
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+var (
+	pluginURLRegex = regexp.MustCompile(`^(https:|git|ssh:|file:)|\.(tar\.gz|tgz)$`)
+)
+
+// DokkuVersion returns the installed version of dokku
+func DokkuVersion() string {
+	return common.ReadFirstLine(filepath.Join(common.MustGetEnv("DOKKU_ROOT"), "VERSION"))
+}
+
+// EnabledPlugins returns the names of all enabled plugins
+func EnabledPlugins() ([]string, error) {
+	files, err := ioutil.ReadDir(common.MustGetEnv("PLUGIN_ENABLED_PATH"))
+	if err != nil {
+		return []string{}, err
+	}
+
+	plugins := []string{}
+	for _, file := range files {
+		if file.IsDir() || file.Mode()&os.ModeSymlink != 0 {
+			plugins = append(plugins, file.Name())
+		}
+	}
+	sort.Strings(plugins)
+	return plugins, nil
+}
+
+// IsPluginURL returns true if the value is a location a plugin may be downloaded from
+func IsPluginURL(value string) bool {
+	return pluginURLRegex.MatchString(value)
+}
+
+// PluginName returns the default name of a plugin downloaded from a url
+func PluginName(pluginURL string) string {
+	parts := strings.Split(pluginURL, "/")
+	name := strings.TrimSuffix(parts[len(parts)-1], ".git")
+	return strings.TrimPrefix(name, "dokku-")
+}
+
+// ResolveDependencies returns the plugins a plugin depends on, directly or transitively,
+// ordered so that every plugin appears after its own dependencies
+func ResolveDependencies(pluginName string) ([]string, error) {
+	resolved := []string{}
+	if err := resolveDependencies(pluginName, []string{}, map[string]bool{}, &resolved); err != nil {
+		return []string{}, err
+	}
+	return resolved[:len(resolved)-1], nil
+}
+
+// ValidatePlugin checks that the dokku version and every dependency of a plugin satisfy
+// its plugin.toml constraints, and that no enabled plugin requires a different version of it
+func ValidatePlugin(pluginName string) error {
+	manifest, err := readPluginManifest(pluginName)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+	if err := checkDokkuVersion(manifest); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	dependencies, err := ResolveDependencies(pluginName)
+	if err != nil {
+		return err
+	}
+	for _, dependency := range append([]string{pluginName}, dependencies...) {
+		dependencyManifest, err := readPluginManifest(dependency)
+		if err != nil {
+			return err
+		}
+		for _, name := range dependencyManifest.DependencyNames() {
+			if err := checkDependency(dependencyManifest, name); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	enabledPlugins, err := EnabledPlugins()
+	if err != nil {
+		return err
+	}
+	for _, enabledPlugin := range enabledPlugins {
+		if enabledPlugin == pluginName {
+			continue
+		}
+		dependentManifest, err := readPluginManifest(enabledPlugin)
+		if err != nil {
+			common.LogWarn(err.Error())
+			continue
+		}
+		if _, ok := dependentManifest.Dependencies[pluginName]; ok {
+			if err := checkDependency(dependentManifest, pluginName); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Plugin %s is incompatible with this installation:\n%s", pluginName, strings.Join(uniqueStrings(problems), "\n"))
+	}
+	return nil
+}
+
+// InstallPlugin downloads, validates and enables a plugin, then runs the install trigger.
+// The plugin is removed again if validation or the install trigger fails
+func InstallPlugin(pluginURL string, pluginName string, committish string) error {
+	if pluginName == "" {
+		pluginName = PluginName(pluginURL)
+	}
+	if pluginExists(pluginName) {
+		return fmt.Errorf("Plugin %s is already installed, use plugin:update to change its version", pluginName)
+	}
+
+	common.LogInfo1Quiet(fmt.Sprintf("Cloning plugin repo %s to %s", pluginURL, getPluginPath(pluginName)))
+	if err := plugn("install", pluginURL, pluginName); err != nil {
+		return fmt.Errorf("Unable to download plugin %s: %s", pluginName, err.Error())
+	}
+
+	rollback := func(reason error) error {
+		common.LogWarn(fmt.Sprintf("Removing plugin %s", pluginName))
+		plugn("disable", pluginName)
+		if err := plugn("uninstall", pluginName); err != nil {
+			os.RemoveAll(getPluginPath(pluginName))
+		}
+		return reason
+	}
+
+	if committish != "" {
+		if err := plugn("update", pluginName, committish); err != nil {
+			return rollback(fmt.Errorf("Unable to checkout %s for plugin %s: %s", committish, pluginName, err.Error()))
+		}
+	}
+	if err := ValidatePlugin(pluginName); err != nil {
+		return rollback(err)
+	}
+
+	if err := plugn("enable", pluginName); err != nil {
+		return rollback(fmt.Errorf("Unable to enable plugin %s: %s", pluginName, err.Error()))
+	}
+	common.LogInfo1Quiet(fmt.Sprintf("Plugin %s enabled", pluginName))
+
+	if err := common.PlugnTrigger("install"); err != nil {
+		return rollback(fmt.Errorf("Install trigger failed for plugin %s: %s", pluginName, err.Error()))
+	}
+	return nil
+}
+
+// UpdatePlugin updates a plugin to a committish, validates it and runs the install and update
+// triggers. The plugin is returned to its previous commit if any of these steps fail
+func UpdatePlugin(pluginName string, committish string) error {
+	if !pluginExists(pluginName) {
+		return fmt.Errorf("Plugin (%s) is not currently installed", pluginName)
+	}
+
+	previousCommit, err := gitHead(pluginName)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Plugin %s is not a git repository, it cannot be rolled back if the update fails", pluginName))
+	}
+
+	rollback := func(reason error) error {
+		if previousCommit == "" {
+			return reason
+		}
+		common.LogWarn(fmt.Sprintf("Rolling back plugin %s to %s", pluginName, previousCommit))
+		if err := plugn("update", pluginName, previousCommit); err != nil {
+			return fmt.Errorf("%s\nUnable to roll back plugin %s: %s", reason.Error(), pluginName, err.Error())
+		}
+		if err := common.PlugnTrigger("install"); err != nil {
+			common.LogWarn(fmt.Sprintf("Install trigger failed after rolling back plugin %s: %s", pluginName, err.Error()))
+		}
+		return reason
+	}
+
+	if err := plugn("update", pluginName, committish); err != nil {
+		return rollback(fmt.Errorf("Unable to update plugin %s: %s", pluginName, err.Error()))
+	}
+	if err := ValidatePlugin(pluginName); err != nil {
+		return rollback(err)
+	}
+	if err := common.PlugnTrigger("install"); err != nil {
+		return rollback(fmt.Errorf("Install trigger failed for plugin %s: %s", pluginName, err.Error()))
+	}
+	return nil
+}
+
+// PrimeBashCompletion writes the list of available commands to the bash-completion cache
+func PrimeBashCompletion() error {
+	common.LogInfo1Quiet("Priming bash-completion cache")
+	b, err := exec.Command("dokku", "--quiet", "help", "--all").Output()
+	if err != nil {
+		return err
+	}
+
+	commands := []string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		if fields := strings.Fields(line); strings.HasPrefix(line, "    ") && len(fields) > 0 {
+			commands = append(commands, fields[0])
+		}
+	}
+	sort.Strings(commands)
+	return ioutil.WriteFile("/var/cache/dokku-completion", []byte(strings.Join(commands, "\n")+"\n"), 0644)
+}
+
+func checkDependency(manifest Manifest, dependency string) error {
+	constraint := manifest.Dependencies[dependency]
+	if !isPluginEnabled(dependency) {
+		return fmt.Errorf("%s requires plugin %s %s, which is not installed and enabled", manifest.Name, dependency, constraint)
+	}
+
+	dependencyManifest, err := readPluginManifest(dependency)
+	if err != nil {
+		return err
+	}
+	if ok, err := constraint.Check(dependencyManifest.Version); err != nil || !ok {
+		return fmt.Errorf("%s requires plugin %s %s, but version %s is installed", manifest.Name, dependency, constraint, displayVersion(dependencyManifest.Version))
+	}
+	return nil
+}
+
+func checkDokkuVersion(manifest Manifest) error {
+	version := DokkuVersion()
+	if _, err := parseVersion(version); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to determine the dokku version (%s), skipping the dokku constraint of %s", version, manifest.Name))
+		return nil
+	}
+	if ok, err := manifest.Dokku.Check(version); err != nil || !ok {
+		return fmt.Errorf("%s requires dokku %s, but version %s is installed", manifest.Name, manifest.Dokku, version)
+	}
+	return nil
+}
+
+func displayVersion(version string) string {
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
+
+func getPluginPath(pluginName string) string {
+	return filepath.Join(common.MustGetEnv("PLUGIN_AVAILABLE_PATH"), pluginName)
+}
+
+func gitHead(pluginName string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = getPluginPath(pluginName)
+	b, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func isPluginEnabled(pluginName string) bool {
+	_, err := os.Stat(filepath.Join(common.MustGetEnv("PLUGIN_ENABLED_PATH"), pluginName))
+	return err == nil
+}
+
+func pluginExists(pluginName string) bool {
+	return common.DirectoryExists(getPluginPath(pluginName))
+}
+
+func plugn(args ...string) error {
+	cmd := exec.Command("plugn", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func readPluginManifest(pluginName string) (Manifest, error) {
+	return ReadManifest(pluginName, getPluginPath(pluginName))
+}
+
+// resolveDependencies walks the dependency graph depth-first, appending each plugin after its dependencies
+func resolveDependencies(pluginName string, path []string, visited map[string]bool, resolved *[]string) error {
+	for _, name := range path {
+		if name == pluginName {
+			return fmt.Errorf("Circular plugin dependency: %s", strings.Join(append(path, pluginName), " -> "))
+		}
+	}
+	if visited[pluginName] {
+		return nil
+	}
+
+	if pluginExists(pluginName) {
+		manifest, err := readPluginManifest(pluginName)
+		if err != nil {
+			return err
+		}
+		for _, dependency := range manifest.DependencyNames() {
+			if err := resolveDependencies(dependency, append(path, pluginName), visited, resolved); err != nil {
+				return err
+			}
+		}
+	}
+
+	visited[pluginName] = true
+	*resolved = append(*resolved, pluginName)
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
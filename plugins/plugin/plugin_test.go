@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+// setupTestRoots creates plugin directories and a plugn stub that installs
+// plugins by copying file:// urls and enables them with symlinks
+func setupTestRoots(t *testing.T) (string, func()) {
+	roots := testutil.SetupRoots(t)
+	root := roots.DokkuRoot
+	for _, dir := range []string{"available", "enabled", "sources"} {
+		Expect(os.MkdirAll(filepath.Join(root, dir), 0755)).To(Succeed())
+	}
+	Expect(ioutil.WriteFile(filepath.Join(root, "VERSION"), []byte("v0.15.5\n"), 0644)).To(Succeed())
+
+	plugn := `#!/bin/sh
+case "$1" in
+  install) cp -r "${2#file://}" "$PLUGIN_AVAILABLE_PATH/$3" ;;
+  enable) ln -s "$PLUGIN_AVAILABLE_PATH/$2" "$PLUGIN_ENABLED_PATH/$2" ;;
+  disable) rm -f "$PLUGIN_ENABLED_PATH/$2" ;;
+  uninstall) rm -rf "$PLUGIN_AVAILABLE_PATH/$2" ;;
+  trigger) [ ! -e "$PLUGIN_AVAILABLE_PATH/broken" ] ;;
+esac
+`
+	roots.WriteStub(t, "plugn", plugn)
+
+	os.Setenv("PLUGIN_AVAILABLE_PATH", filepath.Join(root, "available"))
+	os.Setenv("PLUGIN_ENABLED_PATH", filepath.Join(root, "enabled"))
+	return root, roots.Teardown
+}
+
+func writePlugin(dir string, name string, manifest string) {
+	Expect(os.MkdirAll(filepath.Join(dir, name), 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(dir, name, "plugin.toml"), []byte(manifest), 0644)).To(Succeed())
+}
+
+func enablePlugin(root string, name string, manifest string) {
+	writePlugin(filepath.Join(root, "available"), name, manifest)
+	Expect(os.Symlink(filepath.Join(root, "available", name), filepath.Join(root, "enabled", name))).To(Succeed())
+}
+
+func TestPluginReadManifest(t *testing.T) {
+	RegisterTestingT(t)
+	root, cleanup := setupTestRoots(t)
+	defer cleanup()
+
+	writePlugin(root, "postgres", `# a comment
+[plugin]
+description = "dokku postgres service plugin" # trailing comment
+version = '1.4.0'
+dokku = ">= 0.15.0, < 0.17.0"
+[plugin.dependencies]
+"service-common" = ">= 1.0"
+letsencrypt = "*"
+[plugin.config]
+enabled = true
+`)
+	manifest, err := ReadManifest("postgres", filepath.Join(root, "postgres"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(manifest.Description).To(Equal("dokku postgres service plugin"))
+	Expect(manifest.Version).To(Equal("1.4.0"))
+	Expect(manifest.Dokku.String()).To(Equal(">= 0.15.0, < 0.17.0"))
+	Expect(manifest.DependencyNames()).To(Equal([]string{"letsencrypt", "service-common"}))
+
+	manifest, err = ReadManifest("missing", filepath.Join(root, "missing"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(manifest.Dependencies).To(BeEmpty())
+
+	writePlugin(root, "invalid", "[plugin]\ndokku = \"~> 0.15\"\n")
+	_, err = ReadManifest("invalid", filepath.Join(root, "invalid"))
+	Expect(err).To(HaveOccurred())
+
+	writePlugin(root, "malformed", "[plugin\nversion = \"0.1.0\"\n")
+	_, err = ReadManifest("malformed", filepath.Join(root, "malformed"))
+	Expect(err).To(HaveOccurred())
+
+	writePlugin(root, "mistyped", "[plugin]\nversion = 1\n")
+	_, err = ReadManifest("mistyped", filepath.Join(root, "mistyped"))
+	Expect(err).To(HaveOccurred())
+}
+
+func TestPluginResolveDependencies(t *testing.T) {
+	RegisterTestingT(t)
+	root, cleanup := setupTestRoots(t)
+	defer cleanup()
+
+	enablePlugin(root, "a", "[plugin.dependencies]\nb = \"*\"\nc = \"*\"\n")
+	enablePlugin(root, "b", "[plugin.dependencies]\nc = \"*\"\n")
+	enablePlugin(root, "c", "[plugin]\nversion = \"1.0.0\"\n")
+	Expect(ResolveDependencies("a")).To(Equal([]string{"c", "b"}))
+	Expect(ResolveDependencies("c")).To(BeEmpty())
+
+	enablePlugin(root, "d", "[plugin.dependencies]\ne = \"*\"\n")
+	enablePlugin(root, "e", "[plugin.dependencies]\nd = \"*\"\n")
+	_, err := ResolveDependencies("d")
+	Expect(err).To(MatchError("Circular plugin dependency: d -> e -> d"))
+}
+
+func TestPluginValidatePlugin(t *testing.T) {
+	RegisterTestingT(t)
+	root, cleanup := setupTestRoots(t)
+	defer cleanup()
+
+	enablePlugin(root, "common", "[plugin]\nversion = \"1.2.0\"\n")
+	enablePlugin(root, "postgres", "[plugin]\ndokku = \">= 0.15.0\"\n[plugin.dependencies]\ncommon = \">= 1.0, < 2.0\"\n")
+	Expect(ValidatePlugin("postgres")).To(Succeed())
+
+	enablePlugin(root, "redis", "[plugin]\ndokku = \">= 0.16.0\"\n[plugin.dependencies]\ncommon = \">= 1.3\"\nletsencrypt = \"*\"\n")
+	err := ValidatePlugin("redis")
+	Expect(err).To(HaveOccurred())
+	Expect(err.Error()).To(ContainSubstring("redis requires dokku >= 0.16.0, but version v0.15.5 is installed"))
+	Expect(err.Error()).To(ContainSubstring("redis requires plugin common >= 1.3, but version 1.2.0 is installed"))
+	Expect(err.Error()).To(ContainSubstring("redis requires plugin letsencrypt *, which is not installed and enabled"))
+
+	writePlugin(filepath.Join(root, "available"), "common", "[plugin]\nversion = \"2.0.0\"\n")
+	err = ValidatePlugin("common")
+	Expect(err).To(HaveOccurred())
+	Expect(err.Error()).To(ContainSubstring("postgres requires plugin common >= 1.0, < 2.0, but version 2.0.0 is installed"))
+}
+
+func TestPluginInstallPlugin(t *testing.T) {
+	RegisterTestingT(t)
+	root, cleanup := setupTestRoots(t)
+	defer cleanup()
+
+	sources := filepath.Join(root, "sources")
+	writePlugin(sources, "dokku-postgres", "[plugin]\nversion = \"1.0.0\"\n[plugin.dependencies]\ncommon = \">= 1.0\"\n")
+	err := InstallPlugin("file://"+filepath.Join(sources, "dokku-postgres"), "", "")
+	Expect(err).To(MatchError(ContainSubstring("postgres requires plugin common >= 1.0, which is not installed and enabled")))
+	Expect(pluginExists("postgres")).To(BeFalse())
+	Expect(isPluginEnabled("postgres")).To(BeFalse())
+
+	enablePlugin(root, "common", "[plugin]\nversion = \"1.0.0\"\n")
+	Expect(InstallPlugin("file://"+filepath.Join(sources, "dokku-postgres"), "", "")).To(Succeed())
+	Expect(isPluginEnabled("postgres")).To(BeTrue())
+
+	err = InstallPlugin("file://"+filepath.Join(sources, "dokku-postgres"), "", "")
+	Expect(err).To(MatchError(ContainSubstring("already installed")))
+
+	writePlugin(sources, "broken", "[plugin]\nversion = \"1.0.0\"\n")
+	Expect(InstallPlugin("file://"+filepath.Join(sources, "broken"), "", "")).NotTo(Succeed())
+	Expect(pluginExists("broken")).To(BeFalse())
+	Expect(isPluginEnabled("broken")).To(BeFalse())
+}
+
+func TestPluginName(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(PluginName("https://github.com/dokku/dokku-postgres.git")).To(Equal("postgres"))
+	Expect(PluginName("git@github.com:dokku/dokku-redis.git")).To(Equal("redis"))
+	Expect(IsPluginURL("https://github.com/dokku/dokku-postgres.git")).To(BeTrue())
+	Expect(IsPluginURL("plugin.tgz")).To(BeTrue())
+	Expect(IsPluginURL("postgres")).To(BeFalse())
+}
+
+func TestPluginListTriggers(t *testing.T) {
+	RegisterTestingT(t)
+	root, cleanup := setupTestRoots(t)
+	defer cleanup()
+
+	enablePlugin(root, "00_dokku-standard", "")
+	enablePlugin(root, "apps", "")
+	for _, file := range []string{"00_dokku-standard/post-create", "00_dokku-standard/commands", "apps/post-create", "apps/post-delete", "apps/internal-functions", "apps/plugin.toml"} {
+		Expect(ioutil.WriteFile(filepath.Join(root, "available", file), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+	}
+	Expect(ioutil.WriteFile(filepath.Join(root, "available", "apps", "report"), []byte("#!/bin/sh\n"), 0644)).To(Succeed())
+
+	triggers, err := ListTriggers()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(triggers).To(Equal(map[string][]string{
+		"post-create": {"00_dokku-standard", "apps"},
+		"post-delete": {"apps"},
+	}))
+	Expect(TriggerNames(triggers)).To(Equal([]string{"post-create", "post-delete"}))
+}
+
+func TestPluginSetTriggerTracing(t *testing.T) {
+	RegisterTestingT(t)
+	root, cleanup := setupTestRoots(t)
+	defer cleanup()
+
+	tracePath := filepath.Join(root, ".dokkurc", "DOKKU_TRACE_TRIGGERS")
+	Expect(SetTriggerTracing(true)).To(Succeed())
+	b, err := ioutil.ReadFile(tracePath)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("export DOKKU_TRACE_TRIGGERS=1\n"))
+
+	Expect(SetTriggerTracing(false)).To(Succeed())
+	Expect(tracePath).NotTo(BeAnExistingFile())
+	Expect(SetTriggerTracing(false)).To(Succeed())
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// installs a plugin from a url and runs the install trigger for active plugins
+func main() {
+	args := flag.NewFlagSet("plugin:install", flag.ExitOnError)
+	core := args.Bool("core", false, "--core: only run the install trigger for core plugins")
+	committish := args.String("committish", "", "--committish: a tag, branch or commit to checkout")
+	pluginName := args.String("name", "", "--name: a custom name for the plugin")
+	common.ParseArgs(args, os.Args[2:])
+	pluginURL := args.Arg(0)
+
+	if err := plugin.CommandInstall(pluginURL, *core, *committish, *pluginName); err != nil {
+		common.LogFail(err.Error())
+	}
+}
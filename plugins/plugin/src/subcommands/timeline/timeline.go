@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// shows the trigger calls of a recorded trace as a timeline
+func main() {
+	args := flag.NewFlagSet("plugin:timeline", flag.ExitOnError)
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	common.ParseArgs(args, os.Args[2:])
+	traceID := args.Arg(0)
+
+	if err := plugin.CommandTimeline(traceID, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
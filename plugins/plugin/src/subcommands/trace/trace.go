@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// enables or disables recording trigger calls for every dokku command
+func main() {
+	flag.Parse()
+	value := flag.Arg(1)
+
+	if err := plugin.CommandTrace(value); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// lists the recorded trigger traces
+func main() {
+	if err := plugin.CommandTraces(); err != nil {
+		common.LogFail(err.Error())
+	}
+}
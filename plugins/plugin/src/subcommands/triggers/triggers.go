@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// lists every trigger and the enabled plugins implementing it
+func main() {
+	args := flag.NewFlagSet("plugin:triggers", flag.ExitOnError)
+	format := args.String("format", "stdout", "--format: output format (stdout, json)")
+	common.ParseArgs(args, os.Args[2:])
+	triggerName := args.Arg(0)
+
+	if err := plugin.CommandTriggers(triggerName, *format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// updates a plugin to an optional committish and runs the update trigger for active plugins
+func main() {
+	flag.Parse()
+	pluginName := flag.Arg(1)
+	committish := flag.Arg(2)
+
+	if err := plugin.CommandUpdate(pluginName, committish); err != nil {
+		common.LogFail(err.Error())
+	}
+}
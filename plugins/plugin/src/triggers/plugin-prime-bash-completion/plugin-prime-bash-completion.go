@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/plugin"
+)
+
+// writes the list of available commands to the bash-completion cache
+func main() {
+	if err := plugin.PrimeBashCompletion(); err != nil {
+		common.LogFail(err.Error())
+	}
+}
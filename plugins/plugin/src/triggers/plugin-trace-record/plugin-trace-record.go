@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// records a trigger call made from a shell script while trigger tracing is enabled
+func main() {
+	flag.Parse()
+	if !common.TriggerTracingEnabled() || flag.NArg() < 4 {
+		return
+	}
+
+	exitCode, err := strconv.Atoi(flag.Arg(0))
+	if err != nil {
+		exitCode = -1
+	}
+
+	startedAt := time.Now()
+	if nanoseconds, err := strconv.ParseInt(flag.Arg(1), 10, 64); err == nil {
+		startedAt = time.Unix(0, nanoseconds)
+	}
+
+	depth, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		depth = 0
+	}
+
+	trace := common.NewTriggerTrace(flag.Arg(3), flag.Args()[4:], startedAt, exitCode, depth)
+	if err := common.RecordTriggerTrace(trace); err != nil {
+		common.LogWarn(err.Error())
+	}
+}
@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CommandInstall implements plugin:install
+func CommandInstall(pluginURL string, core bool, committish string, pluginName string) error {
+	switch {
+	case core:
+		if pluginURL != "" {
+			common.LogInfo1Quiet("Cannot install additional core plugins, running core plugin install trigger")
+		}
+		os.Setenv("PLUGIN_PATH", common.MustGetEnv("PLUGIN_CORE_PATH"))
+		if err := common.PlugnTrigger("install"); err != nil {
+			return err
+		}
+	case IsPluginURL(pluginURL):
+		if err := InstallPlugin(pluginURL, pluginName, committish); err != nil {
+			return err
+		}
+	default:
+		if err := common.PlugnTrigger("install"); err != nil {
+			return err
+		}
+	}
+
+	return PrimeBashCompletion()
+}
+
+// CommandTimeline implements plugin:timeline
+func CommandTimeline(traceID string, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	if traceID == "" {
+		traceIDs, err := common.ListTriggerTraces()
+		if err != nil {
+			return err
+		}
+		if len(traceIDs) == 0 {
+			return errors.New("No trigger traces recorded, enable trigger tracing with plugin:trace on")
+		}
+		traceID = traceIDs[len(traceIDs)-1]
+	}
+
+	traces, err := common.ReadTriggerTrace(traceID)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(traces)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	command := ""
+	if len(traces) > 0 {
+		command = traces[0].Command
+	}
+	common.LogInfo2Quiet(fmt.Sprintf("Trigger timeline for %s: %s", traceID, command))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OFFSET\tDURATION\tEXIT\tTRIGGER")
+	for _, trace := range traces {
+		offset := trace.StartedAt.Sub(traces[0].StartedAt).Round(time.Millisecond)
+		duration := time.Duration(trace.DurationMs) * time.Millisecond
+		trigger := strings.TrimSpace(trace.Trigger + " " + strings.Join(trace.Args, " "))
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s%s\n", offset, duration, trace.ExitCode, strings.Repeat("  ", trace.Depth), trigger)
+	}
+	return w.Flush()
+}
+
+// CommandTrace implements plugin:trace
+func CommandTrace(value string) error {
+	switch value {
+	case "on":
+		common.LogInfo1("Enabling trigger tracing")
+		return SetTriggerTracing(true)
+	case "off":
+		common.LogInfo1("Disabling trigger tracing")
+		return SetTriggerTracing(false)
+	}
+	return errors.New("Valid trace options are [on/off]")
+}
+
+// CommandTraces implements plugin:traces
+func CommandTraces() error {
+	traceIDs, err := common.ListTriggerTraces()
+	if err != nil {
+		return err
+	}
+
+	common.LogInfo2Quiet("Trigger traces")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTRIGGERS\tDURATION\tCOMMAND")
+	for _, traceID := range traceIDs {
+		traces, err := common.ReadTriggerTrace(traceID)
+		if err != nil {
+			common.LogWarn(err.Error())
+			continue
+		}
+
+		command := ""
+		var startedAt, finishedAt time.Time
+		for i, trace := range traces {
+			end := trace.StartedAt.Add(time.Duration(trace.DurationMs) * time.Millisecond)
+			if i == 0 {
+				command, startedAt = trace.Command, trace.StartedAt
+			}
+			if end.After(finishedAt) {
+				finishedAt = end
+			}
+		}
+		duration := time.Duration(0)
+		if len(traces) > 0 {
+			duration = finishedAt.Sub(startedAt).Round(time.Millisecond)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", traceID, len(traces), duration, command)
+	}
+	return w.Flush()
+}
+
+// CommandTriggers implements plugin:triggers
+func CommandTriggers(triggerName string, format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	triggers, err := ListTriggers()
+	if err != nil {
+		return err
+	}
+	if triggerName != "" {
+		plugins, ok := triggers[triggerName]
+		if !ok {
+			return fmt.Errorf("No enabled plugin implements the %s trigger", triggerName)
+		}
+		triggers = map[string][]string{triggerName: plugins}
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(triggers)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	if triggerName != "" {
+		common.LogInfo2Quiet(fmt.Sprintf("%s trigger", triggerName))
+		for _, pluginName := range triggers[triggerName] {
+			fmt.Println(pluginName)
+		}
+		return nil
+	}
+
+	common.LogInfo2Quiet("Triggers")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRIGGER\tPLUGINS")
+	for _, name := range TriggerNames(triggers) {
+		fmt.Fprintf(w, "%s\t%s\n", name, strings.Join(triggers[name], " "))
+	}
+	return w.Flush()
+}
+
+// CommandUpdate implements plugin:update
+func CommandUpdate(pluginName string, committish string) error {
+	if pluginName != "" {
+		if err := UpdatePlugin(pluginName, committish); err != nil {
+			return err
+		}
+	}
+	if err := common.PlugnTrigger("update"); err != nil {
+		return err
+	}
+	return PrimeBashCompletion()
+}
@@ -0,0 +1,87 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+var (
+	// nonTriggerFiles are executables in a plugin directory that plugn never runs as triggers
+	nonTriggerFiles = map[string]bool{
+		"commands": true,
+		"hook":     true,
+	}
+)
+
+// ListTriggers returns every trigger implemented by an enabled plugin, mapped to the
+// plugins implementing it in the order plugn runs them
+func ListTriggers() (map[string][]string, error) {
+	triggers := map[string][]string{}
+	enabledPlugins, err := EnabledPlugins()
+	if err != nil {
+		return triggers, err
+	}
+
+	for _, pluginName := range enabledPlugins {
+		pluginPath := filepath.Join(common.MustGetEnv("PLUGIN_ENABLED_PATH"), pluginName)
+		files, err := ioutil.ReadDir(pluginPath)
+		if err != nil {
+			return triggers, err
+		}
+		for _, file := range files {
+			if isTriggerFile(filepath.Join(pluginPath, file.Name())) {
+				triggers[file.Name()] = append(triggers[file.Name()], pluginName)
+			}
+		}
+	}
+	return triggers, nil
+}
+
+// SetTriggerTracing enables or disables recording trigger calls for every dokku command
+func SetTriggerTracing(enabled bool) error {
+	dokkurcPath := filepath.Join(common.MustGetEnv("DOKKU_ROOT"), ".dokkurc")
+	tracePath := filepath.Join(dokkurcPath, "DOKKU_TRACE_TRIGGERS")
+	if !enabled {
+		if err := os.Remove(tracePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dokkurcPath, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(tracePath, []byte("export DOKKU_TRACE_TRIGGERS=1\n"), 0644); err != nil {
+		return err
+	}
+	common.SetPermissions(tracePath, 0644)
+	return nil
+}
+
+// TriggerNames returns the names of a set of triggers, sorted
+func TriggerNames(triggers map[string][]string) []string {
+	names := []string{}
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isTriggerFile(path string) bool {
+	name := filepath.Base(path)
+	if nonTriggerFiles[name] || strings.HasSuffix(name, "functions") || strings.Contains(name, ".") {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular() && info.Mode()&0111 != 0
+}
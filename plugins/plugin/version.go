@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	constraintRegex = regexp.MustCompile(`^(>=|<=|!=|=|>|<)?\s*v?([0-9][0-9A-Za-z.+-]*)$`)
+	versionRegex    = regexp.MustCompile(`^v?([0-9]+(\.[0-9]+)*)`)
+)
+
+// Constraint is a list of version requirements that must all be satisfied
+type Constraint struct {
+	raw     string
+	clauses []constraintClause
+}
+
+type constraintClause struct {
+	operator string
+	version  string
+}
+
+// CompareVersions compares two dotted numeric versions, returning -1, 0 or 1. Missing
+// components are treated as zero, and anything after the numeric components is ignored
+func CompareVersions(a string, b string) (int, error) {
+	aParts, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bParts, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := 0, 0
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		if aPart < bPart {
+			return -1, nil
+		}
+		if aPart > bPart {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// ParseConstraint parses a comma-separated list of version requirements, such as ">= 0.15.0, < 0.17.0"
+func ParseConstraint(value string) (Constraint, error) {
+	constraint := Constraint{raw: strings.TrimSpace(value)}
+	for _, clause := range strings.Split(value, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" || clause == "*" {
+			continue
+		}
+
+		matches := constraintRegex.FindStringSubmatch(clause)
+		if matches == nil {
+			return constraint, fmt.Errorf("Invalid version constraint %s", clause)
+		}
+		if _, err := parseVersion(matches[2]); err != nil {
+			return constraint, fmt.Errorf("Invalid version constraint %s", clause)
+		}
+
+		operator := matches[1]
+		if operator == "" {
+			operator = "="
+		}
+		constraint.clauses = append(constraint.clauses, constraintClause{operator: operator, version: matches[2]})
+	}
+	return constraint, nil
+}
+
+// Check returns true if the version satisfies every requirement of the constraint
+func (c Constraint) Check(version string) (bool, error) {
+	for _, clause := range c.clauses {
+		result, err := CompareVersions(version, clause.version)
+		if err != nil {
+			return false, err
+		}
+
+		satisfied := false
+		switch clause.operator {
+		case "=":
+			satisfied = result == 0
+		case "!=":
+			satisfied = result != 0
+		case ">":
+			satisfied = result > 0
+		case ">=":
+			satisfied = result >= 0
+		case "<":
+			satisfied = result < 0
+		case "<=":
+			satisfied = result <= 0
+		}
+		if !satisfied {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// String returns the constraint as written in plugin.toml
+func (c Constraint) String() string {
+	if c.raw == "" {
+		return "*"
+	}
+	return c.raw
+}
+
+func parseVersion(version string) ([]int, error) {
+	matches := versionRegex.FindStringSubmatch(strings.TrimSpace(version))
+	if matches == nil {
+		return []int{}, fmt.Errorf("Invalid version %s", version)
+	}
+
+	parts := []int{}
+	for _, part := range strings.Split(matches[1], ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return parts, fmt.Errorf("Invalid version %s", version)
+		}
+		parts = append(parts, number)
+	}
+	return parts, nil
+}
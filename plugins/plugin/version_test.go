@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPluginCompareVersions(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(CompareVersions("0.15.5", "0.15.5")).To(Equal(0))
+	Expect(CompareVersions("v0.15", "0.15.0")).To(Equal(0))
+	Expect(CompareVersions("0.9.0", "0.15.0")).To(Equal(-1))
+	Expect(CompareVersions("1.0.0", "0.15.0")).To(Equal(1))
+	Expect(CompareVersions("0.16.0-rc1", "0.16.0")).To(Equal(0))
+
+	_, err := CompareVersions("master", "0.15.0")
+	Expect(err).To(HaveOccurred())
+}
+
+func TestPluginConstraints(t *testing.T) {
+	RegisterTestingT(t)
+
+	constraint, err := ParseConstraint(">= 0.15.0, < 0.17.0")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(constraint.String()).To(Equal(">= 0.15.0, < 0.17.0"))
+	Expect(constraint.Check("0.15.0")).To(BeTrue())
+	Expect(constraint.Check("0.16.4")).To(BeTrue())
+	Expect(constraint.Check("0.17.0")).To(BeFalse())
+	Expect(constraint.Check("0.14.9")).To(BeFalse())
+
+	constraint, err = ParseConstraint("1.2")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(constraint.Check("1.2.0")).To(BeTrue())
+	Expect(constraint.Check("1.2.1")).To(BeFalse())
+
+	constraint, err = ParseConstraint("")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(constraint.String()).To(Equal("*"))
+	Expect(constraint.Check("0.1.0")).To(BeTrue())
+
+	_, err = ParseConstraint("~> 1.0")
+	Expect(err).To(HaveOccurred())
+	_, err = ParseConstraint(">= latest")
+	Expect(err).To(HaveOccurred())
+}
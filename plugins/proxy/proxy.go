@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"strings"
+
 	"github.com/dokku/dokku/plugins/common"
 	"github.com/dokku/dokku/plugins/config"
 )
@@ -19,3 +21,8 @@ func IsAppProxyEnabled(appName string) bool {
 	}
 	return proxyEnabled
 }
+
+// GetPortMap returns the proxy port mappings of an app, each in the form scheme:host-port:container-port
+func GetPortMap(appName string) []string {
+	return strings.Fields(config.GetWithDefault(appName, "DOKKU_PROXY_PORT_MAP", ""))
+}
@@ -0,0 +1,140 @@
+package ps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+var (
+	procfileLineRegex = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+)
+
+// GetScale returns the number of containers declared for each process type of an app
+func GetScale(appName string) (map[string]int, error) {
+	scale := map[string]int{}
+	lines, err := common.FileToSlice(filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "DOKKU_SCALE"))
+	if os.IsNotExist(err) {
+		return scale, nil
+	}
+	if err != nil {
+		return scale, err
+	}
+
+	for _, line := range lines {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		count, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return scale, fmt.Errorf("Invalid scale for process type %s: %s", parts[0], parts[1])
+		}
+		scale[parts[0]] = count
+	}
+	return scale, nil
+}
+
+// SetScale validates and writes the number of containers for one or more process types of an app,
+// leaving the scale of other process types unchanged. The app must be restarted to apply the change
+func SetScale(appName string, scale map[string]int) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	processTypes, err := getProcessTypes(appName)
+	if err != nil {
+		return err
+	}
+	for processType, count := range scale {
+		if count < 0 {
+			return fmt.Errorf("Invalid scale for process type %s, the value must not be negative", processType)
+		}
+		if count > 0 && processTypes != nil && !processTypes[processType] {
+			return fmt.Errorf("%s is not a valid process name", processType)
+		}
+	}
+
+	current, err := GetScale(appName)
+	if err != nil {
+		return err
+	}
+	lines := []string{}
+	filename := filepath.Join(common.MustGetEnv("DOKKU_ROOT"), appName, "DOKKU_SCALE")
+	if common.FileExists(filename) {
+		if lines, err = common.FileToSlice(filename); err != nil {
+			return err
+		}
+	}
+
+	changed := []string{}
+	for processType := range scale {
+		changed = append(changed, processType)
+	}
+	sort.Strings(changed)
+	for _, processType := range changed {
+		common.LogInfo1Quiet(fmt.Sprintf("Scaling %s:%s to %d", appName, processType, scale[processType]))
+		line := fmt.Sprintf("%s=%d", processType, scale[processType])
+		if _, ok := current[processType]; !ok {
+			lines = append(lines, line)
+			continue
+		}
+		for i := range lines {
+			if strings.HasPrefix(lines[i], processType+"=") {
+				lines[i] = line
+			}
+		}
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	common.SetPermissions(filename, 0644)
+	return nil
+}
+
+// getProcessTypes returns the process types declared in the Procfile of the deployed image of an
+// app, or nil when the app has not been deployed or its image has no Procfile
+func getProcessTypes(appName string) (map[string]bool, error) {
+	if !common.IsDeployed(appName) {
+		return nil, nil
+	}
+
+	tmpWorkDir, err := ioutil.TempDir("", "dokku_procfile")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpWorkDir)
+
+	procfilePath := filepath.Join(tmpWorkDir, "Procfile")
+	image := common.GetAppImageName(appName, "", "")
+	if err := common.CopyFromImage(image, "Procfile", procfilePath); err != nil || !common.FileExists(procfilePath) {
+		return nil, nil
+	}
+
+	lines, err := common.FileToSlice(procfilePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessTypes(lines), nil
+}
+
+func parseProcessTypes(lines []string) map[string]bool {
+	processTypes := map[string]bool{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if matches := procfileLineRegex.FindStringSubmatch(line); matches != nil {
+			processTypes[matches[1]] = true
+		}
+	}
+	return processTypes
+}
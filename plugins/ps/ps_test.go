@@ -0,0 +1,51 @@
+package ps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+)
+
+func TestPsGetScale(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	roots.CreateApps(t, "my-app")
+	dokkuRoot := roots.DokkuRoot
+
+	Expect(GetScale("my-app")).To(Equal(map[string]int{}))
+
+	Expect(ioutil.WriteFile(filepath.Join(dokkuRoot, "my-app", "DOKKU_SCALE"), []byte("web=2\nworker=0\n\n"), 0644)).To(Succeed())
+	Expect(GetScale("my-app")).To(Equal(map[string]int{"web": 2, "worker": 0}))
+
+	Expect(ioutil.WriteFile(filepath.Join(dokkuRoot, "my-app", "DOKKU_SCALE"), []byte("web=two\n"), 0644)).To(Succeed())
+	_, err := GetScale("my-app")
+	Expect(err).To(HaveOccurred())
+}
+
+func TestPsSetScale(t *testing.T) {
+	RegisterTestingT(t)
+	roots := testutil.SetupRoots(t)
+	defer roots.Teardown()
+	roots.CreateApps(t, "my-app")
+	dokkuRoot := roots.DokkuRoot
+
+	Expect(SetScale("my-app", map[string]int{"web": 2})).To(Succeed())
+	Expect(GetScale("my-app")).To(Equal(map[string]int{"web": 2}))
+
+	Expect(SetScale("my-app", map[string]int{"worker": 1, "web": 0})).To(Succeed())
+	b, err := ioutil.ReadFile(filepath.Join(dokkuRoot, "my-app", "DOKKU_SCALE"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("web=0\nworker=1\n"))
+
+	Expect(SetScale("my-app", map[string]int{"web": -1})).NotTo(Succeed())
+	Expect(SetScale("missing-app", map[string]int{"web": 1})).NotTo(Succeed())
+}
+
+func TestPsParseProcessTypes(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(parseProcessTypes([]string{"web: npm start", "# worker: npm run worker", "release:npm run migrate", "", "invalid"})).To(Equal(map[string]bool{"web": true, "release": true}))
+}
@@ -25,6 +25,13 @@ func main() {
 	dokkuGlobalRunArgs := common.MustGetEnv("DOKKU_GLOBAL_RUN_ARGS")
 	image := common.GetDeployingAppImageName(appName, "", "")
 	if info, _ := os.Stat(cacheDir); info != nil && info.IsDir() {
+		// buildkit caches are written on the host rather than within a build container
+		for _, buildkitCacheDir := range []string{"buildkit", "buildkit.new"} {
+			if err := os.RemoveAll(strings.Join([]string{cacheDir, buildkitCacheDir}, "/")); err != nil {
+				common.LogFail(err.Error())
+			}
+		}
+
 		purgeCacheCmd := common.NewShellCmd(strings.Join([]string{"docker run --rm", dokkuGlobalRunArgs,
 			"-v", strings.Join([]string{cacheHostDir, ":/cache"}, ""), image,
 			`find /cache -depth -mindepth 1 -maxdepth 1 -exec rm -Rf {} ;`}, " "))
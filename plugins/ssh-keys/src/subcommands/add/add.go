@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	sshkeys "github.com/dokku/dokku/plugins/ssh-keys"
+)
+
+// adds a new public key by pipe or path
+func main() {
+	args := flag.NewFlagSet("ssh-keys:add", flag.ExitOnError)
+	expires := args.String("expires", "", "expires: a date, timestamp or duration after which the key is denied access")
+	args.Parse(os.Args[2:])
+	name := args.Arg(0)
+	keyFile := args.Arg(1)
+
+	if err := sshkeys.CommandAdd(name, keyFile, *expires); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	sshkeys "github.com/dokku/dokku/plugins/ssh-keys"
+)
+
+// lists all authorized dokku public ssh keys
+func main() {
+	args := flag.NewFlagSet("ssh-keys:list", flag.ExitOnError)
+	format := args.String("format", "stdout", "format: [ stdout | json ] which format to output keys as")
+	args.Parse(os.Args[2:])
+
+	if err := sshkeys.CommandList(*format); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	sshkeys "github.com/dokku/dokku/plugins/ssh-keys"
+)
+
+// removes a public ssh key by name
+func main() {
+	args := flag.NewFlagSet("ssh-keys:remove", flag.ExitOnError)
+	args.Parse(os.Args[2:])
+	name := args.Arg(0)
+
+	if err := sshkeys.CommandRemove(name); err != nil {
+		common.LogFail(err.Error())
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// runs the install step for the ssh-keys plugin
+func main() {
+	if err := common.PropertySetup("ssh-keys"); err != nil {
+		common.LogFail(fmt.Sprintf("Unable to install the ssh-keys plugin: %s", err.Error()))
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/auth"
+	"github.com/dokku/dokku/plugins/common"
+	sshkeys "github.com/dokku/dokku/plugins/ssh-keys"
+)
+
+// denies expired ssh keys and restricts key management to admins
+func main() {
+	flag.Parse()
+	sshUser := flag.Arg(0)
+	sshName := flag.Arg(1)
+	command := flag.Arg(2)
+
+	// internal dokku invocations were authorized as part of the original command
+	if auth.IsAuthorizedInvocation() || sshUser == "root" {
+		return
+	}
+
+	if err := sshkeys.CheckKeyExpiry(sshName); err != nil {
+		common.LogFail(err.Error())
+	}
+
+	// the auth plugin decides who may manage keys once access control is enabled
+	if (command == "ssh-keys:add" || command == "ssh-keys:remove") && !auth.IsEnabled() && !strings.Contains(sshName, "admin") {
+		common.LogFail("You must be root, or a dokku admin, to execute this command")
+	}
+}
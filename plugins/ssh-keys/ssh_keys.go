@@ -0,0 +1,325 @@
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// MinimumRSABits is the smallest rsa modulus accepted for new keys
+	MinimumRSABits = 2048
+
+	// globalAppName is the property namespace holding key metadata
+	globalAppName = "_global_"
+)
+
+var (
+	fingerprintRegex = regexp.MustCompile(`FINGERPRINT=(\S+)`)
+	keyNameRegex     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+	nameRegex        = regexp.MustCompile(`NAME=\\?"([^"\\]*)\\?"`)
+)
+
+// Key is a single entry in the dokku user's authorized_keys file
+type Key struct {
+	Name        string        `json:"name"`
+	Fingerprint string        `json:"fingerprint"`
+	Type        string        `json:"type"`
+	Bits        int           `json:"bits"`
+	Comment     string        `json:"comment"`
+	AllowedKeys string        `json:"allowed-keys"`
+	ExpiresAt   *time.Time    `json:"expires-at"`
+	Expired     bool          `json:"expired"`
+	PublicKey   ssh.PublicKey `json:"-"`
+
+	// sshcommandFingerprint is the fingerprint recorded by sshcommand when the key was added
+	sshcommandFingerprint string
+}
+
+// AddKey validates a public key and adds it to the authorized_keys file under the given name,
+// returning the key fingerprint
+func AddKey(name string, contents string, expiresAt *time.Time) (string, error) {
+	if !keyNameRegex.MatchString(name) {
+		return "", fmt.Errorf("Invalid key name '%s', names must begin with an alphanumeric character and contain only alphanumeric characters, periods, underscores, at signs and hyphens", name)
+	}
+
+	publicKey, err := ParsePublicKey(contents)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateKey(publicKey); err != nil {
+		return "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", errors.New("Expiry date must be in the future")
+	}
+
+	keys, err := GetKeys()
+	if err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			return "", fmt.Errorf("Duplicate key name %s, please choose a different name", name)
+		}
+	}
+
+	command := common.NewShellCmd(fmt.Sprintf("sshcommand acl-add dokku %s", name))
+	command.ShowOutput = false
+	command.Command.Stdin = strings.NewReader(strings.TrimSpace(contents) + "\n")
+	if output, err := command.Output(); err != nil {
+		return "", fmt.Errorf("sshcommand returned an error: %s", strings.TrimSpace(string(output)))
+	}
+
+	if expiresAt != nil {
+		if err := setExpiry(name, *expiresAt); err != nil {
+			return "", err
+		}
+	}
+	return ssh.FingerprintSHA256(publicKey), nil
+}
+
+// CheckKeyExpiry returns an error if the named key has expired
+func CheckKeyExpiry(name string) error {
+	expiresAt, ok := GetExpiry(name)
+	if !ok || time.Now().Before(expiresAt) {
+		return nil
+	}
+	return fmt.Errorf("The ssh key %s expired at %s", name, expiresAt.Format(time.RFC3339))
+}
+
+// GetAuthorizedKeysPath returns the path to the dokku user's authorized_keys file
+func GetAuthorizedKeysPath() string {
+	return strings.Join([]string{common.MustGetEnv("DOKKU_ROOT"), ".ssh", "authorized_keys"}, "/")
+}
+
+// GetExpiry returns the expiry time for a named key, if one is set
+func GetExpiry(name string) (time.Time, bool) {
+	lines, err := common.PropertyListGet("ssh-keys", globalAppName, "expires")
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) != 2 || parts[0] != name {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, parts[1])
+		if err != nil {
+			return time.Time{}, false
+		}
+		return expiresAt, true
+	}
+	return time.Time{}, false
+}
+
+// GetKeys returns every key in the authorized_keys file along with its expiry
+func GetKeys() ([]Key, error) {
+	path := GetAuthorizedKeysPath()
+	if !common.FileExists(path) {
+		return []Key{}, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []Key{}, fmt.Errorf("Unable to read %s: %s", path, err.Error())
+	}
+
+	keys, err := ParseAuthorizedKeys(data)
+	if err != nil {
+		return keys, fmt.Errorf("%s %s", path, err.Error())
+	}
+
+	now := time.Now()
+	for i := range keys {
+		if expiresAt, ok := GetExpiry(keys[i].Name); ok {
+			keys[i].ExpiresAt = &expiresAt
+			keys[i].Expired = !now.Before(expiresAt)
+		}
+	}
+	return keys, nil
+}
+
+// ParseAuthorizedKeys parses the contents of an authorized_keys file, returning an error naming the first invalid line
+func ParseAuthorizedKeys(data []byte) ([]Key, error) {
+	keys := []Key{}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		publicKey, comment, options, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			return keys, fmt.Errorf("line %d is not a valid ssh public key", i+1)
+		}
+
+		key := Key{
+			Fingerprint: ssh.FingerprintSHA256(publicKey),
+			Type:        publicKey.Type(),
+			Bits:        keyBits(publicKey),
+			Comment:     comment,
+			PublicKey:   publicKey,
+		}
+
+		allowedKeys := []string{}
+		for _, option := range options {
+			if !strings.HasPrefix(option, "command=") {
+				allowedKeys = append(allowedKeys, option)
+				continue
+			}
+			if match := nameRegex.FindStringSubmatch(option); match != nil {
+				key.Name = match[1]
+			}
+			if match := fingerprintRegex.FindStringSubmatch(option); match != nil {
+				key.sshcommandFingerprint = match[1]
+			}
+		}
+		key.AllowedKeys = strings.Join(allowedKeys, ",")
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParseExpiry parses an expiry given as a date, an RFC 3339 timestamp or a duration from now such as 90d or 12h
+func ParseExpiry(value string) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days > 0 {
+			return time.Now().AddDate(0, 0, days).UTC().Truncate(time.Second), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return time.Now().Add(duration).UTC().Truncate(time.Second), nil
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, value); err == nil {
+		return expiresAt.UTC(), nil
+	}
+	if expiresAt, err := time.Parse("2006-01-02", value); err == nil {
+		return expiresAt.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid expiry %s, specify a date (2006-01-02), a timestamp (2006-01-02T15:04:05Z) or a duration (90d, 12h)", value)
+}
+
+// ParsePublicKey parses exactly one public key in authorized_keys format
+func ParsePublicKey(contents string) (ssh.PublicKey, error) {
+	contents = strings.TrimSpace(contents)
+	if contents == "" {
+		return nil, errors.New("No ssh public key provided")
+	}
+	if strings.Contains(contents, "\n") {
+		return nil, errors.New("Too many keys provided, set one per invocation of dokku ssh-keys:add <NAME> <KEY_FILE>")
+	}
+
+	publicKey, _, options, _, err := ssh.ParseAuthorizedKey([]byte(contents))
+	if err != nil {
+		return nil, errors.New("Key is not a valid ssh public key")
+	}
+	if len(options) > 0 {
+		return nil, errors.New("Key must not contain authorized_keys options")
+	}
+	return publicKey, nil
+}
+
+// RemoveKey removes a named key from the authorized_keys file
+func RemoveKey(name string) error {
+	keys, err := GetKeys()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, key := range keys {
+		if key.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("No ssh key found with name %s", name)
+	}
+
+	command := common.NewShellCmd(fmt.Sprintf("sshcommand acl-remove dokku %s", name))
+	command.ShowOutput = false
+	if output, err := command.Output(); err != nil {
+		return fmt.Errorf("sshcommand returned an error: %s", strings.TrimSpace(string(output)))
+	}
+	return removeExpiry(name)
+}
+
+// ValidateKey returns an error if a public key uses a weak algorithm or key size
+func ValidateKey(publicKey ssh.PublicKey) error {
+	switch publicKey.Type() {
+	case ssh.KeyAlgoDSA:
+		return errors.New("DSA keys are not supported as they are limited to 1024 bits, please use an ed25519, ecdsa or rsa key")
+	case ssh.KeyAlgoRSA:
+		if bits := keyBits(publicKey); bits < MinimumRSABits {
+			return fmt.Errorf("RSA keys must be at least %d bits, the provided key is %d bits", MinimumRSABits, bits)
+		}
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256:
+	default:
+		return fmt.Errorf("Unsupported ssh key type %s", publicKey.Type())
+	}
+	return nil
+}
+
+// DisplayFingerprint returns the fingerprint recorded by sshcommand, falling back to the computed SHA256 fingerprint
+func (k Key) DisplayFingerprint() string {
+	if k.sshcommandFingerprint != "" {
+		return k.sshcommandFingerprint
+	}
+	return k.Fingerprint
+}
+
+func keyBits(publicKey ssh.PublicKey) int {
+	cryptoPublicKey, ok := publicKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return 0
+	}
+
+	switch key := cryptoPublicKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	}
+
+	if strings.Contains(publicKey.Type(), "ed25519") {
+		return 256
+	}
+	return 0
+}
+
+func removeExpiry(name string) error {
+	lines, err := common.PropertyListGet("ssh-keys", globalAppName, "expires")
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 2 && parts[0] == name {
+			continue
+		}
+		fmt.Fprintln(&b, line)
+	}
+	return common.PropertyWrite("ssh-keys", globalAppName, "expires", b.String())
+}
+
+func setExpiry(name string, expiresAt time.Time) error {
+	if err := removeExpiry(name); err != nil {
+		return err
+	}
+	return common.PropertyListAdd("ssh-keys", globalAppName, "expires", fmt.Sprintf("%s %s", name, expiresAt.UTC().Format(time.RFC3339)), 0)
+}
@@ -0,0 +1,165 @@
+package sshkeys
+
+import (
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common/testutil"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+func generateTestKey(key interface{}) ssh.PublicKey {
+	publicKey, err := ssh.NewPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return publicKey
+}
+
+func authorizedKeyLine(publicKey ssh.PublicKey, comment string) string {
+	return fmt.Sprintf("%s %s", strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))), comment)
+}
+
+func TestSSHKeysParseAuthorizedKeys(t *testing.T) {
+	RegisterTestingT(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).NotTo(HaveOccurred())
+	rsaPublicKey := generateTestKey(&rsaKey.PublicKey)
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	edPublicKey := generateTestKey(edPublic)
+
+	data := fmt.Sprintf("command=\"FINGERPRINT=SHA256:recorded NAME=\\\"admin\\\" `cat /home/dokku/.sshcommand` $SSH_ORIGINAL_COMMAND\",no-agent-forwarding,no-user-rc,no-X11-forwarding,no-port-forwarding %s\n\n# comment\n%s\n",
+		authorizedKeyLine(rsaPublicKey, "admin@laptop"),
+		authorizedKeyLine(edPublicKey, "deploy@ci"),
+	)
+
+	keys, err := ParseAuthorizedKeys([]byte(data))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(keys).To(HaveLen(2))
+
+	Expect(keys[0].Name).To(Equal("admin"))
+	Expect(keys[0].Type).To(Equal(ssh.KeyAlgoRSA))
+	Expect(keys[0].Bits).To(Equal(2048))
+	Expect(keys[0].Comment).To(Equal("admin@laptop"))
+	Expect(keys[0].AllowedKeys).To(Equal("no-agent-forwarding,no-user-rc,no-X11-forwarding,no-port-forwarding"))
+	Expect(keys[0].Fingerprint).To(Equal(ssh.FingerprintSHA256(rsaPublicKey)))
+	Expect(keys[0].DisplayFingerprint()).To(Equal("SHA256:recorded"))
+
+	Expect(keys[1].Name).To(BeEmpty())
+	Expect(keys[1].Type).To(Equal(ssh.KeyAlgoED25519))
+	Expect(keys[1].Bits).To(Equal(256))
+	Expect(keys[1].DisplayFingerprint()).To(Equal(ssh.FingerprintSHA256(edPublicKey)))
+
+	_, err = ParseAuthorizedKeys([]byte(data + "invalid\n"))
+	Expect(err).To(MatchError("line 5 is not a valid ssh public key"))
+}
+
+func TestSSHKeysValidateKey(t *testing.T) {
+	RegisterTestingT(t)
+
+	weakRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ValidateKey(generateTestKey(&weakRSAKey.PublicKey))).NotTo(Succeed())
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ValidateKey(generateTestKey(&rsaKey.PublicKey))).To(Succeed())
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ValidateKey(generateTestKey(&ecdsaKey.PublicKey))).To(Succeed())
+
+	var dsaKey dsa.PrivateKey
+	Expect(dsa.GenerateParameters(&dsaKey.Parameters, rand.Reader, dsa.L1024N160)).To(Succeed())
+	Expect(dsa.GenerateKey(&dsaKey, rand.Reader)).To(Succeed())
+	Expect(ValidateKey(generateTestKey(&dsaKey.PublicKey))).NotTo(Succeed())
+}
+
+func TestSSHKeysParsePublicKey(t *testing.T) {
+	RegisterTestingT(t)
+
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	line := authorizedKeyLine(generateTestKey(edPublic), "user@host")
+
+	_, err = ParsePublicKey(line + "\n")
+	Expect(err).NotTo(HaveOccurred())
+	_, err = ParsePublicKey(line + "\n" + line)
+	Expect(err).To(HaveOccurred())
+	_, err = ParsePublicKey("no-pty " + line)
+	Expect(err).To(HaveOccurred())
+	_, err = ParsePublicKey("invalid!")
+	Expect(err).To(HaveOccurred())
+	_, err = ParsePublicKey("")
+	Expect(err).To(HaveOccurred())
+}
+
+func TestSSHKeysParseExpiry(t *testing.T) {
+	RegisterTestingT(t)
+
+	expiresAt, err := ParseExpiry("2030-01-02")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(expiresAt).To(Equal(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)))
+
+	expiresAt, err = ParseExpiry("2030-01-02T03:04:05+01:00")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(expiresAt).To(Equal(time.Date(2030, 1, 2, 2, 4, 5, 0, time.UTC)))
+
+	expiresAt, err = ParseExpiry("90d")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(expiresAt).To(BeTemporally("~", time.Now().AddDate(0, 0, 90), time.Minute))
+
+	expiresAt, err = ParseExpiry("12h")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(expiresAt).To(BeTemporally("~", time.Now().Add(12*time.Hour), time.Minute))
+
+	_, err = ParseExpiry("tomorrow")
+	Expect(err).To(HaveOccurred())
+	_, err = ParseExpiry("-1d")
+	Expect(err).To(HaveOccurred())
+}
+
+func TestSSHKeysExpiry(t *testing.T) {
+	RegisterTestingT(t)
+	defer testutil.SetupRoots(t).Teardown()
+
+	Expect(CheckKeyExpiry("admin")).To(Succeed())
+
+	Expect(setExpiry("admin", time.Now().Add(time.Hour))).To(Succeed())
+	Expect(CheckKeyExpiry("admin")).To(Succeed())
+
+	Expect(setExpiry("admin", time.Now().Add(-time.Hour))).To(Succeed())
+	Expect(setExpiry("deploy", time.Now().Add(time.Hour))).To(Succeed())
+	Expect(CheckKeyExpiry("admin")).NotTo(Succeed())
+	Expect(CheckKeyExpiry("deploy")).To(Succeed())
+
+	sshPath := filepath.Join(os.Getenv("DOKKU_ROOT"), ".ssh")
+	Expect(os.MkdirAll(sshPath, 0700)).To(Succeed())
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	line := fmt.Sprintf("command=\"FINGERPRINT=SHA256:x NAME=\\\"admin\\\" `cat /home/dokku/.sshcommand` $SSH_ORIGINAL_COMMAND\" %s\n", authorizedKeyLine(generateTestKey(edPublic), ""))
+	Expect(ioutil.WriteFile(filepath.Join(sshPath, "authorized_keys"), []byte(line), 0600)).To(Succeed())
+
+	keys, err := GetKeys()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(keys).To(HaveLen(1))
+	Expect(keys[0].ExpiresAt).NotTo(BeNil())
+	Expect(keys[0].Expired).To(BeTrue())
+
+	Expect(removeExpiry("admin")).To(Succeed())
+	Expect(CheckKeyExpiry("admin")).To(Succeed())
+	_, ok := GetExpiry("deploy")
+	Expect(ok).To(BeTrue())
+}
@@ -0,0 +1,90 @@
+package sshkeys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// CommandAdd implements ssh-keys:add
+func CommandAdd(name string, keyFile string, expires string) error {
+	var contents string
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeNamedPipe != 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("Unable to read key from stdin: %s", err.Error())
+		}
+		contents = string(b)
+	}
+	if strings.TrimSpace(contents) == "" && keyFile != "" {
+		b, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("Unable to read key file: %s", err.Error())
+		}
+		contents = string(b)
+	}
+	if name == "" || strings.TrimSpace(contents) == "" {
+		return errors.New("Two arguments are required if not piping, ie: dokku ssh-keys:add <NAME> <KEY_FILE>")
+	}
+
+	var expiresAt *time.Time
+	if expires != "" {
+		t, err := ParseExpiry(expires)
+		if err != nil {
+			return err
+		}
+		expiresAt = &t
+	}
+
+	fingerprint, err := AddKey(name, contents, expiresAt)
+	if err != nil {
+		return err
+	}
+	fmt.Println(fingerprint)
+	return nil
+}
+
+// CommandList implements ssh-keys:list
+func CommandList(format string) error {
+	if format != "stdout" && format != "json" {
+		return errors.New("Invalid format specified, valid formats include: stdout, json")
+	}
+
+	keys, err := GetKeys()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(keys)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	if len(keys) == 0 {
+		return errors.New("No public keys found")
+	}
+	for _, key := range keys {
+		line := fmt.Sprintf("%s NAME=\"%s\" SSHCOMMAND_ALLOWED_KEYS=\"%s\"", key.DisplayFingerprint(), key.Name, key.AllowedKeys)
+		if key.ExpiresAt != nil {
+			line = fmt.Sprintf("%s EXPIRES_AT=\"%s\"", line, key.ExpiresAt.Format(time.RFC3339))
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// CommandRemove implements ssh-keys:remove
+func CommandRemove(name string) error {
+	if name == "" {
+		return errors.New("A name is required to remove a key, ie: dokku ssh-keys:remove <name>")
+	}
+	return RemoveKey(name)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/tar"
+)
+
+// extracts a plain or compressed tarball into a directory
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	filename := flag.Arg(1)
+	dest := flag.Arg(2)
+
+	limits, err := tar.GetLimits(appName)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+
+	if err := tar.Extract(filename, dest, limits); err != nil {
+		common.LogFail(err.Error())
+	}
+}
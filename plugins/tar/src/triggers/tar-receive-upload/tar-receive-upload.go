@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/tar"
+)
+
+// writes a tarball read from stdin to a file, enforcing the maximum tarball size
+func main() {
+	flag.Parse()
+	appName := flag.Arg(0)
+	filename := flag.Arg(1)
+
+	limits, err := tar.GetLimits(appName)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+
+	written, err := tar.WriteUpload(os.Stdin, filename, limits)
+	if err != nil {
+		common.LogFail(err.Error())
+	}
+	common.LogInfo1Quiet(fmt.Sprintf("Received %s tarball", tar.FormatSize(written)))
+}
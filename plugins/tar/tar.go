@@ -0,0 +1,518 @@
+package tar
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
+)
+
+const (
+	// DefaultMaxSize is the default maximum size of an uploaded or extracted tarball
+	DefaultMaxSize = "2G"
+
+	// DefaultMaxFiles is the default maximum number of entries in a tarball
+	DefaultMaxFiles = "200000"
+
+	// progressThreshold is the extracted size above which extraction progress is reported
+	progressThreshold = 10 * 1024 * 1024
+
+	// maxTrailingBytes is the amount of data read after the end of an archive
+	maxTrailingBytes = 1024 * 1024
+)
+
+var compressionMagic = []struct {
+	name  string
+	magic []byte
+}{
+	{"gzip", []byte{0x1f, 0x8b}},
+	{"bzip2", []byte("BZh")},
+	{"xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// Limits bounds the size and number of files of a tarball. A zero value disables a limit
+type Limits struct {
+	MaxSize  int64
+	MaxFiles int
+}
+
+// GetLimits returns the tarball limits of an app, falling back to the global config
+func GetLimits(appName string) (Limits, error) {
+	limits := Limits{}
+
+	maxSize := getConfig(appName, "DOKKU_TAR_MAX_SIZE", DefaultMaxSize)
+	size, err := ParseSize(maxSize)
+	if err != nil {
+		return limits, fmt.Errorf("Invalid DOKKU_TAR_MAX_SIZE %s: %s", maxSize, err.Error())
+	}
+	limits.MaxSize = size
+
+	maxFiles := getConfig(appName, "DOKKU_TAR_MAX_FILES", DefaultMaxFiles)
+	files, err := strconv.Atoi(maxFiles)
+	if err != nil || files < 0 {
+		return limits, fmt.Errorf("Invalid DOKKU_TAR_MAX_FILES %s", maxFiles)
+	}
+	limits.MaxFiles = files
+	return limits, nil
+}
+
+// ParseSize parses a size in bytes, optionally suffixed with K, M, G or T
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, errors.New("expected a number of bytes, optionally suffixed with K, M, G or T")
+	}
+	return size * multiplier, nil
+}
+
+// FormatSize returns a human readable size
+func FormatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// DetectCompression returns the compression of a stream based on its leading bytes,
+// or "none" for an uncompressed stream
+func DetectCompression(r *bufio.Reader) string {
+	header, _ := r.Peek(6)
+	for _, format := range compressionMagic {
+		if bytes.HasPrefix(header, format.magic) {
+			return format.name
+		}
+	}
+	return "none"
+}
+
+// WriteUpload writes an uploaded tarball to a file, failing once it exceeds the maximum size.
+// The file is removed when the upload fails
+func WriteUpload(r io.Reader, filename string, limits Limits) (int64, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to write tarball: %s", err.Error())
+	}
+
+	reader := r
+	if limits.MaxSize > 0 {
+		reader = io.LimitReader(r, limits.MaxSize+1)
+	}
+	written, err := io.Copy(f, reader)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && limits.MaxSize > 0 && written > limits.MaxSize {
+		err = fmt.Errorf("Tarball exceeds the maximum size of %s, see DOKKU_TAR_MAX_SIZE", FormatSize(limits.MaxSize))
+	}
+	if err != nil {
+		os.Remove(filename)
+		return written, err
+	}
+	return written, nil
+}
+
+// Extract unpacks a plain or compressed tarball into a directory. Entries are checked
+// against the limits before anything is written, directories shared by every entry are
+// stripped, and entries that would be written outside of the directory are refused
+func Extract(filename string, dest string, limits Limits) error {
+	stats, err := scanArchive(filename, limits)
+	if err != nil {
+		return err
+	}
+
+	description := "tarball"
+	if stats.compression != "none" {
+		description = stats.compression + " compressed tarball"
+	}
+	common.LogInfo1Quiet(fmt.Sprintf("Extracting %d files (%s) from %s", stats.files, FormatSize(stats.size), description))
+	if stats.strip > 0 {
+		common.LogInfo1Quiet(fmt.Sprintf("Striping %d worth of directories from tarball", stats.strip))
+	}
+
+	archive, _, err := openArchive(filename)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	progress := newProgress(stats.size)
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to read tarball: %s", err.Error())
+		}
+
+		name := stripComponents(cleanEntryName(header.Name), stats.strip)
+		if name == "" {
+			continue
+		}
+		if err := extractEntry(reader, header, dest, name, stats.strip); err != nil {
+			return err
+		}
+		progress.add(header.Size)
+	}
+	return archive.Finish()
+}
+
+type archiveStats struct {
+	compression string
+	files       int
+	size        int64
+	strip       int
+}
+
+// scanArchive reads the headers of a tarball, validating every entry and computing
+// the number of leading directories shared by all entries
+func scanArchive(filename string, limits Limits) (archiveStats, error) {
+	archive, compression, err := openArchive(filename)
+	if err != nil {
+		return archiveStats{}, err
+	}
+	defer archive.Close()
+
+	stats := archiveStats{compression: compression}
+	var prefix []string
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("Unable to read tarball: %s", err.Error())
+		}
+
+		name := cleanEntryName(header.Name)
+		if name == "" {
+			continue
+		}
+		if err := validateEntry(header, name); err != nil {
+			return stats, err
+		}
+
+		stats.files++
+		stats.size += header.Size
+		if limits.MaxFiles > 0 && stats.files > limits.MaxFiles {
+			return stats, fmt.Errorf("Tarball contains more than the maximum of %d files, see DOKKU_TAR_MAX_FILES", limits.MaxFiles)
+		}
+		if limits.MaxSize > 0 && stats.size > limits.MaxSize {
+			return stats, fmt.Errorf("Tarball exceeds the maximum extracted size of %s, see DOKKU_TAR_MAX_SIZE", FormatSize(limits.MaxSize))
+		}
+
+		dirs := strings.Split(name, "/")
+		if header.Typeflag != tar.TypeDir {
+			dirs = dirs[:len(dirs)-1]
+		}
+		if prefix == nil {
+			prefix = dirs
+		}
+		prefix = commonPrefix(prefix, dirs)
+	}
+
+	if stats.files == 0 {
+		return stats, errors.New("Tarball is empty")
+	}
+	stats.strip = len(prefix)
+	return stats, archive.Finish()
+}
+
+// openArchive returns the decompressed contents of a tarball along with its compression
+func openArchive(filename string) (*archiveReader, string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to open tarball: %s", err.Error())
+	}
+
+	buffered := bufio.NewReader(f)
+	compression := DetectCompression(buffered)
+	switch compression {
+	case "gzip":
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, compression, fmt.Errorf("Unable to read gzip tarball: %s", err.Error())
+		}
+		return &archiveReader{Reader: gz, closers: []io.Closer{gz, f}}, compression, nil
+	case "bzip2":
+		return &archiveReader{Reader: bzip2.NewReader(buffered), closers: []io.Closer{f}}, compression, nil
+	case "xz", "zstd":
+		binary := map[string]string{"xz": "xz", "zstd": "zstd"}[compression]
+		if _, err := exec.LookPath(binary); err != nil {
+			f.Close()
+			return nil, compression, fmt.Errorf("Unable to read %s tarball, %s is not installed", compression, binary)
+		}
+
+		cmd := exec.Command(binary, "--decompress", "--stdout")
+		cmd.Stdin = buffered
+		cmd.Stderr = os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			f.Close()
+			return nil, compression, err
+		}
+		if err := cmd.Start(); err != nil {
+			f.Close()
+			return nil, compression, fmt.Errorf("Unable to read %s tarball: %s", compression, err.Error())
+		}
+		return &archiveReader{Reader: stdout, closers: []io.Closer{f}, cmd: cmd}, compression, nil
+	}
+	return &archiveReader{Reader: buffered, closers: []io.Closer{f}}, compression, nil
+}
+
+// archiveReader closes the file and decompressor backing a tarball
+type archiveReader struct {
+	io.Reader
+	closers []io.Closer
+	cmd     *exec.Cmd
+	closed  bool
+}
+
+// Finish reads the remainder of a tarball, returning an error if it could not be decompressed
+func (a *archiveReader) Finish() error {
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+
+	var err error
+	if a.cmd != nil {
+		// padding after the end of the archive is read, but not an unbounded stream
+		if _, copyErr := io.CopyN(ioutil.Discard, a.Reader, maxTrailingBytes); copyErr == nil {
+			a.cmd.Process.Kill()
+			a.cmd.Wait()
+		} else if waitErr := a.cmd.Wait(); waitErr != nil {
+			err = fmt.Errorf("Unable to decompress tarball: %s", waitErr.Error())
+		}
+	}
+	a.closeFiles()
+	return err
+}
+
+// Close stops reading a tarball, terminating any decompressor that is still running
+func (a *archiveReader) Close() error {
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+
+	if a.cmd != nil {
+		a.cmd.Process.Kill()
+		a.cmd.Wait()
+	}
+	a.closeFiles()
+	return nil
+}
+
+func (a *archiveReader) closeFiles() {
+	for _, closer := range a.closers {
+		closer.Close()
+	}
+}
+
+// cleanEntryName normalizes the name of a tarball entry, returning an empty
+// string for entries referring to the root of the tarball
+func cleanEntryName(name string) string {
+	name = filepath.ToSlash(filepath.Clean("/" + strings.TrimPrefix(name, "./")))
+	name = strings.TrimPrefix(name, "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+func validateEntry(header *tar.Header, name string) error {
+	if !isWithin(header.Name) {
+		return fmt.Errorf("Tarball entry %s refers to a path outside of the app directory", header.Name)
+	}
+
+	switch header.Typeflag {
+	case tar.TypeSymlink:
+		if filepath.IsAbs(header.Linkname) || !isWithin(filepath.Join(filepath.Dir(name), header.Linkname)) {
+			return fmt.Errorf("Tarball symlink %s points outside of the app directory", header.Name)
+		}
+	case tar.TypeLink:
+		if filepath.IsAbs(header.Linkname) || !isWithin(header.Linkname) {
+			return fmt.Errorf("Tarball hardlink %s points outside of the app directory", header.Name)
+		}
+	}
+	return nil
+}
+
+// isWithin returns true if a relative path does not escape the directory it is relative to
+func isWithin(name string) bool {
+	if filepath.IsAbs(name) {
+		return false
+	}
+	cleaned := filepath.Clean(name)
+	return cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
+
+func extractEntry(reader io.Reader, header *tar.Header, dest string, name string, strip int) error {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	mode := os.FileMode(header.Mode).Perm()
+	if err := checkParents(dest, name); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		os.Remove(target)
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("Unable to extract %s: %s", name, err.Error())
+		}
+		return os.Chmod(target, mode|0700)
+	case tar.TypeReg, tar.TypeRegA:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("Unable to extract %s: %s", name, err.Error())
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode|0400)
+		if err != nil {
+			return fmt.Errorf("Unable to extract %s: %s", name, err.Error())
+		}
+		if _, err := io.Copy(f, reader); err != nil {
+			f.Close()
+			return fmt.Errorf("Unable to extract %s: %s", name, err.Error())
+		}
+		return f.Close()
+	case tar.TypeSymlink:
+		if !isWithin(filepath.Join(filepath.Dir(name), header.Linkname)) {
+			return fmt.Errorf("Tarball symlink %s points outside of the app directory", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("Unable to extract %s: %s", name, err.Error())
+		}
+		os.Remove(target)
+		return os.Symlink(header.Linkname, target)
+	case tar.TypeLink:
+		source := stripComponents(cleanEntryName(header.Linkname), strip)
+		if source == "" {
+			return fmt.Errorf("Tarball hardlink %s points outside of the app directory", header.Name)
+		}
+		if err := checkParents(dest, source); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("Unable to extract %s: %s", name, err.Error())
+		}
+		os.Remove(target)
+		return os.Link(filepath.Join(dest, filepath.FromSlash(source)), target)
+	}
+
+	common.LogWarn(fmt.Sprintf("Skipping unsupported tarball entry %s", header.Name))
+	return nil
+}
+
+// checkParents refuses entries written through a previously extracted symlink,
+// as a chain of symlinks may point outside of the app directory
+func checkParents(dest string, name string) error {
+	parts := strings.Split(name, "/")
+	current := dest
+	for _, part := range parts[:len(parts)-1] {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("Tarball entry %s is written through the symlink %s", name, part)
+		}
+	}
+	return nil
+}
+
+func stripComponents(name string, strip int) string {
+	if name == "" {
+		return ""
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) <= strip {
+		return ""
+	}
+	return strings.Join(parts[strip:], "/")
+}
+
+func commonPrefix(a []string, b []string) []string {
+	length := 0
+	for length < len(a) && length < len(b) && a[length] == b[length] {
+		length++
+	}
+	return a[:length]
+}
+
+func getConfig(appName string, key string, defaultValue string) string {
+	return config.GetWithDefault(appName, key, config.GetWithDefault("--global", key, defaultValue))
+}
+
+// progress reports the share of a tarball extracted so far in steps of a quarter
+type progress struct {
+	total     int64
+	extracted int64
+	reported  int64
+}
+
+func newProgress(total int64) *progress {
+	return &progress{total: total}
+}
+
+func (p *progress) add(size int64) {
+	p.extracted += size
+	if p.total < progressThreshold {
+		return
+	}
+
+	step := p.extracted * 4 / p.total
+	if step > p.reported {
+		p.reported = step
+		common.LogVerboseQuiet(fmt.Sprintf("Extracted %d%% (%s of %s)", step*25, FormatSize(p.extracted), FormatSize(p.total)))
+	}
+}
@@ -0,0 +1,216 @@
+package tar
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+type testEntry struct {
+	name     string
+	body     string
+	typeflag byte
+	linkname string
+}
+
+func buildTarball(entries []testEntry) []byte {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.body)), Typeflag: entry.typeflag, Linkname: entry.linkname}
+		switch entry.typeflag {
+		case 0:
+			header.Typeflag = tar.TypeReg
+		case tar.TypeDir:
+			header.Mode = 0755
+		}
+		Expect(writer.WriteHeader(header)).To(Succeed())
+		if header.Typeflag == tar.TypeReg {
+			_, err := writer.Write([]byte(entry.body))
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+	Expect(writer.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func writeTarball(dir string, contents []byte) string {
+	filename := filepath.Join(dir, "src.tar")
+	Expect(ioutil.WriteFile(filename, contents, 0644)).To(Succeed())
+	return filename
+}
+
+func setupExtractDirs() (string, string, func()) {
+	srcDir, err := ioutil.TempDir("", "dokku-tar-src")
+	Expect(err).NotTo(HaveOccurred())
+	dest, err := ioutil.TempDir("", "dokku-tar-dest")
+	Expect(err).NotTo(HaveOccurred())
+	return srcDir, dest, func() {
+		os.RemoveAll(srcDir)
+		os.RemoveAll(dest)
+	}
+}
+
+var appEntries = []testEntry{
+	{name: "./", typeflag: tar.TypeDir},
+	{name: "./prefix/", typeflag: tar.TypeDir},
+	{name: "./prefix/Procfile", body: "web: node app.js\n"},
+	{name: "./prefix/lib/", typeflag: tar.TypeDir},
+	{name: "./prefix/lib/app.js", body: "console.log('hi')\n"},
+	{name: "./prefix/current", typeflag: tar.TypeSymlink, linkname: "lib"},
+}
+
+func TestTarParseSize(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(ParseSize("1024")).To(Equal(int64(1024)))
+	Expect(ParseSize("512M")).To(Equal(int64(512 * 1024 * 1024)))
+	Expect(ParseSize("2g")).To(Equal(int64(2 * 1024 * 1024 * 1024)))
+	Expect(ParseSize("10KiB")).To(Equal(int64(10 * 1024)))
+	Expect(ParseSize("0")).To(Equal(int64(0)))
+	_, err := ParseSize("lots")
+	Expect(err).To(HaveOccurred())
+	_, err = ParseSize("-1")
+	Expect(err).To(HaveOccurred())
+
+	Expect(FormatSize(512)).To(Equal("512 B"))
+	Expect(FormatSize(1536)).To(Equal("1.5 KB"))
+}
+
+func TestTarDetectCompression(t *testing.T) {
+	RegisterTestingT(t)
+
+	detect := func(b []byte) string {
+		return DetectCompression(bufio.NewReader(bytes.NewReader(b)))
+	}
+	Expect(detect([]byte{0x1f, 0x8b, 0x08})).To(Equal("gzip"))
+	Expect(detect([]byte("BZh91AY"))).To(Equal("bzip2"))
+	Expect(detect([]byte{0xfd, '7', 'z', 'X', 'Z', 0x00})).To(Equal("xz"))
+	Expect(detect([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00})).To(Equal("zstd"))
+	Expect(detect([]byte("Procfile"))).To(Equal("none"))
+	Expect(detect([]byte{})).To(Equal("none"))
+}
+
+func TestTarExtract(t *testing.T) {
+	RegisterTestingT(t)
+	srcDir, dest, cleanup := setupExtractDirs()
+	defer cleanup()
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err := gz.Write(buildTarball(appEntries))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(gz.Close()).To(Succeed())
+
+	Expect(Extract(writeTarball(srcDir, gzipped.Bytes()), dest, Limits{})).To(Succeed())
+	b, err := ioutil.ReadFile(filepath.Join(dest, "Procfile"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("web: node app.js\n"))
+	b, err = ioutil.ReadFile(filepath.Join(dest, "current", "app.js"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal("console.log('hi')\n"))
+	Expect(filepath.Join(dest, "prefix")).NotTo(BeAnExistingFile())
+}
+
+func TestTarExtractCompressionTools(t *testing.T) {
+	RegisterTestingT(t)
+
+	tarball := buildTarball(appEntries)
+	for _, tool := range []string{"bzip2", "xz", "zstd"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Logf("skipping %s, it is not installed", tool)
+			continue
+		}
+
+		srcDir, dest, cleanup := setupExtractDirs()
+		cmd := exec.Command(tool, "--compress", "--stdout")
+		cmd.Stdin = bytes.NewReader(tarball)
+		compressed, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred())
+
+		filename := writeTarball(srcDir, compressed)
+		stats, err := scanArchive(filename, Limits{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.compression).To(Equal(tool))
+		Expect(stats.strip).To(Equal(1))
+		Expect(Extract(filename, dest, Limits{})).To(Succeed())
+		Expect(filepath.Join(dest, "lib", "app.js")).To(BeAnExistingFile())
+		cleanup()
+	}
+}
+
+func TestTarExtractLimits(t *testing.T) {
+	RegisterTestingT(t)
+	srcDir, dest, cleanup := setupExtractDirs()
+	defer cleanup()
+
+	filename := writeTarball(srcDir, buildTarball([]testEntry{
+		{name: "a.txt", body: strings.Repeat("a", 600)},
+		{name: "b.txt", body: strings.Repeat("b", 600)},
+	}))
+	Expect(Extract(filename, dest, Limits{MaxFiles: 1})).To(MatchError(ContainSubstring("DOKKU_TAR_MAX_FILES")))
+	Expect(Extract(filename, dest, Limits{MaxSize: 1024})).To(MatchError(ContainSubstring("DOKKU_TAR_MAX_SIZE")))
+	files, err := ioutil.ReadDir(dest)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(files).To(BeEmpty())
+
+	Expect(Extract(filename, dest, Limits{MaxSize: 2048, MaxFiles: 2})).To(Succeed())
+	Expect(filepath.Join(dest, "b.txt")).To(BeAnExistingFile())
+
+	empty := writeTarball(srcDir, buildTarball([]testEntry{}))
+	Expect(Extract(empty, dest, Limits{})).To(MatchError("Tarball is empty"))
+}
+
+func TestTarExtractPathTraversal(t *testing.T) {
+	RegisterTestingT(t)
+
+	invalid := map[string][]testEntry{
+		"parent":         {{name: "../evil", body: "x"}},
+		"absolute":       {{name: "/etc/evil", body: "x"}},
+		"symlink":        {{name: "passwd", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+		"symlink parent": {{name: "app/up", typeflag: tar.TypeSymlink, linkname: "../../.."}},
+		"hardlink":       {{name: "shadow", typeflag: tar.TypeLink, linkname: "../shadow"}},
+		"symlink chain": {
+			{name: "a/b", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "a/c", typeflag: tar.TypeSymlink, linkname: "b/.."},
+			{name: "a/c/evil", body: "x"},
+		},
+		"stripped symlink": {
+			{name: "app/lib", typeflag: tar.TypeSymlink, linkname: "../app/src"},
+			{name: "app/src/index.js", body: "x"},
+		},
+	}
+
+	for description, entries := range invalid {
+		srcDir, dest, cleanup := setupExtractDirs()
+		err := Extract(writeTarball(srcDir, buildTarball(entries)), dest, Limits{})
+		Expect(err).To(HaveOccurred(), description)
+		Expect(filepath.Join(filepath.Dir(dest), "evil")).NotTo(BeAnExistingFile(), description)
+		cleanup()
+	}
+}
+
+func TestTarWriteUpload(t *testing.T) {
+	RegisterTestingT(t)
+	srcDir, _, cleanup := setupExtractDirs()
+	defer cleanup()
+
+	filename := filepath.Join(srcDir, "src.tar")
+	written, err := WriteUpload(strings.NewReader(strings.Repeat("x", 100)), filename, Limits{MaxSize: 100})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(written).To(Equal(int64(100)))
+	Expect(filename).To(BeAnExistingFile())
+
+	_, err = WriteUpload(strings.NewReader(strings.Repeat("x", 101)), filename, Limits{MaxSize: 100})
+	Expect(err).To(MatchError(ContainSubstring("maximum size of 100 B")))
+	Expect(filename).NotTo(BeAnExistingFile())
+}
@@ -62,6 +62,7 @@ endif
 		--package "$(BUILD_DIRECTORY)/$(DOKKU_RPM_PACKAGE_NAME)" \
 		--depends '/usr/bin/docker' \
 		--depends 'bind-utils' \
+		--depends 'cronie' \
 		--depends 'curl' \
 		--depends 'gcc' \
 		--depends 'git' \
@@ -213,6 +213,80 @@ teardown() {
   assert_success
 }
 
+@test "(apps) apps:rename moves plugin properties" {
+  run /bin/bash -c "dokku network:set $TEST_APP bind-all-interfaces true"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku resource:limit --memory 512MB $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku buildpacks:add $TEST_APP heroku/nodejs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:rename --skip-deploy $TEST_APP great-test-name"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku network:report great-test-name --network-bind-all-interfaces"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "true"
+  run /bin/bash -c "dokku resource:report great-test-name --resource-_default_.limit.memory"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "512MB"
+  run /bin/bash -c "dokku --quiet buildpacks:list great-test-name | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "heroku/nodejs"
+  run [ -d /var/lib/dokku/config/network/$TEST_APP ]
+  assert_failure
+  run [ -d /var/lib/dokku/config/resource/$TEST_APP ]
+  assert_failure
+
+  run /bin/bash -c "dokku --force apps:destroy great-test-name"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
+
+@test "(apps) apps:clone copies plugin properties" {
+  run /bin/bash -c "dokku network:set $TEST_APP bind-all-interfaces true"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku buildpacks:add $TEST_APP heroku/nodejs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:clone --skip-deploy $TEST_APP great-test-name"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku network:report great-test-name --network-bind-all-interfaces"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "true"
+  run /bin/bash -c "dokku --quiet buildpacks:list great-test-name | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "heroku/nodejs"
+  run /bin/bash -c "dokku network:report $TEST_APP --network-bind-all-interfaces"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "true"
+
+  run /bin/bash -c "dokku --force apps:destroy great-test-name"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
+
 @test "(apps) apps:exists" {
   run /bin/bash -c "dokku apps:exists $TEST_APP"
   echo "output: $output"
@@ -272,3 +346,302 @@ teardown() {
 
   destroy_app
 }
+
+@test "(apps) apps:locked --format json" {
+  create_app
+
+  run /bin/bash -c "dokku apps:locked $TEST_APP --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains '"locked":false'
+
+  run /bin/bash -c "dokku apps:lock $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:locked --format json $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains '"locked":true'
+  assert_output_contains '"waiters":[]'
+
+  run /bin/bash -c "dokku apps:locked $TEST_APP --format yaml"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku apps:unlock $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  destroy_app
+}
+
+@test "(apps) apps:set" {
+  create_app
+
+  run /bin/bash -c "dokku apps:set $TEST_APP owner payments@example.com"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:set $TEST_APP description=Payments API"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:report $TEST_APP --owner"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "payments@example.com"
+
+  run /bin/bash -c "dokku apps:report $TEST_APP --description"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "Payments API"
+
+  run /bin/bash -c "dokku apps:set $TEST_APP color blue"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku apps:set $TEST_APP label.Team=payments"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku apps:set $TEST_APP label.team payments"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:set $TEST_APP label.env production"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:report $TEST_APP --labels"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "env=production team=payments"
+
+  run /bin/bash -c "dokku apps:set $TEST_APP owner"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:report $TEST_APP --owner"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output ""
+
+  destroy_app
+}
+
+@test "(apps) apps:list --label" {
+  create_app
+  run /bin/bash -c "dokku apps:create other-$TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:set $TEST_APP label.team=payments"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:set other-$TEST_APP label.team=search"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku --quiet apps:list --label team=payments"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "$TEST_APP"
+
+  run /bin/bash -c "dokku apps:list --label team=payments --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "[{\"name\":\"$TEST_APP\",\"owner\":\"\",\"description\":\"\",\"labels\":{\"team\":\"payments\"}}]"
+
+  run /bin/bash -c "dokku apps:list --label team=missing --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "[]"
+
+  run /bin/bash -c "dokku apps:list --label team"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku --force apps:destroy other-$TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  destroy_app
+}
+
+@test "(apps) labels are applied to containers" {
+  deploy_app
+
+  run /bin/bash -c "dokku apps:set $TEST_APP label.team=payments"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku ps:rebuild $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  CID=$(< $DOKKU_ROOT/$TEST_APP/CONTAINER.web.1)
+  run /bin/bash -c "docker inspect --format '{{ index .Config.Labels \"team\" }}' $CID"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "payments"
+
+  destroy_app
+}
+
+@test "(apps) apps:create --template" {
+  mkdir -p /var/lib/dokku/data/apps/templates
+  cat >/var/lib/dokku/data/apps/templates/bats-template.yml <<TEMPLATE
+owner: payments@example.com
+labels:
+  team: payments
+config:
+  TEMPLATE_KEY: template-value
+TEMPLATE
+
+  run /bin/bash -c "dokku apps:create $TEST_APP --template missing-template"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku apps:create $TEST_APP --template bats-template --dry-run"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Set config keys TEMPLATE_KEY"
+
+  run /bin/bash -c "dokku apps:exists $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku apps:create $TEST_APP --template bats-template"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:get $TEST_APP TEMPLATE_KEY"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "template-value"
+
+  run /bin/bash -c "dokku apps:report $TEST_APP --owner"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "payments@example.com"
+
+  rm -f /var/lib/dokku/data/apps/templates/bats-template.yml
+  destroy_app
+}
+
+@test "(apps) apps:export and apps:apply" {
+  create_app
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP EXPORT_KEY=export-value"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku storage:mount $TEST_APP /var/lib/dokku/data/storage/$TEST_APP:/app/storage"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:export $TEST_APP > /tmp/$TEST_APP-state.yml"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:apply -f - < /tmp/$TEST_APP-state.yml"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "No changes for $TEST_APP"
+
+  echo "{\"name\": \"$TEST_APP\", \"config\": {\"EXPORT_KEY\": \"changed-value\"}, \"storage\": []}" > /tmp/$TEST_APP-state.json
+
+  run /bin/bash -c "dokku apps:apply --dry-run -f /tmp/$TEST_APP-state.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Set config keys EXPORT_KEY"
+
+  run /bin/bash -c "dokku config:get $TEST_APP EXPORT_KEY"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "export-value"
+
+  run /bin/bash -c "dokku apps:apply -f /tmp/$TEST_APP-state.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:get $TEST_APP EXPORT_KEY"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "changed-value"
+
+  run /bin/bash -c "dokku storage:list $TEST_APP | grep -q /app/storage"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  rm -f /tmp/$TEST_APP-state.yml /tmp/$TEST_APP-state.json
+  destroy_app
+}
+
+@test "(apps) apps:deploy-image" {
+  deploy_app
+  docker tag "dokku/$TEST_APP:latest" "dokku-test/deploy-image:v2"
+
+  run /bin/bash -c "dokku apps:deploy-image $TEST_APP dokku-test/deploy-image:v2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Tagging dokku-test/deploy-image:v2 as dokku/$TEST_APP:v2"
+  assert_output_contains "Application deployed"
+
+  run /bin/bash -c "docker images -q dokku/$TEST_APP:v2 | wc -l"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "1"
+
+  run /bin/bash -c "docker save dokku-test/deploy-image:v2 | dokku apps:deploy-image $TEST_APP -"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Loading image from stdin"
+
+  run /bin/bash -c "dokku apps:deploy-image $TEST_APP dokku-test/missing-image:v1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Unable to pull dokku-test/missing-image:v1"
+
+  run /bin/bash -c "dokku apps:locked $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  docker rmi "dokku-test/deploy-image:v2"
+  destroy_app
+}
@@ -0,0 +1,62 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+setup() {
+  global_setup
+  create_app
+}
+
+teardown() {
+  destroy_app
+  global_teardown
+}
+
+@test "(builder-dockerfile) builder-dockerfile:help" {
+  run /bin/bash -c "dokku builder-dockerfile:help"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "Manages how Dockerfile apps are built"
+}
+
+@test "(builder-dockerfile) builder-dockerfile:set builder" {
+  run /bin/bash -c "dokku builder-dockerfile:report $TEST_APP --builder-dockerfile-builder"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "docker"
+
+  run /bin/bash -c "dokku builder-dockerfile:set $TEST_APP builder podman"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku builder-dockerfile:set $TEST_APP invalid buildkit"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku builder-dockerfile:set $TEST_APP builder buildkit"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builder-dockerfile:report $TEST_APP --builder-dockerfile-builder"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "buildkit"
+
+  run /bin/bash -c "dokku builder-dockerfile:report $TEST_APP --builder-dockerfile-cache-dir"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "$DOKKU_ROOT/$TEST_APP/cache/buildkit"
+
+  run /bin/bash -c "dokku builder-dockerfile:set $TEST_APP builder"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builder-dockerfile:report $TEST_APP --builder-dockerfile-builder"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "docker"
+}
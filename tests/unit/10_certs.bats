@@ -62,6 +62,139 @@ teardown() {
   assert_success
 }
 
+@test "(certs) certs:report" {
+  run /bin/bash -c "dokku certs:add $TEST_APP < $BATS_TEST_DIRNAME/server_ssl.tar"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:report $TEST_APP --ssl-enabled"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "true"
+
+  run /bin/bash -c "dokku certs:report $TEST_APP --ssl-key-matches"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "true"
+
+  run /bin/bash -c "dokku certs:report $TEST_APP --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains '"ssl-enabled":true'
+
+  run /bin/bash -c "dokku certs:report $TEST_APP --ssl-invalid"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(certs) certs:expiring" {
+  run /bin/bash -c "dokku certs:add $TEST_APP < $BATS_TEST_DIRNAME/server_ssl.tar"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:expiring --days 0 --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:expiring --days 36500 --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "\"app\":\"$TEST_APP\""
+
+  run /bin/bash -c "dokku certs:expiring --days -1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(certs) certs:add --require-ssl-coverage" {
+  run /bin/bash -c "dokku domains:set $TEST_APP node-js-app.dokku.me uncovered.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:add $TEST_APP --require-ssl-coverage < $BATS_TEST_DIRNAME/server_ssl.tar"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "uncovered.dokku.me"
+
+  run /bin/bash -c "dokku certs:report $TEST_APP --ssl-enabled"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "false"
+
+  run /bin/bash -c "dokku domains:remove $TEST_APP uncovered.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:add $TEST_APP --require-ssl-coverage < $BATS_TEST_DIRNAME/server_ssl.tar"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "not covered by the ssl certificate" 0
+}
+
+@test "(certs) domains:add checks ssl coverage" {
+  run /bin/bash -c "dokku domains:set $TEST_APP node-js-app.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:add $TEST_APP < $BATS_TEST_DIRNAME/server_ssl.tar"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains:add $TEST_APP --require-ssl-coverage uncovered.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "uncovered.dokku.me"
+
+  run /bin/bash -c "dokku domains:report $TEST_APP --domains-app-vhosts"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "node-js-app.dokku.me"
+
+  run /bin/bash -c "dokku domains:add $TEST_APP uncovered.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "not covered by the ssl certificate"
+}
+
+@test "(certs) certs:auto" {
+  run /bin/bash -c "dokku domains:set $TEST_APP *.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku certs:auto $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Wildcard domains cannot be validated"
+
+  run /bin/bash -c "dokku certs:renew $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Automatic certificates are not enabled for $TEST_APP"
+
+  run /bin/bash -c "dokku certs:renew --all"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
+
 @test "(certs) certs:remove" {
   run /bin/bash -c "dokku certs:add $TEST_APP < $BATS_TEST_DIRNAME/server_ssl.tar && dokku certs:remove $TEST_APP"
   echo "output: $output"
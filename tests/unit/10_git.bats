@@ -118,3 +118,123 @@ teardown() {
   echo "status: $status"
   assert_success
 }
+
+@test "(git) git:sync" {
+  local REPO_DIR=$(mktemp -d "/tmp/dokku.me.XXXXX")
+  cp -r "${BATS_TEST_DIRNAME}/../../tests/apps/nodejs-express/." "$REPO_DIR"
+  git -C "$REPO_DIR" init -q
+  git -C "$REPO_DIR" add -A
+  git -C "$REPO_DIR" -c user.name=dokku -c user.email=dokku@dokku.me commit -q -m "initial commit"
+  git -C "$REPO_DIR" tag v1
+  chmod -R go+rX "$REPO_DIR"
+  local REV="$(git -C "$REPO_DIR" rev-parse HEAD)"
+
+  run /bin/bash -c "dokku git:set --global allow-local-sync true"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku git:sync $TEST_APP file://$REPO_DIR/missing"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku git:sync $TEST_APP file://$REPO_DIR"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Updated master to $REV"
+
+  run /bin/bash -c "dokku config:get $TEST_APP GIT_REV"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output ""
+
+  run /bin/bash -c "dokku git:sync --build $TEST_APP file://$REPO_DIR v1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Application deployed"
+
+  run /bin/bash -c "dokku config:get $TEST_APP GIT_REV"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "$REV"
+
+  dokku git:set --global allow-local-sync
+  rm -rf "$REPO_DIR"
+}
+
+@test "(git) git:sync rejects option-like arguments" {
+  local MARKER="/tmp/dokku-git-sync-marker"
+  rm -f "$MARKER"
+
+  run /bin/bash -c "dokku git:sync $TEST_APP '--upload-pack=touch $MARKER; git-upload-pack' https://github.com/heroku/node-js-getting-started.git"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid remote repository"
+
+  run /bin/bash -c "dokku git:sync $TEST_APP https://github.com/heroku/node-js-getting-started.git '--upload-pack=touch $MARKER'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid git ref"
+
+  run /bin/bash -c "test -f $MARKER"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(git) git:sync requires allow-local-sync for local repositories" {
+  local OTHER_APP="other-$TEST_APP"
+  dokku apps:create "$OTHER_APP"
+
+  run /bin/bash -c "dokku git:sync $TEST_APP $DOKKU_ROOT/$OTHER_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "allow-local-sync"
+
+  run /bin/bash -c "dokku git:sync $TEST_APP file://$DOKKU_ROOT/$OTHER_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "allow-local-sync"
+
+  run /bin/bash -c "dokku git:set $TEST_APP allow-local-sync true"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  dokku --force apps:destroy "$OTHER_APP"
+}
+
+@test "(git) git:deploy-key" {
+  run /bin/bash -c "dokku git:deploy-key $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku git:deploy-key $TEST_APP --generate"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "ssh-ed25519"
+
+  run /bin/bash -c "dokku git:deploy-key $TEST_APP | grep -c dokku-$TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "1"
+
+  run /bin/bash -c "dokku git:deploy-key $TEST_APP --remove"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku git:deploy-key $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
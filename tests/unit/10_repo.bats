@@ -22,6 +22,10 @@ teardown() {
   echo "output: $output"
   echo "status: $status"
   assert_failure
+  run /bin/bash -c "mkdir -p $DOKKU_ROOT/$TEST_APP/cache/buildkit && touch $DOKKU_ROOT/$TEST_APP/cache/buildkit/index.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
   run /bin/bash -c "dokku repo:purge-cache $TEST_APP"
   echo "output: $output"
   echo "status: $status"
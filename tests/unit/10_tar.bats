@@ -42,3 +42,25 @@ deploy_app_tar() {
   echo "status: $status"
   assert_success
 }
+
+@test "(tar) compressed deploy using tar:in" {
+  deploy_app_tar nodejs-express -z
+
+  run /bin/bash -c "response=\"$(curl -s -S ${TEST_APP}.dokku.me)\"; echo \$response; test \"\$response\" == \"nodejs/express\""
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
+
+@test "(tar) tar:in rejects tarballs over the size limit" {
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP DOKKU_TAR_MAX_SIZE=1K"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "tar c -C ${BATS_TEST_DIRNAME}/../../tests/apps/nodejs-express . | dokku tar:in $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "DOKKU_TAR_MAX_SIZE"
+}
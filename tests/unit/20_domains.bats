@@ -90,6 +90,99 @@ teardown() {
   assert_failure
 }
 
+@test "(domains) domains:add (invalid labels)" {
+  run /bin/bash -c "dokku domains:add $TEST_APP -test.app.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku domains:add $TEST_APP 'www.*.dokku.me'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku domains:add $TEST_APP '*.me'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(domains) domains:add (normalized)" {
+  run /bin/bash -c "dokku domains:add $TEST_APP Test.App.Dokku.Me. bücher.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains $TEST_APP 2>/dev/null"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_line test.app.dokku.me
+  assert_line xn--bcher-kva.dokku.me
+}
+
+@test "(domains) domains:add (regex domain)" {
+  run /bin/bash -c "dokku domains:add $TEST_APP '~^api[0-9]+\.dokku\.me$'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains:add $TEST_APP '~^api[0-9+\.dokku\.me$'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(domains) domains:add (conflict)" {
+  run /bin/bash -c "dokku apps:create ${TEST_APP}-2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains:add $TEST_APP conflict.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains:add ${TEST_APP}-2 conflict.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku --force domains:add ${TEST_APP}-2 conflict.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku domains:conflicts"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "conflict.dokku.me" 0
+
+  run /bin/bash -c "dokku domains:add ${TEST_APP}-2 --force conflict.dokku.me"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains:conflicts --format json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains '"domain":"conflict.dokku.me"'
+
+  run /bin/bash -c "dokku --force apps:destroy ${TEST_APP}-2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku domains:conflicts"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "conflict.dokku.me" 0
+}
+
 @test "(domains) domains:remove" {
   run /bin/bash -c "dokku domains:add $TEST_APP test.app.dokku.me"
   echo "output: $output"
@@ -0,0 +1,113 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+setup() {
+  global_setup
+  deploy_app
+}
+
+teardown() {
+  destroy_app
+  global_teardown
+}
+
+@test "(logs) logs:help" {
+  run /bin/bash -c "dokku logs:help"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "Output app logs"
+}
+
+@test "(logs) logs" {
+  run /bin/bash -c "dokku logs $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "app[web.1]:"
+
+  run /bin/bash -c "dokku logs $TEST_APP -q -n 1 | wc -l"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "1"
+
+  run /bin/bash -c "dokku logs $TEST_APP --ps worker"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output ""
+
+  run /bin/bash -c "dokku logs $TEST_APP --since 1h --grep 'this-never-matches'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output ""
+
+  run /bin/bash -c "dokku logs $TEST_APP --since yesterday"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(logs) logs --format json" {
+  run /bin/bash -c "dokku logs $TEST_APP --format json -n 1 | jq -r '.\"process-type\" + \".\" + (.index | tostring)'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "web.1"
+
+  run /bin/bash -c "dokku logs $TEST_APP --format yaml"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(logs) logs:failed" {
+  run /bin/bash -c "dokku logs:failed $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "No failed containers found"
+}
+
+@test "(logs) logs:set vector-sink" {
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-shipper"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "none"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink ftp://logs.example.com"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink syslog+tcp://127.0.0.1:514"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-vector-sink"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "syslog+tcp://127.0.0.1:514"
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-shipper"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "syslog log driver"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-shipper"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "none"
+}
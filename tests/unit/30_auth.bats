@@ -0,0 +1,109 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+setup() {
+  global_setup
+  create_app
+}
+
+teardown() {
+  dokku auth:disable || true
+  dokku auth:user-remove admin-user || true
+  dokku auth:user-remove alice || true
+  destroy_app
+  global_teardown
+}
+
+@test "(auth) auth:user-add, auth:key-add, auth:list" {
+  run /bin/bash -c "dokku auth:user-add alice developers"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:user-add alice"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku auth:key-add alice alice-laptop"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:grant alice $TEST_APP deploy"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:grant alice $TEST_APP owner"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku auth:list | grep Grants"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "$TEST_APP:deploy"
+
+  run /bin/bash -c "dokku auth:list --format json | jq -r '.[0].keys[0]'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "alice-laptop"
+}
+
+@test "(auth) auth:enable" {
+  run /bin/bash -c "dokku auth:enable"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku auth:user-add admin-user admin && dokku auth:key-add admin-user admin"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:enable"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:list"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "Access control enabled"
+}
+
+@test "(auth) user-auth" {
+  run /bin/bash -c "dokku auth:user-add admin-user admin && dokku auth:key-add admin-user admin && dokku auth:enable"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:user-add alice && dokku auth:key-add alice alice-laptop && dokku auth:grant alice $TEST_APP read-only"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "sudo -u dokku SSH_USER=dokku NAME=alice-laptop dokku ps:report $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "sudo -u dokku SSH_USER=dokku NAME=alice-laptop dokku config:set $TEST_APP KEY=value"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "the config permission is required"
+
+  run /bin/bash -c "sudo -u dokku SSH_USER=dokku NAME=unknown-laptop dokku ps:report $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "No dokku user is mapped"
+
+  run /bin/bash -c "sudo -u dokku SSH_USER=dokku NAME=alice-laptop dokku auth:grant alice $TEST_APP deploy"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
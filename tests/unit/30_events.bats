@@ -40,3 +40,60 @@ teardown() {
   assert_success
   run /bin/bash -c "dokku events:off"
 }
+
+@test "(events) audit log" {
+  run /bin/bash -c "dokku apps:list"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:set --global AUDIT_SECRET_VALUE=hunter2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku events:list --audit --command config --format json | jq -r '.[-1].args | join(\" \")'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "--global AUDIT_SECRET_VALUE=********"
+
+  run /bin/bash -c "dokku events:list --audit --command apps:list --limit 1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "apps:list"
+
+  run /bin/bash -c "dokku events:list --audit --since yesterday"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku events:list --command apps:list"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "--command may only be used with --audit"
+
+  run /bin/bash -c "dokku config:unset --global AUDIT_SECRET_VALUE"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
+
+@test "(events) events:set" {
+  run /bin/bash -c "dokku events:set audit-syslog maybe"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku events:set audit-max-files 3"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku events:set audit-max-files"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
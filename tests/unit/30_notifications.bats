@@ -0,0 +1,158 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+setup() {
+  global_setup
+  create_app
+  WEBHOOK_LOG="$(mktemp)"
+  python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers["Content-Length"]))
+        with open(sys.argv[1], "a") as f:
+            f.write(self.headers["X-Dokku-Event"] + " " + self.headers["X-Dokku-Signature"] + " " + body.decode() + "\n")
+        self.send_response(200 if self.path == "/ok" else 404)
+        self.end_headers()
+http.server.HTTPServer(("127.0.0.1", 8765), Handler).serve_forever()
+' "$WEBHOOK_LOG" &
+  WEBHOOK_PID="$!"
+  sleep 1
+}
+
+teardown() {
+  kill "$WEBHOOK_PID" || true
+  rm -f "$WEBHOOK_LOG"
+  dokku notifications:remove --global ops || true
+  destroy_app
+  global_teardown
+}
+
+@test "(notifications) notifications:add, notifications:list, notifications:remove" {
+  run /bin/bash -c "dokku notifications:add $TEST_APP ci ftp://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku notifications:add --events pre-deploy $TEST_APP ci http://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku notifications:add --events post-deploy --secret s3cret $TEST_APP ci http://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku notifications:add $TEST_APP ci http://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku notifications:list --format json $TEST_APP | jq -r '.[0].events[0]'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "post-deploy"
+
+  run /bin/bash -c "dokku notifications:list $TEST_APP | grep -c s3cret"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "0"
+
+  run /bin/bash -c "dokku notifications:remove $TEST_APP ci"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku notifications:list --format json $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "[]"
+}
+
+@test "(notifications) notifications:test, notifications:deliveries" {
+  run /bin/bash -c "dokku notifications:add --secret s3cret $TEST_APP ci http://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku notifications:test $TEST_APP ci"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "grep -c '^test sha256=' $WEBHOOK_LOG"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "1"
+
+  run /bin/bash -c "dokku notifications:add $TEST_APP missing http://127.0.0.1:8765/missing"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku notifications:test $TEST_APP missing"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku notifications:deliveries --format json $TEST_APP | jq -r 'map(.success) | join(\",\")'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "true,false"
+}
+
+@test "(notifications) notifications are delivered on config changes" {
+  run /bin/bash -c "dokku notifications:add --global --events post-config-update ops http://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP SECRET_KEY=hunter2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  wait_for_webhook post-config-update
+
+  run /bin/bash -c "grep '^post-config-update' $WEBHOOK_LOG | cut -d' ' -f3- | jq -r '.data.keys'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "SECRET_KEY"
+
+  run /bin/bash -c "grep -c hunter2 $WEBHOOK_LOG"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "0"
+}
+
+@test "(notifications) notifications are delivered on global config changes" {
+  run /bin/bash -c "dokku notifications:add --global --events post-config-update ops http://127.0.0.1:8765/ok"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:set --global GLOBAL_KEY=value"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  wait_for_webhook post-config-update
+
+  run /bin/bash -c "grep '^post-config-update' $WEBHOOK_LOG | cut -d' ' -f3- | jq -r '.app + \":\" + .data.keys'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output ":GLOBAL_KEY"
+
+  dokku config:unset --global GLOBAL_KEY
+}
+
+wait_for_webhook() {
+  declare EVENT="$1"
+  for _ in $(seq 1 20); do
+    grep -q "^$EVENT " "$WEBHOOK_LOG" && return 0
+    sleep 0.5
+  done
+  return 1
+}
@@ -81,3 +81,56 @@ teardown() {
   echo "status: $status"
   assert_failure
 }
+
+@test "(ssh-keys) ssh-keys:list --format json" {
+  run /bin/bash -c "dokku ssh-keys:add name1 /tmp/testkey.pub"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku ssh-keys:list --format json | jq -r '.[] | select(.name == \"name1\") | .fingerprint'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "$(ssh-keygen -E sha256 -lf /tmp/testkey.pub | awk '{print $2}')"
+  run /bin/bash -c "dokku ssh-keys:list --format json | jq -r '.[] | select(.name == \"name1\") | .type'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "ssh-rsa"
+  run /bin/bash -c "dokku ssh-keys:list --format yaml"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+}
+
+@test "(ssh-keys) ssh-keys:add rejects weak keys" {
+  rm -f /tmp/testkey-weak /tmp/testkey-weak.pub
+  ssh-keygen -t rsa -b 1024 -P "" -f /tmp/testkey-weak &>/dev/null
+  run /bin/bash -c "dokku ssh-keys:add weak /tmp/testkey-weak.pub"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "RSA keys must be at least 2048 bits"
+  rm -f /tmp/testkey-weak /tmp/testkey-weak.pub
+}
+
+@test "(ssh-keys) ssh-keys:add --expires" {
+  run /bin/bash -c "dokku ssh-keys:add --expires 2000-01-01 expired /tmp/testkey.pub"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  run /bin/bash -c "dokku ssh-keys:add --expires 30d expiring /tmp/testkey.pub"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  run /bin/bash -c "dokku ssh-keys:list | grep expiring"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "EXPIRES_AT="
+  run /bin/bash -c "dokku ssh-keys:list --format json | jq -r '.[] | select(.name == \"expiring\") | .expired'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "false"
+  run /bin/bash -c "dokku ssh-keys:remove expiring"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
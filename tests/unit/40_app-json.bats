@@ -44,3 +44,33 @@ teardown() {
   echo "status: $status"
   assert_success
 }
+
+@test "(app-json) app.json formation" {
+  run deploy_app nodejs-express dokku@dokku.me:$TEST_APP add_app_json_formation
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "cat $DOKKU_ROOT/$TEST_APP/DOKKU_SCALE | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "cron=0 custom=0 release=0 web=1 worker=2"
+}
+
+@test "(app-json) app.json invalid" {
+  run deploy_app nodejs-express dokku@dokku.me:$TEST_APP add_invalid_app_json
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "cron[0].schedule must have five fields"
+}
+
+add_app_json_formation() {
+  local APP_REPO_DIR="$2"
+  python3 -c "import json; f='$APP_REPO_DIR/app.json'; d=json.load(open(f)); d['formation']={'worker': {'quantity': 2}}; json.dump(d, open(f, 'w'))"
+}
+
+add_invalid_app_json() {
+  local APP_REPO_DIR="$2"
+  python3 -c "import json; f='$APP_REPO_DIR/app.json'; d=json.load(open(f)); d['cron']=[{'command': 'true', 'schedule': '* * *'}]; json.dump(d, open(f, 'w'))"
+}
@@ -0,0 +1,98 @@
+#!/usr/bin/env bats
+
+load test_helper
+
+setup() {
+  global_setup
+  create_app
+}
+
+teardown() {
+  destroy_app
+  global_teardown
+}
+
+@test "(cron) cron:help" {
+  run /bin/bash -c "dokku cron:help"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "Manage scheduled tasks defined in app.json"
+}
+
+@test "(cron) app.json tasks" {
+  run deploy_app nodejs-express dokku@dokku.me:$TEST_APP add_app_json_cron
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Registering 1 cron task(s)"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --format json | python3 -c 'import json,sys; print(json.load(sys.stdin)[0][\"id\"])'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  local TASK_ID="$output"
+
+  run /bin/bash -c "crontab -l -u dokku"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output_contains "@daily DOKKU_CRON_RUN=true /usr/bin/dokku cron:run $TEST_APP $TASK_ID >/dev/null 2>&1"
+
+  run /bin/bash -c "dokku cron:run $TEST_APP $TASK_ID"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "cron-task-output"
+
+  run /bin/bash -c "dokku cron:logs $TEST_APP $TASK_ID"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "cron-task-output"
+
+  run /bin/bash -c "dokku cron:run $TEST_APP missing"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  destroy_app
+  run /bin/bash -c "crontab -l -u dokku | grep -c $TASK_ID"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "0"
+  create_app
+}
+
+@test "(cron) cron:run with auth enabled" {
+  run deploy_app nodejs-express dokku@dokku.me:$TEST_APP add_app_json_cron
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku auth:user-add admin-user admin && dokku auth:key-add admin-user admin && dokku auth:enable"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  local TASK_ID="$(dokku cron:list "$TEST_APP" --format json | python3 -c 'import json,sys; print(json.load(sys.stdin)[0]["id"])')"
+  local CRON_COMMAND="$(crontab -l -u dokku | grep "cron:run $TEST_APP $TASK_ID" | cut -d' ' -f2- | sed 's/ >.*//')"
+
+  run /bin/bash -c "sudo -u dokku SSH_USER=dokku /bin/sh -c '$CRON_COMMAND'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "cron-task-output"
+
+  run /bin/bash -c "sudo -u dokku SSH_USER=dokku dokku cron:run $TEST_APP $TASK_ID"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "No dokku user is mapped"
+
+  dokku auth:disable
+  dokku auth:user-remove admin-user
+}
+
+add_app_json_cron() {
+  local APP_REPO_DIR="$2"
+  python3 -c "import json; f='$APP_REPO_DIR/app.json'; d=json.load(open(f)); d['cron']=[{'command': 'echo cron-task-output', 'schedule': '@daily'}]; json.dump(d, open(f, 'w'))"
+}
@@ -156,3 +156,61 @@ teardown() {
   echo "status: $status"
   assert_failure
 }
+
+@test "(plugin) plugin:install refuses incompatible plugins" {
+  pushd "$TEST_PLUGIN_LOCAL_REPO"
+  printf '[plugin]\ndescription = "smoke test plugin"\nversion = "0.1.0"\ndokku = "< 0.1.0"\n[plugin.config]\n' >plugin.toml
+  git -c user.name=test -c user.email=test@example.com commit -qam "require an old dokku"
+  popd
+
+  run /bin/bash -c "dokku plugin:install file://$TEST_PLUGIN_LOCAL_REPO --name $TEST_PLUGIN_NAME"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "$TEST_PLUGIN_NAME requires dokku < 0.1.0"
+
+  run /bin/bash -c "dokku plugin | grep $TEST_PLUGIN_NAME"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  [[ ! -d "$PLUGIN_AVAILABLE_PATH/$TEST_PLUGIN_NAME" ]]
+}
+
+@test "(plugin) plugin:triggers, plugin:trace, plugin:timeline" {
+  run /bin/bash -c "dokku plugin:triggers post-create"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "00_dokku-standard"
+
+  run /bin/bash -c "dokku plugin:triggers not-a-trigger"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku plugin:trace on"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku apps:list"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku plugin:trace off"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku plugin:traces | grep apps:list"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku plugin:timeline \$(dokku plugin:traces | grep apps:list | tail -n1 | awk '{print \$1}')"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "user-auth"
+}
@@ -57,3 +57,42 @@ teardown() {
   echo "status: $status"
   assert_failure
 }
+
+@test "(tags) tags:promote" {
+  local DEST_APP="${TEST_APP}-promoted"
+  run /bin/bash -c "dokku apps:create $DEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku tags:create $TEST_APP v0.9.0"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku tags:promote $TEST_APP $DEST_APP v0.9.0"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Promoting dokku/${TEST_APP}:v0.9.0 to dokku/${DEST_APP}:v0.9.0"
+
+  run /bin/bash -c "docker ps | egrep '/start web' | egrep -q dokku/${DEST_APP}:v0.9.0"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:get $DEST_APP GIT_REV"
+  echo "output: $output"
+  echo "status: $status"
+  assert_output "$(dokku config:get $TEST_APP GIT_REV)"
+
+  run /bin/bash -c "dokku tags:promote $TEST_APP $DEST_APP missing-tag"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku --force apps:destroy $DEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}